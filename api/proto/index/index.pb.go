@@ -68,31 +68,654 @@ func (x *DocId) GetDocId() string {
 	return ""
 }
 
+type DocIds struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	DocIds []string `protobuf:"bytes,1,rep,name=DocIds,proto3" json:"DocIds,omitempty"`
+}
+
+func (x *DocIds) Reset() {
+	*x = DocIds{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_index_index_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DocIds) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DocIds) ProtoMessage() {}
+
+func (x *DocIds) ProtoReflect() protoreflect.Message {
+	mi := &file_index_index_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DocIds.ProtoReflect.Descriptor instead.
+func (*DocIds) Descriptor() ([]byte, []int) {
+	return file_index_index_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *DocIds) GetDocIds() []string {
+	if x != nil {
+		return x.DocIds
+	}
+	return nil
+}
+
 type AffectedCount struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Count int32 `protobuf:"varint,1,opt,name=Count,proto3" json:"Count,omitempty"`
+	Count int32 `protobuf:"varint,1,opt,name=Count,proto3" json:"Count,omitempty"`
+}
+
+func (x *AffectedCount) Reset() {
+	*x = AffectedCount{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_index_index_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *AffectedCount) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AffectedCount) ProtoMessage() {}
+
+func (x *AffectedCount) ProtoReflect() protoreflect.Message {
+	mi := &file_index_index_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AffectedCount.ProtoReflect.Descriptor instead.
+func (*AffectedCount) Descriptor() ([]byte, []int) {
+	return file_index_index_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *AffectedCount) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+// ShardFilter limits a read to the documents of some shards, a worker hosting replicas of more shards answers for these only
+type ShardFilter struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Shards int32   `protobuf:"varint,1,opt,name=Shards,proto3" json:"Shards,omitempty"` // shards of the cluster, the shard of a document is farmhash(id) % Shards
+	Ids    []int32 `protobuf:"varint,2,rep,packed,name=Ids,proto3" json:"Ids,omitempty"`
+}
+
+func (x *ShardFilter) Reset() {
+	*x = ShardFilter{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_index_index_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ShardFilter) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShardFilter) ProtoMessage() {}
+
+func (x *ShardFilter) ProtoReflect() protoreflect.Message {
+	mi := &file_index_index_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShardFilter.ProtoReflect.Descriptor instead.
+func (*ShardFilter) Descriptor() ([]byte, []int) {
+	return file_index_index_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ShardFilter) GetShards() int32 {
+	if x != nil {
+		return x.Shards
+	}
+	return 0
+}
+
+func (x *ShardFilter) GetIds() []int32 {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+type SearchRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Query   *search.TermQuery `protobuf:"bytes,1,opt,name=Query,proto3" json:"Query,omitempty"`
+	OnFlag  uint64            `protobuf:"varint,2,opt,name=OnFlag,proto3" json:"OnFlag,omitempty"`
+	OffFlag uint64            `protobuf:"varint,3,opt,name=OffFlag,proto3" json:"OffFlag,omitempty"`
+	OrFlags []uint64          `protobuf:"varint,4,rep,packed,name=OrFlags,proto3" json:"OrFlags,omitempty"`
+	Shards  *ShardFilter      `protobuf:"bytes,5,opt,name=Shards,proto3" json:"Shards,omitempty"` // all documents of the worker if unset
+}
+
+func (x *SearchRequest) Reset() {
+	*x = SearchRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_index_index_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SearchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchRequest) ProtoMessage() {}
+
+func (x *SearchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_index_index_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchRequest.ProtoReflect.Descriptor instead.
+func (*SearchRequest) Descriptor() ([]byte, []int) {
+	return file_index_index_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *SearchRequest) GetQuery() *search.TermQuery {
+	if x != nil {
+		return x.Query
+	}
+	return nil
+}
+
+func (x *SearchRequest) GetOnFlag() uint64 {
+	if x != nil {
+		return x.OnFlag
+	}
+	return 0
+}
+
+func (x *SearchRequest) GetOffFlag() uint64 {
+	if x != nil {
+		return x.OffFlag
+	}
+	return 0
+}
+
+func (x *SearchRequest) GetOrFlags() []uint64 {
+	if x != nil {
+		return x.OrFlags
+	}
+	return nil
+}
+
+func (x *SearchRequest) GetShards() *ShardFilter {
+	if x != nil {
+		return x.Shards
+	}
+	return nil
+}
+
+type SearchResult struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Results []*search.Document `protobuf:"bytes,1,rep,name=Results,proto3" json:"Results,omitempty"`
+	Scores  []float32          `protobuf:"fixed32,2,rep,packed,name=Scores,proto3" json:"Scores,omitempty"` // only set by SearchVector, similarity of each result
+}
+
+func (x *SearchResult) Reset() {
+	*x = SearchResult{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_index_index_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SearchResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchResult) ProtoMessage() {}
+
+func (x *SearchResult) ProtoReflect() protoreflect.Message {
+	mi := &file_index_index_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchResult.ProtoReflect.Descriptor instead.
+func (*SearchResult) Descriptor() ([]byte, []int) {
+	return file_index_index_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *SearchResult) GetResults() []*search.Document {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+func (x *SearchResult) GetScores() []float32 {
+	if x != nil {
+		return x.Scores
+	}
+	return nil
+}
+
+type VectorSearchRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Vector []float32    `protobuf:"fixed32,1,rep,packed,name=Vector,proto3" json:"Vector,omitempty"`
+	K      int32        `protobuf:"varint,2,opt,name=K,proto3" json:"K,omitempty"`
+	Shards *ShardFilter `protobuf:"bytes,3,opt,name=Shards,proto3" json:"Shards,omitempty"`
+}
+
+func (x *VectorSearchRequest) Reset() {
+	*x = VectorSearchRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_index_index_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *VectorSearchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VectorSearchRequest) ProtoMessage() {}
+
+func (x *VectorSearchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_index_index_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VectorSearchRequest.ProtoReflect.Descriptor instead.
+func (*VectorSearchRequest) Descriptor() ([]byte, []int) {
+	return file_index_index_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *VectorSearchRequest) GetVector() []float32 {
+	if x != nil {
+		return x.Vector
+	}
+	return nil
+}
+
+func (x *VectorSearchRequest) GetK() int32 {
+	if x != nil {
+		return x.K
+	}
+	return 0
+}
+
+func (x *VectorSearchRequest) GetShards() *ShardFilter {
+	if x != nil {
+		return x.Shards
+	}
+	return nil
+}
+
+type CountRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Shards *ShardFilter `protobuf:"bytes,1,opt,name=Shards,proto3" json:"Shards,omitempty"`
+}
+
+func (x *CountRequest) Reset() {
+	*x = CountRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_index_index_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CountRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CountRequest) ProtoMessage() {}
+
+func (x *CountRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_index_index_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CountRequest.ProtoReflect.Descriptor instead.
+func (*CountRequest) Descriptor() ([]byte, []int) {
+	return file_index_index_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *CountRequest) GetShards() *ShardFilter {
+	if x != nil {
+		return x.Shards
+	}
+	return nil
+}
+
+type MoreLikeThisRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	DocId  string           `protobuf:"bytes,1,opt,name=DocId,proto3" json:"DocId,omitempty"`
+	Like   *search.Document `protobuf:"bytes,2,opt,name=Like,proto3" json:"Like,omitempty"` // source document, looked up locally by DocId when absent
+	Limit  int32            `protobuf:"varint,3,opt,name=Limit,proto3" json:"Limit,omitempty"`
+	Shards *ShardFilter     `protobuf:"bytes,4,opt,name=Shards,proto3" json:"Shards,omitempty"`
+}
+
+func (x *MoreLikeThisRequest) Reset() {
+	*x = MoreLikeThisRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_index_index_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *MoreLikeThisRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MoreLikeThisRequest) ProtoMessage() {}
+
+func (x *MoreLikeThisRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_index_index_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MoreLikeThisRequest.ProtoReflect.Descriptor instead.
+func (*MoreLikeThisRequest) Descriptor() ([]byte, []int) {
+	return file_index_index_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *MoreLikeThisRequest) GetDocId() string {
+	if x != nil {
+		return x.DocId
+	}
+	return ""
+}
+
+func (x *MoreLikeThisRequest) GetLike() *search.Document {
+	if x != nil {
+		return x.Like
+	}
+	return nil
+}
+
+func (x *MoreLikeThisRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *MoreLikeThisRequest) GetShards() *ShardFilter {
+	if x != nil {
+		return x.Shards
+	}
+	return nil
+}
+
+type GetDocResult struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Doc *search.Document `protobuf:"bytes,1,opt,name=Doc,proto3" json:"Doc,omitempty"` // unset if the worker does not have the document
+}
+
+func (x *GetDocResult) Reset() {
+	*x = GetDocResult{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_index_index_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetDocResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDocResult) ProtoMessage() {}
+
+func (x *GetDocResult) ProtoReflect() protoreflect.Message {
+	mi := &file_index_index_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDocResult.ProtoReflect.Descriptor instead.
+func (*GetDocResult) Descriptor() ([]byte, []int) {
+	return file_index_index_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GetDocResult) GetDoc() *search.Document {
+	if x != nil {
+		return x.Doc
+	}
+	return nil
+}
+
+type MultiGetDocResult struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Docs []*search.Document `protobuf:"bytes,1,rep,name=Docs,proto3" json:"Docs,omitempty"` // the documents the worker has, in the requested order
+}
+
+func (x *MultiGetDocResult) Reset() {
+	*x = MultiGetDocResult{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_index_index_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *MultiGetDocResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MultiGetDocResult) ProtoMessage() {}
+
+func (x *MultiGetDocResult) ProtoReflect() protoreflect.Message {
+	mi := &file_index_index_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MultiGetDocResult.ProtoReflect.Descriptor instead.
+func (*MultiGetDocResult) Descriptor() ([]byte, []int) {
+	return file_index_index_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *MultiGetDocResult) GetDocs() []*search.Document {
+	if x != nil {
+		return x.Docs
+	}
+	return nil
+}
+
+type MoreLikeThisResult struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Results []*search.Document `protobuf:"bytes,1,rep,name=Results,proto3" json:"Results,omitempty"`
+	Scores  []float32          `protobuf:"fixed32,2,rep,packed,name=Scores,proto3" json:"Scores,omitempty"`
+	Like    *search.Document   `protobuf:"bytes,3,opt,name=Like,proto3" json:"Like,omitempty"` // set by the worker owning the source document
+}
+
+func (x *MoreLikeThisResult) Reset() {
+	*x = MoreLikeThisResult{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_index_index_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *MoreLikeThisResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MoreLikeThisResult) ProtoMessage() {}
+
+func (x *MoreLikeThisResult) ProtoReflect() protoreflect.Message {
+	mi := &file_index_index_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MoreLikeThisResult.ProtoReflect.Descriptor instead.
+func (*MoreLikeThisResult) Descriptor() ([]byte, []int) {
+	return file_index_index_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *MoreLikeThisResult) GetResults() []*search.Document {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+func (x *MoreLikeThisResult) GetScores() []float32 {
+	if x != nil {
+		return x.Scores
+	}
+	return nil
+}
+
+func (x *MoreLikeThisResult) GetLike() *search.Document {
+	if x != nil {
+		return x.Like
+	}
+	return nil
+}
+
+type BulkOperation struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Doc      *search.Document `protobuf:"bytes,1,opt,name=Doc,proto3" json:"Doc,omitempty"`           // document to index, replacing the document with the same id
+	DeleteId string           `protobuf:"bytes,2,opt,name=DeleteId,proto3" json:"DeleteId,omitempty"` // id of the document to delete, used when Doc is unset
 }
 
-func (x *AffectedCount) Reset() {
-	*x = AffectedCount{}
+func (x *BulkOperation) Reset() {
+	*x = BulkOperation{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_index_index_proto_msgTypes[1]
+		mi := &file_index_index_proto_msgTypes[12]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *AffectedCount) String() string {
+func (x *BulkOperation) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*AffectedCount) ProtoMessage() {}
+func (*BulkOperation) ProtoMessage() {}
 
-func (x *AffectedCount) ProtoReflect() protoreflect.Message {
-	mi := &file_index_index_proto_msgTypes[1]
+func (x *BulkOperation) ProtoReflect() protoreflect.Message {
+	mi := &file_index_index_proto_msgTypes[12]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -103,46 +726,52 @@ func (x *AffectedCount) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use AffectedCount.ProtoReflect.Descriptor instead.
-func (*AffectedCount) Descriptor() ([]byte, []int) {
-	return file_index_index_proto_rawDescGZIP(), []int{1}
+// Deprecated: Use BulkOperation.ProtoReflect.Descriptor instead.
+func (*BulkOperation) Descriptor() ([]byte, []int) {
+	return file_index_index_proto_rawDescGZIP(), []int{12}
 }
 
-func (x *AffectedCount) GetCount() int32 {
+func (x *BulkOperation) GetDoc() *search.Document {
 	if x != nil {
-		return x.Count
+		return x.Doc
 	}
-	return 0
+	return nil
 }
 
-type SearchRequest struct {
+func (x *BulkOperation) GetDeleteId() string {
+	if x != nil {
+		return x.DeleteId
+	}
+	return ""
+}
+
+type BulkResult struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Query   *search.TermQuery `protobuf:"bytes,1,opt,name=Query,proto3" json:"Query,omitempty"`
-	OnFlag  uint64            `protobuf:"varint,2,opt,name=OnFlag,proto3" json:"OnFlag,omitempty"`
-	OffFlag uint64            `protobuf:"varint,3,opt,name=OffFlag,proto3" json:"OffFlag,omitempty"`
-	OrFlags []uint64          `protobuf:"varint,4,rep,packed,name=OrFlags,proto3" json:"OrFlags,omitempty"`
+	Count    int32  `protobuf:"varint,1,opt,name=Count,proto3" json:"Count,omitempty"` // 1 if the document was indexed or deleted
+	Error    string `protobuf:"bytes,2,opt,name=Error,proto3" json:"Error,omitempty"`
+	Replaced bool   `protobuf:"varint,3,opt,name=Replaced,proto3" json:"Replaced,omitempty"` // the document replaced a document with the same id
 }
 
-func (x *SearchRequest) Reset() {
-	*x = SearchRequest{}
+func (x *BulkResult) Reset() {
+	*x = BulkResult{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_index_index_proto_msgTypes[2]
+		mi := &file_index_index_proto_msgTypes[13]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *SearchRequest) String() string {
+func (x *BulkResult) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*SearchRequest) ProtoMessage() {}
+func (*BulkResult) ProtoMessage() {}
 
-func (x *SearchRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_index_index_proto_msgTypes[2]
+func (x *BulkResult) ProtoReflect() protoreflect.Message {
+	mi := &file_index_index_proto_msgTypes[13]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -153,64 +782,105 @@ func (x *SearchRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use SearchRequest.ProtoReflect.Descriptor instead.
-func (*SearchRequest) Descriptor() ([]byte, []int) {
-	return file_index_index_proto_rawDescGZIP(), []int{2}
+// Deprecated: Use BulkResult.ProtoReflect.Descriptor instead.
+func (*BulkResult) Descriptor() ([]byte, []int) {
+	return file_index_index_proto_rawDescGZIP(), []int{13}
 }
 
-func (x *SearchRequest) GetQuery() *search.TermQuery {
+func (x *BulkResult) GetCount() int32 {
 	if x != nil {
-		return x.Query
+		return x.Count
 	}
-	return nil
+	return 0
 }
 
-func (x *SearchRequest) GetOnFlag() uint64 {
+func (x *BulkResult) GetError() string {
 	if x != nil {
-		return x.OnFlag
+		return x.Error
 	}
-	return 0
+	return ""
 }
 
-func (x *SearchRequest) GetOffFlag() uint64 {
+func (x *BulkResult) GetReplaced() bool {
 	if x != nil {
-		return x.OffFlag
+		return x.Replaced
 	}
-	return 0
+	return false
 }
 
-func (x *SearchRequest) GetOrFlags() []uint64 {
+type BulkResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Results []*BulkResult `protobuf:"bytes,1,rep,name=Results,proto3" json:"Results,omitempty"` // one per operation, in the order they were sent
+}
+
+func (x *BulkResponse) Reset() {
+	*x = BulkResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_index_index_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BulkResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BulkResponse) ProtoMessage() {}
+
+func (x *BulkResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_index_index_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BulkResponse.ProtoReflect.Descriptor instead.
+func (*BulkResponse) Descriptor() ([]byte, []int) {
+	return file_index_index_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *BulkResponse) GetResults() []*BulkResult {
 	if x != nil {
-		return x.OrFlags
+		return x.Results
 	}
 	return nil
 }
 
-type SearchResult struct {
+type TransferRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Results []*search.Document `protobuf:"bytes,1,rep,name=Results,proto3" json:"Results,omitempty"`
+	Shards     *ShardFilter `protobuf:"bytes,1,opt,name=Shards,proto3" json:"Shards,omitempty"`
+	DigestOnly bool         `protobuf:"varint,2,opt,name=DigestOnly,proto3" json:"DigestOnly,omitempty"` // only the last chunk is sent, to verify a copy
 }
 
-func (x *SearchResult) Reset() {
-	*x = SearchResult{}
+func (x *TransferRequest) Reset() {
+	*x = TransferRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_index_index_proto_msgTypes[3]
+		mi := &file_index_index_proto_msgTypes[15]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *SearchResult) String() string {
+func (x *TransferRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*SearchResult) ProtoMessage() {}
+func (*TransferRequest) ProtoMessage() {}
 
-func (x *SearchResult) ProtoReflect() protoreflect.Message {
-	mi := &file_index_index_proto_msgTypes[3]
+func (x *TransferRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_index_index_proto_msgTypes[15]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -221,41 +891,53 @@ func (x *SearchResult) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use SearchResult.ProtoReflect.Descriptor instead.
-func (*SearchResult) Descriptor() ([]byte, []int) {
-	return file_index_index_proto_rawDescGZIP(), []int{3}
+// Deprecated: Use TransferRequest.ProtoReflect.Descriptor instead.
+func (*TransferRequest) Descriptor() ([]byte, []int) {
+	return file_index_index_proto_rawDescGZIP(), []int{15}
 }
 
-func (x *SearchResult) GetResults() []*search.Document {
+func (x *TransferRequest) GetShards() *ShardFilter {
 	if x != nil {
-		return x.Results
+		return x.Shards
 	}
 	return nil
 }
 
-type CountRequest struct {
+func (x *TransferRequest) GetDigestOnly() bool {
+	if x != nil {
+		return x.DigestOnly
+	}
+	return false
+}
+
+// TransferChunk is a part of the documents of some shards, the last chunk of a transfer has no documents
+type TransferChunk struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
+
+	Docs   []*search.Document `protobuf:"bytes,1,rep,name=Docs,proto3" json:"Docs,omitempty"`
+	Count  int32              `protobuf:"varint,2,opt,name=Count,proto3" json:"Count,omitempty"`   // set on the last chunk, documents of the shards
+	Digest uint64             `protobuf:"varint,3,opt,name=Digest,proto3" json:"Digest,omitempty"` // set on the last chunk, xor of the hashes of the ids and contents of the documents
 }
 
-func (x *CountRequest) Reset() {
-	*x = CountRequest{}
+func (x *TransferChunk) Reset() {
+	*x = TransferChunk{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_index_index_proto_msgTypes[4]
+		mi := &file_index_index_proto_msgTypes[16]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *CountRequest) String() string {
+func (x *TransferChunk) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*CountRequest) ProtoMessage() {}
+func (*TransferChunk) ProtoMessage() {}
 
-func (x *CountRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_index_index_proto_msgTypes[4]
+func (x *TransferChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_index_index_proto_msgTypes[16]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -266,9 +948,30 @@ func (x *CountRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use CountRequest.ProtoReflect.Descriptor instead.
-func (*CountRequest) Descriptor() ([]byte, []int) {
-	return file_index_index_proto_rawDescGZIP(), []int{4}
+// Deprecated: Use TransferChunk.ProtoReflect.Descriptor instead.
+func (*TransferChunk) Descriptor() ([]byte, []int) {
+	return file_index_index_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *TransferChunk) GetDocs() []*search.Document {
+	if x != nil {
+		return x.Docs
+	}
+	return nil
+}
+
+func (x *TransferChunk) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *TransferChunk) GetDigest() uint64 {
+	if x != nil {
+		return x.Digest
+	}
+	return 0
 }
 
 var File_index_index_proto protoreflect.FileDescriptor
@@ -280,41 +983,153 @@ var file_index_index_proto_rawDesc = []byte{
 	0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x17, 0x73, 0x65, 0x61, 0x72, 0x63, 0x68, 0x2f, 0x74, 0x65, 0x72,
 	0x6d, 0x5f, 0x71, 0x75, 0x65, 0x72, 0x79, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x1d, 0x0a,
 	0x05, 0x44, 0x6f, 0x63, 0x49, 0x64, 0x12, 0x14, 0x0a, 0x05, 0x44, 0x6f, 0x63, 0x49, 0x64, 0x18,
-	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x44, 0x6f, 0x63, 0x49, 0x64, 0x22, 0x25, 0x0a, 0x0d,
-	0x41, 0x66, 0x66, 0x65, 0x63, 0x74, 0x65, 0x64, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x14, 0x0a,
-	0x05, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x43, 0x6f,
-	0x75, 0x6e, 0x74, 0x22, 0x84, 0x01, 0x0a, 0x0d, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x52, 0x65,
-	0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x27, 0x0a, 0x05, 0x51, 0x75, 0x65, 0x72, 0x79, 0x18, 0x01,
-	0x20, 0x01, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x73, 0x65, 0x61, 0x72, 0x63, 0x68, 0x2e, 0x54, 0x65,
-	0x72, 0x6d, 0x51, 0x75, 0x65, 0x72, 0x79, 0x52, 0x05, 0x51, 0x75, 0x65, 0x72, 0x79, 0x12, 0x16,
-	0x0a, 0x06, 0x4f, 0x6e, 0x46, 0x6c, 0x61, 0x67, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x06,
-	0x4f, 0x6e, 0x46, 0x6c, 0x61, 0x67, 0x12, 0x18, 0x0a, 0x07, 0x4f, 0x66, 0x66, 0x46, 0x6c, 0x61,
-	0x67, 0x18, 0x03, 0x20, 0x01, 0x28, 0x04, 0x52, 0x07, 0x4f, 0x66, 0x66, 0x46, 0x6c, 0x61, 0x67,
-	0x12, 0x18, 0x0a, 0x07, 0x4f, 0x72, 0x46, 0x6c, 0x61, 0x67, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28,
-	0x04, 0x52, 0x07, 0x4f, 0x72, 0x46, 0x6c, 0x61, 0x67, 0x73, 0x22, 0x3a, 0x0a, 0x0c, 0x53, 0x65,
-	0x61, 0x72, 0x63, 0x68, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x2a, 0x0a, 0x07, 0x52, 0x65,
-	0x73, 0x75, 0x6c, 0x74, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x73, 0x65,
-	0x61, 0x72, 0x63, 0x68, 0x2e, 0x44, 0x6f, 0x63, 0x75, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x07, 0x52,
-	0x65, 0x73, 0x75, 0x6c, 0x74, 0x73, 0x22, 0x0e, 0x0a, 0x0c, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x52,
-	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x32, 0x92, 0x02, 0x0a, 0x0c, 0x49, 0x6e, 0x64, 0x65, 0x78,
-	0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x3f, 0x0a, 0x09, 0x44, 0x65, 0x6c, 0x65, 0x74,
-	0x65, 0x44, 0x6f, 0x63, 0x12, 0x14, 0x2e, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x73, 0x65, 0x72,
-	0x76, 0x69, 0x63, 0x65, 0x2e, 0x44, 0x6f, 0x63, 0x49, 0x64, 0x1a, 0x1c, 0x2e, 0x69, 0x6e, 0x64,
-	0x65, 0x78, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x41, 0x66, 0x66, 0x65, 0x63,
-	0x74, 0x65, 0x64, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x38, 0x0a, 0x06, 0x41, 0x64, 0x64, 0x44,
-	0x6f, 0x63, 0x12, 0x10, 0x2e, 0x73, 0x65, 0x61, 0x72, 0x63, 0x68, 0x2e, 0x44, 0x6f, 0x63, 0x75,
-	0x6d, 0x65, 0x6e, 0x74, 0x1a, 0x1c, 0x2e, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x73, 0x65, 0x72,
-	0x76, 0x69, 0x63, 0x65, 0x2e, 0x41, 0x66, 0x66, 0x65, 0x63, 0x74, 0x65, 0x64, 0x43, 0x6f, 0x75,
-	0x6e, 0x74, 0x12, 0x43, 0x0a, 0x06, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x12, 0x1c, 0x2e, 0x69,
-	0x6e, 0x64, 0x65, 0x78, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x53, 0x65, 0x61,
-	0x72, 0x63, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1b, 0x2e, 0x69, 0x6e, 0x64,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x44, 0x6f, 0x63, 0x49, 0x64, 0x22, 0x20, 0x0a, 0x06,
+	0x44, 0x6f, 0x63, 0x49, 0x64, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x44, 0x6f, 0x63, 0x49, 0x64, 0x73,
+	0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x06, 0x44, 0x6f, 0x63, 0x49, 0x64, 0x73, 0x22, 0x25,
+	0x0a, 0x0d, 0x41, 0x66, 0x66, 0x65, 0x63, 0x74, 0x65, 0x64, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x12,
+	0x14, 0x0a, 0x05, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05,
+	0x43, 0x6f, 0x75, 0x6e, 0x74, 0x22, 0x37, 0x0a, 0x0b, 0x53, 0x68, 0x61, 0x72, 0x64, 0x46, 0x69,
+	0x6c, 0x74, 0x65, 0x72, 0x12, 0x16, 0x0a, 0x06, 0x53, 0x68, 0x61, 0x72, 0x64, 0x73, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x05, 0x52, 0x06, 0x53, 0x68, 0x61, 0x72, 0x64, 0x73, 0x12, 0x10, 0x0a, 0x03,
+	0x49, 0x64, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x05, 0x52, 0x03, 0x49, 0x64, 0x73, 0x22, 0xb8,
+	0x01, 0x0a, 0x0d, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x12, 0x27, 0x0a, 0x05, 0x51, 0x75, 0x65, 0x72, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x11, 0x2e, 0x73, 0x65, 0x61, 0x72, 0x63, 0x68, 0x2e, 0x54, 0x65, 0x72, 0x6d, 0x51, 0x75, 0x65,
+	0x72, 0x79, 0x52, 0x05, 0x51, 0x75, 0x65, 0x72, 0x79, 0x12, 0x16, 0x0a, 0x06, 0x4f, 0x6e, 0x46,
+	0x6c, 0x61, 0x67, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x06, 0x4f, 0x6e, 0x46, 0x6c, 0x61,
+	0x67, 0x12, 0x18, 0x0a, 0x07, 0x4f, 0x66, 0x66, 0x46, 0x6c, 0x61, 0x67, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x04, 0x52, 0x07, 0x4f, 0x66, 0x66, 0x46, 0x6c, 0x61, 0x67, 0x12, 0x18, 0x0a, 0x07, 0x4f,
+	0x72, 0x46, 0x6c, 0x61, 0x67, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x04, 0x52, 0x07, 0x4f, 0x72,
+	0x46, 0x6c, 0x61, 0x67, 0x73, 0x12, 0x32, 0x0a, 0x06, 0x53, 0x68, 0x61, 0x72, 0x64, 0x73, 0x18,
+	0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x73, 0x65,
+	0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x53, 0x68, 0x61, 0x72, 0x64, 0x46, 0x69, 0x6c, 0x74, 0x65,
+	0x72, 0x52, 0x06, 0x53, 0x68, 0x61, 0x72, 0x64, 0x73, 0x22, 0x52, 0x0a, 0x0c, 0x53, 0x65, 0x61,
+	0x72, 0x63, 0x68, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x2a, 0x0a, 0x07, 0x52, 0x65, 0x73,
+	0x75, 0x6c, 0x74, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x73, 0x65, 0x61,
+	0x72, 0x63, 0x68, 0x2e, 0x44, 0x6f, 0x63, 0x75, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x07, 0x52, 0x65,
+	0x73, 0x75, 0x6c, 0x74, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x53, 0x63, 0x6f, 0x72, 0x65, 0x73, 0x18,
+	0x02, 0x20, 0x03, 0x28, 0x02, 0x52, 0x06, 0x53, 0x63, 0x6f, 0x72, 0x65, 0x73, 0x22, 0x6f, 0x0a,
+	0x13, 0x56, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x56, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x18, 0x01,
+	0x20, 0x03, 0x28, 0x02, 0x52, 0x06, 0x56, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x12, 0x0c, 0x0a, 0x01,
+	0x4b, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x01, 0x4b, 0x12, 0x32, 0x0a, 0x06, 0x53, 0x68,
+	0x61, 0x72, 0x64, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x69, 0x6e, 0x64,
+	0x65, 0x78, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x53, 0x68, 0x61, 0x72, 0x64,
+	0x46, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x52, 0x06, 0x53, 0x68, 0x61, 0x72, 0x64, 0x73, 0x22, 0x42,
+	0x0a, 0x0c, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x32,
+	0x0a, 0x06, 0x53, 0x68, 0x61, 0x72, 0x64, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a,
+	0x2e, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x53,
+	0x68, 0x61, 0x72, 0x64, 0x46, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x52, 0x06, 0x53, 0x68, 0x61, 0x72,
+	0x64, 0x73, 0x22, 0x9b, 0x01, 0x0a, 0x13, 0x4d, 0x6f, 0x72, 0x65, 0x4c, 0x69, 0x6b, 0x65, 0x54,
+	0x68, 0x69, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x44, 0x6f,
+	0x63, 0x49, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x44, 0x6f, 0x63, 0x49, 0x64,
+	0x12, 0x24, 0x0a, 0x04, 0x4c, 0x69, 0x6b, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x10,
+	0x2e, 0x73, 0x65, 0x61, 0x72, 0x63, 0x68, 0x2e, 0x44, 0x6f, 0x63, 0x75, 0x6d, 0x65, 0x6e, 0x74,
+	0x52, 0x04, 0x4c, 0x69, 0x6b, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x12, 0x32, 0x0a, 0x06,
+	0x53, 0x68, 0x61, 0x72, 0x64, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x69,
+	0x6e, 0x64, 0x65, 0x78, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x53, 0x68, 0x61,
+	0x72, 0x64, 0x46, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x52, 0x06, 0x53, 0x68, 0x61, 0x72, 0x64, 0x73,
+	0x22, 0x32, 0x0a, 0x0c, 0x47, 0x65, 0x74, 0x44, 0x6f, 0x63, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74,
+	0x12, 0x22, 0x0a, 0x03, 0x44, 0x6f, 0x63, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e,
+	0x73, 0x65, 0x61, 0x72, 0x63, 0x68, 0x2e, 0x44, 0x6f, 0x63, 0x75, 0x6d, 0x65, 0x6e, 0x74, 0x52,
+	0x03, 0x44, 0x6f, 0x63, 0x22, 0x39, 0x0a, 0x11, 0x4d, 0x75, 0x6c, 0x74, 0x69, 0x47, 0x65, 0x74,
+	0x44, 0x6f, 0x63, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x24, 0x0a, 0x04, 0x44, 0x6f, 0x63,
+	0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x73, 0x65, 0x61, 0x72, 0x63, 0x68,
+	0x2e, 0x44, 0x6f, 0x63, 0x75, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x04, 0x44, 0x6f, 0x63, 0x73, 0x22,
+	0x7e, 0x0a, 0x12, 0x4d, 0x6f, 0x72, 0x65, 0x4c, 0x69, 0x6b, 0x65, 0x54, 0x68, 0x69, 0x73, 0x52,
+	0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x2a, 0x0a, 0x07, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x73,
+	0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x73, 0x65, 0x61, 0x72, 0x63, 0x68, 0x2e,
+	0x44, 0x6f, 0x63, 0x75, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x07, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74,
+	0x73, 0x12, 0x16, 0x0a, 0x06, 0x53, 0x63, 0x6f, 0x72, 0x65, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28,
+	0x02, 0x52, 0x06, 0x53, 0x63, 0x6f, 0x72, 0x65, 0x73, 0x12, 0x24, 0x0a, 0x04, 0x4c, 0x69, 0x6b,
+	0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x73, 0x65, 0x61, 0x72, 0x63, 0x68,
+	0x2e, 0x44, 0x6f, 0x63, 0x75, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x04, 0x4c, 0x69, 0x6b, 0x65, 0x22,
+	0x4f, 0x0a, 0x0d, 0x42, 0x75, 0x6c, 0x6b, 0x4f, 0x70, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e,
+	0x12, 0x22, 0x0a, 0x03, 0x44, 0x6f, 0x63, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e,
+	0x73, 0x65, 0x61, 0x72, 0x63, 0x68, 0x2e, 0x44, 0x6f, 0x63, 0x75, 0x6d, 0x65, 0x6e, 0x74, 0x52,
+	0x03, 0x44, 0x6f, 0x63, 0x12, 0x1a, 0x0a, 0x08, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x49, 0x64,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x49, 0x64,
+	0x22, 0x54, 0x0a, 0x0a, 0x42, 0x75, 0x6c, 0x6b, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x14,
+	0x0a, 0x05, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x43,
+	0x6f, 0x75, 0x6e, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x05, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x1a, 0x0a, 0x08, 0x52, 0x65,
+	0x70, 0x6c, 0x61, 0x63, 0x65, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08, 0x52, 0x65,
+	0x70, 0x6c, 0x61, 0x63, 0x65, 0x64, 0x22, 0x43, 0x0a, 0x0c, 0x42, 0x75, 0x6c, 0x6b, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x33, 0x0a, 0x07, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74,
+	0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x5f,
+	0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x42, 0x75, 0x6c, 0x6b, 0x52, 0x65, 0x73, 0x75,
+	0x6c, 0x74, 0x52, 0x07, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x73, 0x22, 0x65, 0x0a, 0x0f, 0x54,
+	0x72, 0x61, 0x6e, 0x73, 0x66, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x32,
+	0x0a, 0x06, 0x53, 0x68, 0x61, 0x72, 0x64, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a,
+	0x2e, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x53,
+	0x68, 0x61, 0x72, 0x64, 0x46, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x52, 0x06, 0x53, 0x68, 0x61, 0x72,
+	0x64, 0x73, 0x12, 0x1e, 0x0a, 0x0a, 0x44, 0x69, 0x67, 0x65, 0x73, 0x74, 0x4f, 0x6e, 0x6c, 0x79,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0a, 0x44, 0x69, 0x67, 0x65, 0x73, 0x74, 0x4f, 0x6e,
+	0x6c, 0x79, 0x22, 0x63, 0x0a, 0x0d, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x66, 0x65, 0x72, 0x43, 0x68,
+	0x75, 0x6e, 0x6b, 0x12, 0x24, 0x0a, 0x04, 0x44, 0x6f, 0x63, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x10, 0x2e, 0x73, 0x65, 0x61, 0x72, 0x63, 0x68, 0x2e, 0x44, 0x6f, 0x63, 0x75, 0x6d,
+	0x65, 0x6e, 0x74, 0x52, 0x04, 0x44, 0x6f, 0x63, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x43, 0x6f, 0x75,
+	0x6e, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x12,
+	0x16, 0x0a, 0x06, 0x44, 0x69, 0x67, 0x65, 0x73, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x04, 0x52,
+	0x06, 0x44, 0x69, 0x67, 0x65, 0x73, 0x74, 0x32, 0xf6, 0x06, 0x0a, 0x0c, 0x49, 0x6e, 0x64, 0x65,
+	0x78, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x3f, 0x0a, 0x09, 0x44, 0x65, 0x6c, 0x65,
+	0x74, 0x65, 0x44, 0x6f, 0x63, 0x12, 0x14, 0x2e, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x73, 0x65,
+	0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x44, 0x6f, 0x63, 0x49, 0x64, 0x1a, 0x1c, 0x2e, 0x69, 0x6e,
+	0x64, 0x65, 0x78, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x41, 0x66, 0x66, 0x65,
+	0x63, 0x74, 0x65, 0x64, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x38, 0x0a, 0x06, 0x41, 0x64, 0x64,
+	0x44, 0x6f, 0x63, 0x12, 0x10, 0x2e, 0x73, 0x65, 0x61, 0x72, 0x63, 0x68, 0x2e, 0x44, 0x6f, 0x63,
+	0x75, 0x6d, 0x65, 0x6e, 0x74, 0x1a, 0x1c, 0x2e, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x73, 0x65,
+	0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x41, 0x66, 0x66, 0x65, 0x63, 0x74, 0x65, 0x64, 0x43, 0x6f,
+	0x75, 0x6e, 0x74, 0x12, 0x43, 0x0a, 0x06, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x12, 0x1c, 0x2e,
+	0x69, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x53, 0x65,
+	0x61, 0x72, 0x63, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1b, 0x2e, 0x69, 0x6e,
+	0x64, 0x65, 0x78, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x53, 0x65, 0x61, 0x72,
+	0x63, 0x68, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x42, 0x0a, 0x05, 0x43, 0x6f, 0x75, 0x6e,
+	0x74, 0x12, 0x1b, 0x2e, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63,
+	0x65, 0x2e, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1c,
+	0x2e, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x41,
+	0x66, 0x66, 0x65, 0x63, 0x74, 0x65, 0x64, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x4f, 0x0a, 0x0c,
+	0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x56, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x12, 0x22, 0x2e, 0x69,
+	0x6e, 0x64, 0x65, 0x78, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x56, 0x65, 0x63,
+	0x74, 0x6f, 0x72, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x1a, 0x1b, 0x2e, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65,
+	0x2e, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x55, 0x0a,
+	0x0c, 0x4d, 0x6f, 0x72, 0x65, 0x4c, 0x69, 0x6b, 0x65, 0x54, 0x68, 0x69, 0x73, 0x12, 0x22, 0x2e,
+	0x69, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x4d, 0x6f,
+	0x72, 0x65, 0x4c, 0x69, 0x6b, 0x65, 0x54, 0x68, 0x69, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x21, 0x2e, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63,
+	0x65, 0x2e, 0x4d, 0x6f, 0x72, 0x65, 0x4c, 0x69, 0x6b, 0x65, 0x54, 0x68, 0x69, 0x73, 0x52, 0x65,
+	0x73, 0x75, 0x6c, 0x74, 0x12, 0x3b, 0x0a, 0x06, 0x47, 0x65, 0x74, 0x44, 0x6f, 0x63, 0x12, 0x14,
+	0x2e, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x44,
+	0x6f, 0x63, 0x49, 0x64, 0x1a, 0x1b, 0x2e, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x73, 0x65, 0x72,
+	0x76, 0x69, 0x63, 0x65, 0x2e, 0x47, 0x65, 0x74, 0x44, 0x6f, 0x63, 0x52, 0x65, 0x73, 0x75, 0x6c,
+	0x74, 0x12, 0x46, 0x0a, 0x0b, 0x4d, 0x75, 0x6c, 0x74, 0x69, 0x47, 0x65, 0x74, 0x44, 0x6f, 0x63,
+	0x12, 0x15, 0x2e, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65,
+	0x2e, 0x44, 0x6f, 0x63, 0x49, 0x64, 0x73, 0x1a, 0x20, 0x2e, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x5f,
+	0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x4d, 0x75, 0x6c, 0x74, 0x69, 0x47, 0x65, 0x74,
+	0x44, 0x6f, 0x63, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x43, 0x0a, 0x04, 0x42, 0x75, 0x6c,
+	0x6b, 0x12, 0x1c, 0x2e, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63,
+	0x65, 0x2e, 0x42, 0x75, 0x6c, 0x6b, 0x4f, 0x70, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x1a,
+	0x1b, 0x2e, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e,
+	0x42, 0x75, 0x6c, 0x6b, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x28, 0x01, 0x12, 0x4a,
+	0x0a, 0x08, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x66, 0x65, 0x72, 0x12, 0x1e, 0x2e, 0x69, 0x6e, 0x64,
+	0x65, 0x78, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x54, 0x72, 0x61, 0x6e, 0x73,
+	0x66, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1c, 0x2e, 0x69, 0x6e, 0x64,
+	0x65, 0x78, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x54, 0x72, 0x61, 0x6e, 0x73,
+	0x66, 0x65, 0x72, 0x43, 0x68, 0x75, 0x6e, 0x6b, 0x30, 0x01, 0x12, 0x4b, 0x0a, 0x0c, 0x53, 0x65,
+	0x61, 0x72, 0x63, 0x68, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x12, 0x1c, 0x2e, 0x69, 0x6e, 0x64,
 	0x65, 0x78, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x53, 0x65, 0x61, 0x72, 0x63,
-	0x68, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x42, 0x0a, 0x05, 0x43, 0x6f, 0x75, 0x6e, 0x74,
-	0x12, 0x1b, 0x2e, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65,
-	0x2e, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1c, 0x2e,
-	0x69, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x41, 0x66,
-	0x66, 0x65, 0x63, 0x74, 0x65, 0x64, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x42, 0x09, 0x5a, 0x07, 0x2e,
-	0x3b, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1b, 0x2e, 0x69, 0x6e, 0x64, 0x65, 0x78,
+	0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x52,
+	0x65, 0x73, 0x75, 0x6c, 0x74, 0x30, 0x01, 0x12, 0x57, 0x0a, 0x12, 0x53, 0x65, 0x61, 0x72, 0x63,
+	0x68, 0x56, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x12, 0x22, 0x2e,
+	0x69, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x2e, 0x56, 0x65,
+	0x63, 0x74, 0x6f, 0x72, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x1b, 0x2e, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63,
+	0x65, 0x2e, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x30, 0x01,
+	0x42, 0x09, 0x5a, 0x07, 0x2e, 0x3b, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x62, 0x06, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x33,
 }
 
 var (
@@ -329,32 +1144,73 @@ func file_index_index_proto_rawDescGZIP() []byte {
 	return file_index_index_proto_rawDescData
 }
 
-var file_index_index_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_index_index_proto_msgTypes = make([]protoimpl.MessageInfo, 17)
 var file_index_index_proto_goTypes = []interface{}{
-	(*DocId)(nil),            // 0: index_service.DocId
-	(*AffectedCount)(nil),    // 1: index_service.AffectedCount
-	(*SearchRequest)(nil),    // 2: index_service.SearchRequest
-	(*SearchResult)(nil),     // 3: index_service.SearchResult
-	(*CountRequest)(nil),     // 4: index_service.CountRequest
-	(*search.TermQuery)(nil), // 5: search.TermQuery
-	(*search.Document)(nil),  // 6: search.Document
+	(*DocId)(nil),               // 0: index_service.DocId
+	(*DocIds)(nil),              // 1: index_service.DocIds
+	(*AffectedCount)(nil),       // 2: index_service.AffectedCount
+	(*ShardFilter)(nil),         // 3: index_service.ShardFilter
+	(*SearchRequest)(nil),       // 4: index_service.SearchRequest
+	(*SearchResult)(nil),        // 5: index_service.SearchResult
+	(*VectorSearchRequest)(nil), // 6: index_service.VectorSearchRequest
+	(*CountRequest)(nil),        // 7: index_service.CountRequest
+	(*MoreLikeThisRequest)(nil), // 8: index_service.MoreLikeThisRequest
+	(*GetDocResult)(nil),        // 9: index_service.GetDocResult
+	(*MultiGetDocResult)(nil),   // 10: index_service.MultiGetDocResult
+	(*MoreLikeThisResult)(nil),  // 11: index_service.MoreLikeThisResult
+	(*BulkOperation)(nil),       // 12: index_service.BulkOperation
+	(*BulkResult)(nil),          // 13: index_service.BulkResult
+	(*BulkResponse)(nil),        // 14: index_service.BulkResponse
+	(*TransferRequest)(nil),     // 15: index_service.TransferRequest
+	(*TransferChunk)(nil),       // 16: index_service.TransferChunk
+	(*search.TermQuery)(nil),    // 17: search.TermQuery
+	(*search.Document)(nil),     // 18: search.Document
 }
 var file_index_index_proto_depIdxs = []int32{
-	5, // 0: index_service.SearchRequest.Query:type_name -> search.TermQuery
-	6, // 1: index_service.SearchResult.Results:type_name -> search.Document
-	0, // 2: index_service.IndexService.DeleteDoc:input_type -> index_service.DocId
-	6, // 3: index_service.IndexService.AddDoc:input_type -> search.Document
-	2, // 4: index_service.IndexService.Search:input_type -> index_service.SearchRequest
-	4, // 5: index_service.IndexService.Count:input_type -> index_service.CountRequest
-	1, // 6: index_service.IndexService.DeleteDoc:output_type -> index_service.AffectedCount
-	1, // 7: index_service.IndexService.AddDoc:output_type -> index_service.AffectedCount
-	3, // 8: index_service.IndexService.Search:output_type -> index_service.SearchResult
-	1, // 9: index_service.IndexService.Count:output_type -> index_service.AffectedCount
-	6, // [6:10] is the sub-list for method output_type
-	2, // [2:6] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+	17, // 0: index_service.SearchRequest.Query:type_name -> search.TermQuery
+	3,  // 1: index_service.SearchRequest.Shards:type_name -> index_service.ShardFilter
+	18, // 2: index_service.SearchResult.Results:type_name -> search.Document
+	3,  // 3: index_service.VectorSearchRequest.Shards:type_name -> index_service.ShardFilter
+	3,  // 4: index_service.CountRequest.Shards:type_name -> index_service.ShardFilter
+	18, // 5: index_service.MoreLikeThisRequest.Like:type_name -> search.Document
+	3,  // 6: index_service.MoreLikeThisRequest.Shards:type_name -> index_service.ShardFilter
+	18, // 7: index_service.GetDocResult.Doc:type_name -> search.Document
+	18, // 8: index_service.MultiGetDocResult.Docs:type_name -> search.Document
+	18, // 9: index_service.MoreLikeThisResult.Results:type_name -> search.Document
+	18, // 10: index_service.MoreLikeThisResult.Like:type_name -> search.Document
+	18, // 11: index_service.BulkOperation.Doc:type_name -> search.Document
+	13, // 12: index_service.BulkResponse.Results:type_name -> index_service.BulkResult
+	3,  // 13: index_service.TransferRequest.Shards:type_name -> index_service.ShardFilter
+	18, // 14: index_service.TransferChunk.Docs:type_name -> search.Document
+	0,  // 15: index_service.IndexService.DeleteDoc:input_type -> index_service.DocId
+	18, // 16: index_service.IndexService.AddDoc:input_type -> search.Document
+	4,  // 17: index_service.IndexService.Search:input_type -> index_service.SearchRequest
+	7,  // 18: index_service.IndexService.Count:input_type -> index_service.CountRequest
+	6,  // 19: index_service.IndexService.SearchVector:input_type -> index_service.VectorSearchRequest
+	8,  // 20: index_service.IndexService.MoreLikeThis:input_type -> index_service.MoreLikeThisRequest
+	0,  // 21: index_service.IndexService.GetDoc:input_type -> index_service.DocId
+	1,  // 22: index_service.IndexService.MultiGetDoc:input_type -> index_service.DocIds
+	12, // 23: index_service.IndexService.Bulk:input_type -> index_service.BulkOperation
+	15, // 24: index_service.IndexService.Transfer:input_type -> index_service.TransferRequest
+	4,  // 25: index_service.IndexService.SearchStream:input_type -> index_service.SearchRequest
+	6,  // 26: index_service.IndexService.SearchVectorStream:input_type -> index_service.VectorSearchRequest
+	2,  // 27: index_service.IndexService.DeleteDoc:output_type -> index_service.AffectedCount
+	2,  // 28: index_service.IndexService.AddDoc:output_type -> index_service.AffectedCount
+	5,  // 29: index_service.IndexService.Search:output_type -> index_service.SearchResult
+	2,  // 30: index_service.IndexService.Count:output_type -> index_service.AffectedCount
+	5,  // 31: index_service.IndexService.SearchVector:output_type -> index_service.SearchResult
+	11, // 32: index_service.IndexService.MoreLikeThis:output_type -> index_service.MoreLikeThisResult
+	9,  // 33: index_service.IndexService.GetDoc:output_type -> index_service.GetDocResult
+	10, // 34: index_service.IndexService.MultiGetDoc:output_type -> index_service.MultiGetDocResult
+	14, // 35: index_service.IndexService.Bulk:output_type -> index_service.BulkResponse
+	16, // 36: index_service.IndexService.Transfer:output_type -> index_service.TransferChunk
+	5,  // 37: index_service.IndexService.SearchStream:output_type -> index_service.SearchResult
+	5,  // 38: index_service.IndexService.SearchVectorStream:output_type -> index_service.SearchResult
+	27, // [27:39] is the sub-list for method output_type
+	15, // [15:27] is the sub-list for method input_type
+	15, // [15:15] is the sub-list for extension type_name
+	15, // [15:15] is the sub-list for extension extendee
+	0,  // [0:15] is the sub-list for field type_name
 }
 
 func init() { file_index_index_proto_init() }
@@ -376,7 +1232,7 @@ func file_index_index_proto_init() {
 			}
 		}
 		file_index_index_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*AffectedCount); i {
+			switch v := v.(*DocIds); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -388,7 +1244,7 @@ func file_index_index_proto_init() {
 			}
 		}
 		file_index_index_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*SearchRequest); i {
+			switch v := v.(*AffectedCount); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -400,7 +1256,7 @@ func file_index_index_proto_init() {
 			}
 		}
 		file_index_index_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*SearchResult); i {
+			switch v := v.(*ShardFilter); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -412,6 +1268,42 @@ func file_index_index_proto_init() {
 			}
 		}
 		file_index_index_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SearchRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_index_index_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SearchResult); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_index_index_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*VectorSearchRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_index_index_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*CountRequest); i {
 			case 0:
 				return &v.state
@@ -423,6 +1315,114 @@ func file_index_index_proto_init() {
 				return nil
 			}
 		}
+		file_index_index_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*MoreLikeThisRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_index_index_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetDocResult); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_index_index_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*MultiGetDocResult); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_index_index_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*MoreLikeThisResult); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_index_index_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BulkOperation); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_index_index_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BulkResult); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_index_index_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BulkResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_index_index_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*TransferRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_index_index_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*TransferChunk); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -430,7 +1430,7 @@ func file_index_index_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_index_index_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   5,
+			NumMessages:   17,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -27,6 +27,14 @@ type IndexServiceClient interface {
 	AddDoc(ctx context.Context, in *search.Document, opts ...grpc.CallOption) (*AffectedCount, error)
 	Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchResult, error)
 	Count(ctx context.Context, in *CountRequest, opts ...grpc.CallOption) (*AffectedCount, error)
+	SearchVector(ctx context.Context, in *VectorSearchRequest, opts ...grpc.CallOption) (*SearchResult, error)
+	MoreLikeThis(ctx context.Context, in *MoreLikeThisRequest, opts ...grpc.CallOption) (*MoreLikeThisResult, error)
+	GetDoc(ctx context.Context, in *DocId, opts ...grpc.CallOption) (*GetDocResult, error)
+	MultiGetDoc(ctx context.Context, in *DocIds, opts ...grpc.CallOption) (*MultiGetDocResult, error)
+	Bulk(ctx context.Context, opts ...grpc.CallOption) (IndexService_BulkClient, error)
+	Transfer(ctx context.Context, in *TransferRequest, opts ...grpc.CallOption) (IndexService_TransferClient, error)
+	SearchStream(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (IndexService_SearchStreamClient, error)
+	SearchVectorStream(ctx context.Context, in *VectorSearchRequest, opts ...grpc.CallOption) (IndexService_SearchVectorStreamClient, error)
 }
 
 type indexServiceClient struct {
@@ -73,6 +81,172 @@ func (c *indexServiceClient) Count(ctx context.Context, in *CountRequest, opts .
 	return out, nil
 }
 
+func (c *indexServiceClient) SearchVector(ctx context.Context, in *VectorSearchRequest, opts ...grpc.CallOption) (*SearchResult, error) {
+	out := new(SearchResult)
+	err := c.cc.Invoke(ctx, "/index_service.IndexService/SearchVector", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *indexServiceClient) MoreLikeThis(ctx context.Context, in *MoreLikeThisRequest, opts ...grpc.CallOption) (*MoreLikeThisResult, error) {
+	out := new(MoreLikeThisResult)
+	err := c.cc.Invoke(ctx, "/index_service.IndexService/MoreLikeThis", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *indexServiceClient) GetDoc(ctx context.Context, in *DocId, opts ...grpc.CallOption) (*GetDocResult, error) {
+	out := new(GetDocResult)
+	err := c.cc.Invoke(ctx, "/index_service.IndexService/GetDoc", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *indexServiceClient) MultiGetDoc(ctx context.Context, in *DocIds, opts ...grpc.CallOption) (*MultiGetDocResult, error) {
+	out := new(MultiGetDocResult)
+	err := c.cc.Invoke(ctx, "/index_service.IndexService/MultiGetDoc", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *indexServiceClient) Bulk(ctx context.Context, opts ...grpc.CallOption) (IndexService_BulkClient, error) {
+	stream, err := c.cc.NewStream(ctx, &IndexService_ServiceDesc.Streams[0], "/index_service.IndexService/Bulk", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &indexServiceBulkClient{stream}
+	return x, nil
+}
+
+type IndexService_BulkClient interface {
+	Send(*BulkOperation) error
+	CloseAndRecv() (*BulkResponse, error)
+	grpc.ClientStream
+}
+
+type indexServiceBulkClient struct {
+	grpc.ClientStream
+}
+
+func (x *indexServiceBulkClient) Send(m *BulkOperation) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *indexServiceBulkClient) CloseAndRecv() (*BulkResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(BulkResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *indexServiceClient) Transfer(ctx context.Context, in *TransferRequest, opts ...grpc.CallOption) (IndexService_TransferClient, error) {
+	stream, err := c.cc.NewStream(ctx, &IndexService_ServiceDesc.Streams[1], "/index_service.IndexService/Transfer", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &indexServiceTransferClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type IndexService_TransferClient interface {
+	Recv() (*TransferChunk, error)
+	grpc.ClientStream
+}
+
+type indexServiceTransferClient struct {
+	grpc.ClientStream
+}
+
+func (x *indexServiceTransferClient) Recv() (*TransferChunk, error) {
+	m := new(TransferChunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *indexServiceClient) SearchStream(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (IndexService_SearchStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &IndexService_ServiceDesc.Streams[2], "/index_service.IndexService/SearchStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &indexServiceSearchStreamClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type IndexService_SearchStreamClient interface {
+	Recv() (*SearchResult, error)
+	grpc.ClientStream
+}
+
+type indexServiceSearchStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *indexServiceSearchStreamClient) Recv() (*SearchResult, error) {
+	m := new(SearchResult)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *indexServiceClient) SearchVectorStream(ctx context.Context, in *VectorSearchRequest, opts ...grpc.CallOption) (IndexService_SearchVectorStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &IndexService_ServiceDesc.Streams[3], "/index_service.IndexService/SearchVectorStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &indexServiceSearchVectorStreamClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type IndexService_SearchVectorStreamClient interface {
+	Recv() (*SearchResult, error)
+	grpc.ClientStream
+}
+
+type indexServiceSearchVectorStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *indexServiceSearchVectorStreamClient) Recv() (*SearchResult, error) {
+	m := new(SearchResult)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // IndexServiceServer is the server API for IndexService service.
 // All implementations must embed UnimplementedIndexServiceServer
 // for forward compatibility
@@ -81,6 +255,14 @@ type IndexServiceServer interface {
 	AddDoc(context.Context, *search.Document) (*AffectedCount, error)
 	Search(context.Context, *SearchRequest) (*SearchResult, error)
 	Count(context.Context, *CountRequest) (*AffectedCount, error)
+	SearchVector(context.Context, *VectorSearchRequest) (*SearchResult, error)
+	MoreLikeThis(context.Context, *MoreLikeThisRequest) (*MoreLikeThisResult, error)
+	GetDoc(context.Context, *DocId) (*GetDocResult, error)
+	MultiGetDoc(context.Context, *DocIds) (*MultiGetDocResult, error)
+	Bulk(IndexService_BulkServer) error
+	Transfer(*TransferRequest, IndexService_TransferServer) error
+	SearchStream(*SearchRequest, IndexService_SearchStreamServer) error
+	SearchVectorStream(*VectorSearchRequest, IndexService_SearchVectorStreamServer) error
 	mustEmbedUnimplementedIndexServiceServer()
 }
 
@@ -100,6 +282,30 @@ func (UnimplementedIndexServiceServer) Search(context.Context, *SearchRequest) (
 func (UnimplementedIndexServiceServer) Count(context.Context, *CountRequest) (*AffectedCount, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Count not implemented")
 }
+func (UnimplementedIndexServiceServer) SearchVector(context.Context, *VectorSearchRequest) (*SearchResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchVector not implemented")
+}
+func (UnimplementedIndexServiceServer) MoreLikeThis(context.Context, *MoreLikeThisRequest) (*MoreLikeThisResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MoreLikeThis not implemented")
+}
+func (UnimplementedIndexServiceServer) GetDoc(context.Context, *DocId) (*GetDocResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDoc not implemented")
+}
+func (UnimplementedIndexServiceServer) MultiGetDoc(context.Context, *DocIds) (*MultiGetDocResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MultiGetDoc not implemented")
+}
+func (UnimplementedIndexServiceServer) Bulk(IndexService_BulkServer) error {
+	return status.Errorf(codes.Unimplemented, "method Bulk not implemented")
+}
+func (UnimplementedIndexServiceServer) Transfer(*TransferRequest, IndexService_TransferServer) error {
+	return status.Errorf(codes.Unimplemented, "method Transfer not implemented")
+}
+func (UnimplementedIndexServiceServer) SearchStream(*SearchRequest, IndexService_SearchStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method SearchStream not implemented")
+}
+func (UnimplementedIndexServiceServer) SearchVectorStream(*VectorSearchRequest, IndexService_SearchVectorStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method SearchVectorStream not implemented")
+}
 func (UnimplementedIndexServiceServer) mustEmbedUnimplementedIndexServiceServer() {}
 
 // UnsafeIndexServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -185,6 +391,167 @@ func _IndexService_Count_Handler(srv interface{}, ctx context.Context, dec func(
 	return interceptor(ctx, in, info, handler)
 }
 
+func _IndexService_SearchVector_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VectorSearchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IndexServiceServer).SearchVector(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/index_service.IndexService/SearchVector",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IndexServiceServer).SearchVector(ctx, req.(*VectorSearchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IndexService_MoreLikeThis_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MoreLikeThisRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IndexServiceServer).MoreLikeThis(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/index_service.IndexService/MoreLikeThis",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IndexServiceServer).MoreLikeThis(ctx, req.(*MoreLikeThisRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IndexService_GetDoc_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DocId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IndexServiceServer).GetDoc(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/index_service.IndexService/GetDoc",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IndexServiceServer).GetDoc(ctx, req.(*DocId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IndexService_MultiGetDoc_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DocIds)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IndexServiceServer).MultiGetDoc(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/index_service.IndexService/MultiGetDoc",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IndexServiceServer).MultiGetDoc(ctx, req.(*DocIds))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _IndexService_Bulk_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(IndexServiceServer).Bulk(&indexServiceBulkServer{stream})
+}
+
+type IndexService_BulkServer interface {
+	SendAndClose(*BulkResponse) error
+	Recv() (*BulkOperation, error)
+	grpc.ServerStream
+}
+
+type indexServiceBulkServer struct {
+	grpc.ServerStream
+}
+
+func (x *indexServiceBulkServer) SendAndClose(m *BulkResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *indexServiceBulkServer) Recv() (*BulkOperation, error) {
+	m := new(BulkOperation)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _IndexService_Transfer_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(TransferRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(IndexServiceServer).Transfer(m, &indexServiceTransferServer{stream})
+}
+
+type IndexService_TransferServer interface {
+	Send(*TransferChunk) error
+	grpc.ServerStream
+}
+
+type indexServiceTransferServer struct {
+	grpc.ServerStream
+}
+
+func (x *indexServiceTransferServer) Send(m *TransferChunk) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _IndexService_SearchStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SearchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(IndexServiceServer).SearchStream(m, &indexServiceSearchStreamServer{stream})
+}
+
+type IndexService_SearchStreamServer interface {
+	Send(*SearchResult) error
+	grpc.ServerStream
+}
+
+type indexServiceSearchStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *indexServiceSearchStreamServer) Send(m *SearchResult) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _IndexService_SearchVectorStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(VectorSearchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(IndexServiceServer).SearchVectorStream(m, &indexServiceSearchVectorStreamServer{stream})
+}
+
+type IndexService_SearchVectorStreamServer interface {
+	Send(*SearchResult) error
+	grpc.ServerStream
+}
+
+type indexServiceSearchVectorStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *indexServiceSearchVectorStreamServer) Send(m *SearchResult) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // IndexService_ServiceDesc is the grpc.ServiceDesc for IndexService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -208,7 +575,44 @@ var IndexService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Count",
 			Handler:    _IndexService_Count_Handler,
 		},
+		{
+			MethodName: "SearchVector",
+			Handler:    _IndexService_SearchVector_Handler,
+		},
+		{
+			MethodName: "MoreLikeThis",
+			Handler:    _IndexService_MoreLikeThis_Handler,
+		},
+		{
+			MethodName: "GetDoc",
+			Handler:    _IndexService_GetDoc_Handler,
+		},
+		{
+			MethodName: "MultiGetDoc",
+			Handler:    _IndexService_MultiGetDoc_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Bulk",
+			Handler:       _IndexService_Bulk_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Transfer",
+			Handler:       _IndexService_Transfer_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "SearchStream",
+			Handler:       _IndexService_SearchStream_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "SearchVectorStream",
+			Handler:       _IndexService_SearchVectorStream_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "index/index.proto",
 }
@@ -25,15 +25,17 @@ type Product struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Id            string   `protobuf:"bytes,1,opt,name=Id,proto3" json:"Id,omitempty"`
-	Name          string   `protobuf:"bytes,2,opt,name=Name,proto3" json:"Name,omitempty"`
-	Image         string   `protobuf:"bytes,3,opt,name=Image,proto3" json:"Image,omitempty"`
-	Category      string   `protobuf:"bytes,4,opt,name=Category,proto3" json:"Category,omitempty"`
-	Ratings       float64  `protobuf:"fixed64,5,opt,name=Ratings,proto3" json:"Ratings,omitempty"`
-	NoRatings     int32    `protobuf:"varint,6,opt,name=NoRatings,proto3" json:"NoRatings,omitempty"`
-	DiscountPrice float64  `protobuf:"fixed64,7,opt,name=DiscountPrice,proto3" json:"DiscountPrice,omitempty"`
-	ActualPrice   float64  `protobuf:"fixed64,8,opt,name=ActualPrice,proto3" json:"ActualPrice,omitempty"`
-	Keywords      []string `protobuf:"bytes,9,rep,name=Keywords,proto3" json:"Keywords,omitempty"`
+	Id            string    `protobuf:"bytes,1,opt,name=Id,proto3" json:"Id,omitempty"`
+	Name          string    `protobuf:"bytes,2,opt,name=Name,proto3" json:"Name,omitempty"`
+	Image         string    `protobuf:"bytes,3,opt,name=Image,proto3" json:"Image,omitempty"`
+	Category      string    `protobuf:"bytes,4,opt,name=Category,proto3" json:"Category,omitempty"`
+	Ratings       float64   `protobuf:"fixed64,5,opt,name=Ratings,proto3" json:"Ratings,omitempty"`
+	NoRatings     int32     `protobuf:"varint,6,opt,name=NoRatings,proto3" json:"NoRatings,omitempty"`
+	DiscountPrice float64   `protobuf:"fixed64,7,opt,name=DiscountPrice,proto3" json:"DiscountPrice,omitempty"`
+	ActualPrice   float64   `protobuf:"fixed64,8,opt,name=ActualPrice,proto3" json:"ActualPrice,omitempty"`
+	Keywords      []string  `protobuf:"bytes,9,rep,name=Keywords,proto3" json:"Keywords,omitempty"`
+	Embedding     []float32 `protobuf:"fixed32,10,rep,packed,name=Embedding,proto3" json:"Embedding,omitempty"`
+	SubCategory   string    `protobuf:"bytes,11,opt,name=SubCategory,proto3" json:"SubCategory,omitempty"`
 }
 
 func (x *Product) Reset() {
@@ -131,11 +133,25 @@ func (x *Product) GetKeywords() []string {
 	return nil
 }
 
+func (x *Product) GetEmbedding() []float32 {
+	if x != nil {
+		return x.Embedding
+	}
+	return nil
+}
+
+func (x *Product) GetSubCategory() string {
+	if x != nil {
+		return x.SubCategory
+	}
+	return ""
+}
+
 var File_product_proto protoreflect.FileDescriptor
 
 var file_product_proto_rawDesc = []byte{
 	0x0a, 0x0d, 0x70, 0x72, 0x6f, 0x64, 0x75, 0x63, 0x74, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12,
-	0x06, 0x73, 0x65, 0x61, 0x72, 0x63, 0x68, 0x22, 0xfb, 0x01, 0x0a, 0x07, 0x50, 0x72, 0x6f, 0x64,
+	0x06, 0x73, 0x65, 0x61, 0x72, 0x63, 0x68, 0x22, 0xbb, 0x02, 0x0a, 0x07, 0x50, 0x72, 0x6f, 0x64,
 	0x75, 0x63, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x49, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
 	0x02, 0x49, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x4e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
 	0x09, 0x52, 0x04, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x49, 0x6d, 0x61, 0x67, 0x65,
@@ -151,7 +167,11 @@ var file_product_proto_rawDesc = []byte{
 	0x6c, 0x50, 0x72, 0x69, 0x63, 0x65, 0x18, 0x08, 0x20, 0x01, 0x28, 0x01, 0x52, 0x0b, 0x41, 0x63,
 	0x74, 0x75, 0x61, 0x6c, 0x50, 0x72, 0x69, 0x63, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x4b, 0x65, 0x79,
 	0x77, 0x6f, 0x72, 0x64, 0x73, 0x18, 0x09, 0x20, 0x03, 0x28, 0x09, 0x52, 0x08, 0x4b, 0x65, 0x79,
-	0x77, 0x6f, 0x72, 0x64, 0x73, 0x42, 0x0a, 0x5a, 0x08, 0x2e, 0x3b, 0x73, 0x65, 0x61, 0x72, 0x63,
+	0x77, 0x6f, 0x72, 0x64, 0x73, 0x12, 0x1c, 0x0a, 0x09, 0x45, 0x6d, 0x62, 0x65, 0x64, 0x64, 0x69,
+	0x6e, 0x67, 0x18, 0x0a, 0x20, 0x03, 0x28, 0x02, 0x52, 0x09, 0x45, 0x6d, 0x62, 0x65, 0x64, 0x64,
+	0x69, 0x6e, 0x67, 0x12, 0x20, 0x0a, 0x0b, 0x53, 0x75, 0x62, 0x43, 0x61, 0x74, 0x65, 0x67, 0x6f,
+	0x72, 0x79, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x53, 0x75, 0x62, 0x43, 0x61, 0x74,
+	0x65, 0x67, 0x6f, 0x72, 0x79, 0x42, 0x0a, 0x5a, 0x08, 0x2e, 0x3b, 0x73, 0x65, 0x61, 0x72, 0x63,
 	0x68, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
@@ -8,6 +8,11 @@ func NewTermQuery(field, keyword string) *TermQuery {
 	return &TermQuery{Keyword: &Keyword{Field: field, Word: keyword}} // Only one of Keyword, Must, Should is non-nil
 }
 
+// NewWeightedTermQuery creates a Keyword query whose matches are boosted by weight when scoring
+func NewWeightedTermQuery(field, keyword string, weight float32) *TermQuery {
+	return &TermQuery{Keyword: &Keyword{Field: field, Word: keyword}, Weight: weight}
+}
+
 func (q TermQuery) Empty() bool {
 	return q.Keyword == nil && len(q.Must) == 0 && len(q.Should) == 0
 }
@@ -87,3 +92,74 @@ func (q TermQuery) ToString() string {
 	}
 	return ""
 }
+
+// Match evaluates the query against a set of keywords (keyed by Keyword.ToString) and returns the matched branches
+func (q *TermQuery) Match(keywords map[string]struct{}) (bool, []string) {
+	if q.Keyword != nil {
+		key := q.Keyword.ToString()
+		if _, exists := keywords[key]; exists {
+			return true, []string{key}
+		}
+		return false, nil
+	} else if len(q.Must) > 0 {
+		branches := make([]string, 0, len(q.Must))
+		for _, e := range q.Must {
+			matched, sub := e.Match(keywords)
+			if !matched {
+				return false, nil // every branch of Must has to match
+			}
+			branches = append(branches, sub...)
+		}
+		if len(q.Must) > 1 {
+			branches = append(branches, q.ToString())
+		}
+		return true, branches
+	} else if len(q.Should) > 0 {
+		branches := make([]string, 0, len(q.Should))
+		for _, e := range q.Should {
+			if matched, sub := e.Match(keywords); matched {
+				branches = append(branches, sub...)
+			}
+		}
+		if len(branches) == 0 {
+			return false, nil
+		}
+		if len(q.Should) > 1 {
+			branches = append(branches, q.ToString())
+		}
+		return true, branches
+	}
+
+	return false, nil
+}
+
+// Score sums the weights of the matched Keyword queries, it is 0 if the keywords do not satisfy the query
+func (q *TermQuery) Score(keywords map[string]struct{}) float32 {
+	if q.Keyword != nil {
+		if _, exists := keywords[q.Keyword.ToString()]; !exists {
+			return 0
+		}
+		if q.Weight == 0 {
+			return 1
+		}
+		return q.Weight
+	} else if len(q.Must) > 0 {
+		var score float32
+		for _, e := range q.Must {
+			s := e.Score(keywords)
+			if s == 0 {
+				return 0 // every branch of Must has to match
+			}
+			score += s
+		}
+		return score
+	} else if len(q.Should) > 0 {
+		var score float32
+		for _, e := range q.Should {
+			score += e.Score(keywords)
+		}
+		return score
+	}
+
+	return 0
+}
@@ -25,9 +25,11 @@ type TermQuery struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
+	// Only one of three attrs is non-nil
 	Keyword *Keyword     `protobuf:"bytes,1,opt,name=Keyword,proto3" json:"Keyword,omitempty"`
 	Must    []*TermQuery `protobuf:"bytes,2,rep,name=Must,proto3" json:"Must,omitempty"`
 	Should  []*TermQuery `protobuf:"bytes,3,rep,name=Should,proto3" json:"Should,omitempty"`
+	Weight  float32      `protobuf:"fixed32,4,opt,name=Weight,proto3" json:"Weight,omitempty"` // boost of a Keyword query when scoring matched branches, 0 means 1
 }
 
 func (x *TermQuery) Reset() {
@@ -83,13 +85,20 @@ func (x *TermQuery) GetShould() []*TermQuery {
 	return nil
 }
 
+func (x *TermQuery) GetWeight() float32 {
+	if x != nil {
+		return x.Weight
+	}
+	return 0
+}
+
 var File_search_term_query_proto protoreflect.FileDescriptor
 
 var file_search_term_query_proto_rawDesc = []byte{
 	0x0a, 0x17, 0x73, 0x65, 0x61, 0x72, 0x63, 0x68, 0x2f, 0x74, 0x65, 0x72, 0x6d, 0x5f, 0x71, 0x75,
 	0x65, 0x72, 0x79, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x06, 0x73, 0x65, 0x61, 0x72, 0x63,
 	0x68, 0x1a, 0x10, 0x73, 0x65, 0x61, 0x72, 0x63, 0x68, 0x2f, 0x64, 0x6f, 0x63, 0x2e, 0x70, 0x72,
-	0x6f, 0x74, 0x6f, 0x22, 0x88, 0x01, 0x0a, 0x09, 0x54, 0x65, 0x72, 0x6d, 0x51, 0x75, 0x65, 0x72,
+	0x6f, 0x74, 0x6f, 0x22, 0xa0, 0x01, 0x0a, 0x09, 0x54, 0x65, 0x72, 0x6d, 0x51, 0x75, 0x65, 0x72,
 	0x79, 0x12, 0x29, 0x0a, 0x07, 0x4b, 0x65, 0x79, 0x77, 0x6f, 0x72, 0x64, 0x18, 0x01, 0x20, 0x01,
 	0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x73, 0x65, 0x61, 0x72, 0x63, 0x68, 0x2e, 0x4b, 0x65, 0x79, 0x77,
 	0x6f, 0x72, 0x64, 0x52, 0x07, 0x4b, 0x65, 0x79, 0x77, 0x6f, 0x72, 0x64, 0x12, 0x25, 0x0a, 0x04,
@@ -97,9 +106,10 @@ var file_search_term_query_proto_rawDesc = []byte{
 	0x72, 0x63, 0x68, 0x2e, 0x54, 0x65, 0x72, 0x6d, 0x51, 0x75, 0x65, 0x72, 0x79, 0x52, 0x04, 0x4d,
 	0x75, 0x73, 0x74, 0x12, 0x29, 0x0a, 0x06, 0x53, 0x68, 0x6f, 0x75, 0x6c, 0x64, 0x18, 0x03, 0x20,
 	0x03, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x73, 0x65, 0x61, 0x72, 0x63, 0x68, 0x2e, 0x54, 0x65, 0x72,
-	0x6d, 0x51, 0x75, 0x65, 0x72, 0x79, 0x52, 0x06, 0x53, 0x68, 0x6f, 0x75, 0x6c, 0x64, 0x42, 0x0a,
-	0x5a, 0x08, 0x2e, 0x3b, 0x73, 0x65, 0x61, 0x72, 0x63, 0x68, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74,
-	0x6f, 0x33,
+	0x6d, 0x51, 0x75, 0x65, 0x72, 0x79, 0x52, 0x06, 0x53, 0x68, 0x6f, 0x75, 0x6c, 0x64, 0x12, 0x16,
+	0x0a, 0x06, 0x57, 0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x02, 0x52, 0x06,
+	0x57, 0x65, 0x69, 0x67, 0x68, 0x74, 0x42, 0x0a, 0x5a, 0x08, 0x2e, 0x3b, 0x73, 0x65, 0x61, 0x72,
+	0x63, 0x68, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
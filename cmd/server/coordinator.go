@@ -0,0 +1,62 @@
+package main
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/category"
+)
+
+// CoordinatorMain builds the index of the running workers from the source files and exits. With -dbPath the build is
+// checkpointed in <dbPath>_build, running the coordinator again after a failure resumes it.
+func CoordinatorMain() {
+	categories, err := category.NewEtcdRegistry(etcdServers, category.CATEGORY_ROOT_PATH)
+	if err != nil {
+		panic(err)
+	}
+	defer categories.Close()
+	sentinel := indexing.NewSentinel(etcdServers, indexing.DEFAULT_COLLECTION)
+	defer sentinel.Close()
+	coordinator, err := indexing.NewIngestCoordinator(etcdServers, sentinel)
+	if err != nil {
+		panic(err)
+	}
+	defer coordinator.Close()
+
+	options := buildOptions()
+	options.Embeddings, options.Categories = loadEmbeddings(), categories
+	if len(*dbPath) > 0 {
+		checkpoints, err := indexing.OpenCheckpoints(dbType, *dbPath+"_build")
+		if err != nil {
+			panic(err)
+		}
+		defer checkpoints.Close()
+		options.Checkpoints = checkpoints
+	}
+
+	report, err := coordinator.Run(csvFilesDir, options)
+	if err != nil {
+		log.Printf("coordinated build failed: %s", err)
+		os.Exit(1)
+	}
+	if !report.Complete {
+		log.Printf("coordinated build is not complete")
+		os.Exit(1)
+	}
+
+	// with -watch the coordinator keeps streaming the changes of the data directory to the workers
+	if *watch {
+		if len(*dbPath) == 0 {
+			log.Printf("the coordinator keeps the state of watched files in <dbPath>_watch, -watch needs -dbPath")
+			os.Exit(1)
+		}
+		startWatcher(sentinel, *dbPath, options)
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+		<-sigCh
+		watcher.Close()
+	}
+}
@@ -9,12 +9,14 @@ import (
 	"syscall"
 	
 	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/category"
 	"github.com/m1i3k0e7/distributed-search-engine/api/proto/index"
 	"github.com/m1i3k0e7/distributed-search-engine/pkg/logger"
 	"google.golang.org/grpc"
 )
 
 var service *indexing.IndexServiceWorker //IndexWorker is a gRPC server that provides indexing services
+var workerCategories category.ICategoryRegistry // category ids are shared by all workers through etcd
 
 func GrpcIndexerInit() {
 	// listen on a specific port
@@ -27,12 +29,32 @@ func GrpcIndexerInit() {
 	service = new(indexing.IndexServiceWorker)
 	// Initialize the indexer service
 	service.Init(50000, dbType, *dbPath+"_part"+strconv.Itoa(*workerIndex))
-	if *rebuildIndex {
-		logger.Log.Printf("totalWorkers=%d, workerIndex=%d", *totalWorkers, *workerIndex)
-		indexing.BuildIndexFromDir(csvFilesDir, service.Indexer, *totalWorkers, *workerIndex) // rebuild index from csv files in the directory
-		// indexing.BuildIndexFromFile(csvFile, service.Indexer, *totalWorkers, *workerIndex) // rebuild index from csv file
-	} else {
-		service.Indexer.LoadFromIndexFile() // load index from file
+	// the worker claims its shards before building, it only builds the documents of its shards
+	selfAddr, err := indexing.WorkerAddress(*port)
+	if err != nil {
+		panic(err)
+	}
+	shardMap, err := indexing.GetShardRouter(etcdServers).Claim(selfAddr, *workerIndex, max(*totalWorkers, 1), *replication)
+	if err != nil {
+		panic(err)
+	}
+	logger.Log.Printf("totalWorkers=%d, workerIndex=%d, worker %s hosts shards %v", *totalWorkers, *workerIndex, selfAddr, shardMap.ShardsOf(selfAddr))
+	options := func() indexing.BuildOptions {
+		if workerCategories == nil {
+			workerCategories, err = category.NewEtcdRegistry(etcdServers, category.CATEGORY_ROOT_PATH)
+			if err != nil {
+				panic(err)
+			}
+		}
+		options := buildOptions()
+		options.Embeddings, options.Categories = loadEmbeddings(), workerCategories
+		options.Shards, options.Worker = indexing.GetShardRouter(etcdServers), selfAddr
+		return options
+	}
+	// the worker registers in etcd only once its index is complete
+	buildIndex(service.Indexer, *dbPath+"_part"+strconv.Itoa(*workerIndex), options)
+	if *watch {
+		startWatcher(service.Indexer, *dbPath+"_part"+strconv.Itoa(*workerIndex), options())
 	}
 
 	// Register the service with the gRPC server
@@ -52,7 +74,13 @@ func GrpcIndexerTeardown() {
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 	<-sigCh
+	if watcher != nil {
+		watcher.Close()
+	}
 	service.Close() // close the service after receiving the signal
+	if workerCategories != nil {
+		workerCategories.Close()
+	}
 	os.Exit(0)
 }
 
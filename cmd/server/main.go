@@ -2,33 +2,123 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/m1i3k0e7/distributed-search-engine/internal/config"
 	"github.com/m1i3k0e7/distributed-search-engine/internal/handler"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/schema"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/source"
 	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/kvdb"
+	"github.com/m1i3k0e7/distributed-search-engine/pkg/ranking"
 	"github.com/rs/cors"
 )
 
 var (
-	mode         = flag.Int("mode", 1, "1-standalone web server, 2-grpc index server, 3-distributed web server")
-	rebuildIndex = flag.Bool("index", false, "rebuild index from csv file when server starting")
-	port         = flag.Int("port", 0, "port for web server or grpc index server")
-	dbPath       = flag.String("dbPath", "", "path to the local kvdb database")
-	totalWorkers = flag.Int("totalWorkers", 0, "total number of index workers in the distributed system")
-	workerIndex  = flag.Int("workerIndex", 0, "index worker id in the distributed system")
-	trieDBPath   = "../../internal/indexing/trie/storage/trie_bolt" // Path to the trie database file
+	mode          = flag.Int("mode", 1, "1-standalone web server, 2-grpc index server, 3-distributed web server, 4-ingestion coordinator, 5-shard rebalancer")
+	rebuildIndex  = flag.Bool("index", false, "rebuild index from csv file when server starting")
+	port          = flag.Int("port", 0, "port for web server or grpc index server")
+	dbPath        = flag.String("dbPath", "", "path to the local kvdb database")
+	totalWorkers  = flag.Int("totalWorkers", 0, "total number of index workers in the distributed system, the shards of a new cluster are split among them")
+	workerIndex   = flag.Int("workerIndex", 0, "index worker id in the distributed system, it hosts replicas of the shards s with s % totalWorkers in [workerIndex-replication+1, workerIndex]")
+	replication   = flag.Int("replication", 1, "replicas of every shard, fixed by the first worker of the cluster")
+	embeddingFile = flag.String("embeddings", "", "csv file of precomputed product embeddings, used when rebuilding index")
+	rankingFile   = flag.String("ranking", "", "json file of BM25F field weights and length normalizations")
+	schemaName    = flag.String("schema", "product", "built-in schema (product, video) or json file of the document schema")
+	collection    = flag.String("collection", "default", "collection or alias served by /search, /products and /categories")
+	idField       = flag.String("idField", "", "field whose value is the document id when building the index")
+	mappingFile   = flag.String("mapping", "", "json file mapping the columns of the source files to fields, the Amazon csv header is mapped for the product schema")
+	idFields      = flag.String("idFields", "", "comma separated fields hashed into the document id, the whole row is hashed if both id flags are empty")
+	parallelism   = flag.Int("buildParallelism", 0, "goroutines analyzing rows when building the index, the number of CPUs if 0")
+	watch         = flag.Bool("watch", false, "keep ingesting new and modified source files of the data directory, or keep rebalancing the shards in mode 5")
+	searchTimeout = flag.Duration("searchTimeout", 3*time.Second, "deadline of a search that names no timeoutMs, the shards that did not answer by then are left out")
+	watchInterval = flag.Duration("watchInterval", indexing.WATCH_POLL_INTERVAL, "interval between two scans of the data directory, or of the alive workers in mode 5, in watch mode")
 )
 
 var (
 	dbType      = kvdb.BOLT
-	csvFilesDir     = config.RootPath + "/../data/archive"
+	csvFilesDir = config.RootPath + "/../data/archive"
 	etcdServers = []string{"127.0.0.1:2379"}
+	docSchema   *schema.Schema      // fields of the documents of the default collection
+	docRanking  ranking.BM25FConfig // field weights of the default collection
+	docMapping  *source.Mapping     // columns of the source files of the default collection
+	watcher     *indexing.Watcher   // ingests the changes of the data directory with -watch
 )
 
+// trieDBPath is the file of the query suggestions, kept next to the index rather than in the source tree
+func trieDBPath() string {
+	return *dbPath + "_trie"
+}
+
+// buildOptions returns the options of the index build shared by all modes
+func buildOptions() indexing.BuildOptions {
+	options := indexing.BuildOptions{Schema: docSchema, Mapping: docMapping, IdField: *idField, Parallelism: *parallelism, TrieDBPath: trieDBPath()}
+	if len(*idFields) > 0 {
+		options.IdFields = strings.Split(*idFields, ",")
+	}
+	return options
+}
+
+// buildIndex rebuilds the index if requested, resumes an unfinished build otherwise, or loads the index built before.
+// The build progress is kept in <path>_build, the server never starts serving a half-built index.
+func buildIndex(indexer *indexing.Indexer, path string, options func() indexing.BuildOptions) {
+	checkpoints, err := indexing.OpenCheckpoints(dbType, path+"_build")
+	if err != nil {
+		panic(err)
+	}
+	defer checkpoints.Close()
+
+	resume := checkpoints.Building()
+	if !*rebuildIndex && !resume {
+		indexer.LoadFromIndexFile() // load index from file
+		return
+	}
+	if resume {
+		log.Printf("resume the unfinished build of %s", path)
+		indexer.LoadFromIndexFile() // documents indexed before the crash
+	}
+	buildOptions := options()
+	buildOptions.Checkpoints = checkpoints
+	indexing.BuildIndexFromDir(csvFilesDir, indexer, buildOptions) // rebuild index from csv files in the directory
+	if checkpoints.Building() {
+		panic(fmt.Errorf("build of %s is not complete", path))
+	}
+}
+
+// startWatcher keeps indexer up to date with the data directory if -watch is set, the state of the ingested files is kept in <path>_watch
+func startWatcher(indexer indexing.IIndexer, path string, options indexing.BuildOptions) {
+	if !*watch {
+		return
+	}
+	var err error
+	watcher, err = indexing.NewWatcher(csvFilesDir, indexer, options, dbType, path+"_watch", *watchInterval)
+	if err != nil {
+		panic(err)
+	}
+	watcher.Start()
+	log.Printf("watch %s for new and modified files", csvFilesDir)
+}
+
+// loadEmbeddings reads the embedding file if there is one, the index is built without vectors otherwise
+func loadEmbeddings() map[string][]float32 {
+	if len(*embeddingFile) == 0 {
+		return nil
+	}
+
+	embeddings, err := indexing.LoadEmbeddings(*embeddingFile)
+	if err != nil {
+		log.Printf("load embeddings from %s failed: %s", *embeddingFile, err)
+	}
+
+	return embeddings
+}
+
 func StartGin() {
 	engine := gin.Default()
 	gin.SetMode(gin.ReleaseMode)
@@ -37,10 +127,39 @@ func StartGin() {
 
 	engine.POST("/search", handler.SearchAll)
 	engine.POST("/associate", handler.AssociateQuery)
+	engine.GET("/products/:id/similar", handler.SimilarProducts)
+	engine.GET("/products/:id", handler.GetDocument)
+	engine.PUT("/products/:id", handler.PutDocument)
+	engine.PATCH("/products/:id", handler.PatchDocument)
+	engine.DELETE("/products/:id", handler.DeleteDocument)
+	engine.POST("/_bulk", handler.Bulk)
+	engine.POST("/_mget", handler.MultiGetDocuments)
+	engine.GET("/categories", handler.CategoryTree)
+
+	engine.GET("/collections", handler.ListCollections)
+	engine.GET("/collections/:collection", handler.GetCollection)
+	engine.PUT("/collections/:collection", handler.CreateCollection)
+	engine.DELETE("/collections/:collection", handler.DropCollection)
+	engine.POST("/collections/:collection/search", handler.SearchAll)
+	engine.GET("/collections/:collection/docs/:id/similar", handler.SimilarProducts)
+	engine.GET("/collections/:collection/docs/:id", handler.GetDocument)
+	engine.PUT("/collections/:collection/docs/:id", handler.PutDocument)
+	engine.PATCH("/collections/:collection/docs/:id", handler.PatchDocument)
+	engine.DELETE("/collections/:collection/docs/:id", handler.DeleteDocument)
+	engine.POST("/collections/:collection/_bulk", handler.Bulk)
+	engine.POST("/collections/:collection/_mget", handler.MultiGetDocuments)
+	engine.GET("/collections/:collection/categories", handler.CategoryTree)
+	engine.POST("/collections/:collection/_build", handler.BuildCollection)
+
+	engine.GET("/_ingest", handler.IngestStatus)
+
+	engine.GET("/aliases", handler.ListAliases)
+	engine.PUT("/aliases/:alias", handler.SetAlias)
+	engine.DELETE("/aliases/:alias", handler.RemoveAlias)
 
 	c := cors.New(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:5173", "http://127.0.0.1:5173"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Origin", "Content-Type", "Accept", "Authorization"},
 		AllowCredentials: true,
 	})
@@ -56,12 +175,45 @@ func StartGin() {
 func main() {
 	flag.Parse()
 
+	var err error
+	docSchema, err = schema.GetSchema(*schemaName)
+	if err != nil {
+		log.Fatalf("load schema %s failed: %s", *schemaName, err)
+	}
+	docRanking = docSchema.RankingConfig()
+	if len(*mappingFile) > 0 {
+		docMapping, err = source.LoadMapping(*mappingFile)
+		if err != nil {
+			log.Fatalf("load mapping %s failed: %s", *mappingFile, err)
+		}
+	} else if *schemaName == "product" || len(*schemaName) == 0 {
+		docMapping = source.ProductMapping()
+	}
+
+	if len(*rankingFile) > 0 {
+		config, err := ranking.LoadBM25FConfig(*rankingFile, docRanking)
+		if err != nil {
+			log.Printf("load ranking config from %s failed: %s", *rankingFile, err)
+		}
+		docRanking = config
+	}
+
+	handler.DefaultCollection = *collection
+	handler.DataDir = csvFilesDir
+	handler.BuildDefaults = indexing.BuildOptions{Mapping: docMapping, IdField: *idField, IdFields: buildOptions().IdFields, Parallelism: *parallelism}
+	handler.LoadEmbeddings = loadEmbeddings
+	handler.SearchTimeout = *searchTimeout
+
 	switch *mode {
 	case 1, 3:
 		WebServerMain(*mode) //1. standalone mode 3：distributed mode
 		StartGin()
 	case 2:
 		GrpcIndexerMain() // 2: start grpc index server
+	case 4:
+		CoordinatorMain() // 4: build the index of the grpc index servers
+	case 5:
+		RebalancerMain() // 5: move shards between the grpc index servers
 	}
 }
 
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing"
+)
+
+// RebalancerMain spreads the shards evenly over the alive workers and exits. With -watch it keeps running and rebalances
+// whenever workers join or leave, once the set of workers settled.
+func RebalancerMain() {
+	rebalancer, err := indexing.NewRebalancer(etcdServers)
+	if err != nil {
+		panic(err)
+	}
+	defer rebalancer.Close()
+
+	if !*watch {
+		if _, err := rebalancer.Rebalance(); err != nil {
+			log.Printf("rebalance failed: %s", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	stop := make(chan struct{})
+	go func() {
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+		<-sigCh
+		close(stop)
+	}()
+	rebalancer.Watch(*watchInterval, stop)
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/m1i3k0e7/distributed-search-engine/internal/handler"
 	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/category"
 	storage "github.com/m1i3k0e7/distributed-search-engine/internal/indexing/trie"
 	// "github.com/m1i3k0e7/distributed-search-engine/pkg/logger"
 	// "github.com/m1i3k0e7/distributed-search-engine/internal/indexing/trie"
@@ -21,21 +22,50 @@ func WebServerInit(mode int) {
 			panic(err)
 		}
 
-		if *rebuildIndex {
-			indexing.BuildIndexFromDir(csvFilesDir, standaloneIndexer, *totalWorkers, *workerIndex) // rebuild index from csv files in the directory
-		} else {
-			standaloneIndexer.LoadFromIndexFile() // load index from file
+		categories, err := category.NewLocalRegistry(dbType, *dbPath+"_category") // category ids must survive restarts, the index refers to them
+		if err != nil {
+			panic(err)
+		}
+
+		options := func() indexing.BuildOptions {
+			options := buildOptions()
+			options.Embeddings, options.Categories = loadEmbeddings(), categories
+			return options
+		}
+		buildIndex(standaloneIndexer, *dbPath, options)
+		defaultCollection := &indexing.Collection{Name: indexing.DEFAULT_COLLECTION, Schema: docSchema, Ranking: docRanking, Indexer: standaloneIndexer, Categories: categories}
+		collections, err := indexing.NewLocalCollections(dbType, *dbPath, defaultCollection)
+		if err != nil {
+			panic(err)
 		}
-		handler.Indexer = standaloneIndexer
+		handler.Collections = collections
 		
-		standaloneTrieDB, err := storage.NewTrieDB(trieDBPath)
+		standaloneTrieDB, err := storage.NewTrieDB(trieDBPath())
 		if err != nil {
 			panic(err)
 		}
 		
 		handler.TrieDB = standaloneTrieDB // Set the trie database for the handler
+
+		watchOptions := options()
+		watchOptions.TrieDB = standaloneTrieDB // the server holds the trie db open
+		startWatcher(standaloneIndexer, *dbPath, watchOptions)
 	case 3:
-		handler.Indexer = indexing.NewSentinel(etcdServers) // Distributed indexer using sentinel
+		categories, err := category.NewEtcdRegistry(etcdServers, category.CATEGORY_ROOT_PATH)
+		if err != nil {
+			panic(err)
+		}
+		sentinel := indexing.NewSentinel(etcdServers, indexing.DEFAULT_COLLECTION) // Distributed indexer using sentinel
+		defaultCollection := &indexing.Collection{Name: indexing.DEFAULT_COLLECTION, Schema: docSchema, Ranking: docRanking, Indexer: sentinel, Categories: categories}
+		collections, err := indexing.NewEtcdCollections(etcdServers, defaultCollection)
+		if err != nil {
+			panic(err)
+		}
+		handler.Collections = collections
+		handler.Ingest, err = indexing.NewIngestMonitor(etcdServers)
+		if err != nil {
+			panic(err)
+		}
 	default:
 		panic("invalid mode")
 	}
@@ -46,7 +76,13 @@ func WebServerTeardown() {
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 	<-sigCh
-	handler.Indexer.Close() // close the indexer after receiving the signal
+	if watcher != nil {
+		watcher.Close()
+	}
+	handler.Collections.Close() // close the indexers after receiving the signal
+	if handler.Ingest != nil {
+		handler.Ingest.Close()
+	}
 	os.Exit(0)              // exit the program
 }
 
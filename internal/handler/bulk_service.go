@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	index_proto "github.com/m1i3k0e7/distributed-search-engine/api/proto/index"
+	search_proto "github.com/m1i3k0e7/distributed-search-engine/api/proto/search"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/schema"
+)
+
+const (
+	MAX_BULK_LINE = 16 << 20 // longest line of a bulk request
+)
+
+// BulkItem is one line of a bulk request, exactly one of the operations is set
+type BulkItem struct {
+	Index  map[string]any // document to index, replacing the document with the same id
+	Update map[string]any // fields to update of an existing document, as PATCH
+	Delete map[string]any // {"Id": ...} of the document to delete
+}
+
+type BulkItemResult struct {
+	Id     string
+	Status int
+	Error  string `json:",omitempty"`
+}
+
+type BulkResponse struct {
+	Errors bool // true if any operation failed
+	Items  []BulkItemResult
+}
+
+// bulkLine is a line of a bulk request waiting for the next batch
+type bulkLine struct {
+	id       string
+	item     BulkItem
+	position int // position of the line in BulkResponse.Items
+}
+
+// Bulk applies the newline-delimited json operations of the request body in batches of indexing.BULK_BATCH_SIZE,
+// and returns the result of each operation in order
+func Bulk(ctx *gin.Context) {
+	collection, exists := getCollection(ctx)
+	if !exists {
+		return
+	}
+
+	response := BulkResponse{Items: make([]BulkItemResult, 0)}
+	lines := make([]bulkLine, 0, indexing.BULK_BATCH_SIZE)
+	batch := make(map[string]bool) // ids of the documents in lines
+	flush := func() {
+		// the documents of all update lines are read at once
+		updateIds := make([]string, 0, len(lines))
+		for _, line := range lines {
+			if line.item.Update != nil {
+				updateIds = append(updateIds, line.id)
+			}
+		}
+		existing := make(map[string]*search_proto.Document, len(updateIds))
+		var readErr error
+		if len(updateIds) > 0 {
+			docs, err := collection.Indexer.MultiGetDoc(updateIds)
+			readErr = err
+			for _, doc := range docs {
+				existing[doc.Id] = doc
+			}
+		}
+
+		operations := make([]*index_proto.BulkOperation, 0, len(lines))
+		positions := make([]int, 0, len(lines)) // position of each operation in response.Items
+		for _, line := range lines {
+			item := &response.Items[line.position]
+			if line.item.Update != nil && readErr != nil {
+				item.Status, item.Error = http.StatusServiceUnavailable, readErr.Error()
+				continue
+			}
+			operation, status, err := bulkOperation(collection, line.id, line.item, existing[line.id])
+			if err != nil {
+				item.Status, item.Error = status, err.Error()
+				continue
+			}
+			operations = append(operations, operation)
+			positions = append(positions, line.position)
+		}
+		for i, result := range collection.Indexer.Bulk(operations) {
+			item := &response.Items[positions[i]]
+			switch {
+			case len(result.Error) > 0:
+				item.Status, item.Error = http.StatusServiceUnavailable, result.Error
+			case result.Count == 0:
+				item.Status, item.Error = http.StatusNotFound, "document not found"
+			default:
+				item.Status = http.StatusOK
+			}
+		}
+		lines = lines[:0]
+		clear(batch)
+	}
+
+	scanner := bufio.NewScanner(ctx.Request.Body)
+	scanner.Buffer(make([]byte, 0, 64<<10), MAX_BULK_LINE)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var item BulkItem
+		if err := json.Unmarshal(line, &item); err != nil {
+			response.Items = append(response.Items, BulkItemResult{Status: http.StatusBadRequest, Error: fmt.Sprintf("invalid operation: %s", err)})
+			continue
+		}
+		docId := bulkItemId(item)
+		if batch[docId] && item.Update != nil {
+			flush() // the update reads the document written by the batch
+		}
+
+		response.Items = append(response.Items, BulkItemResult{Id: docId})
+		lines = append(lines, bulkLine{id: docId, item: item, position: len(response.Items) - 1})
+		batch[docId] = true
+		if len(lines) == indexing.BULK_BATCH_SIZE {
+			flush()
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		response.Items = append(response.Items, BulkItemResult{Status: http.StatusBadRequest, Error: fmt.Sprintf("read request failed: %s", err)})
+	}
+	flush()
+
+	for _, item := range response.Items {
+		if item.Status != http.StatusOK {
+			response.Errors = true
+			break
+		}
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+func bulkItemId(item BulkItem) string {
+	for _, fields := range []map[string]any{item.Index, item.Update, item.Delete} {
+		if id, ok := fields[schema.ID_FIELD].(string); ok {
+			return id
+		}
+	}
+	return ""
+}
+
+// bulkOperation converts a line of a bulk request to an operation on the index, existing is the document an update applies to.
+// The status is the response of a failed line.
+func bulkOperation(collection *indexing.Collection, docId string, item BulkItem, existing *search_proto.Document) (*index_proto.BulkOperation, int, error) {
+	if len(docId) == 0 {
+		return nil, http.StatusBadRequest, fmt.Errorf("operation without document %s", schema.ID_FIELD)
+	}
+
+	var doc schema.Document
+	var err error
+	switch {
+	case item.Index != nil:
+		doc, err = collection.Schema.NewDocument(item.Index)
+	case item.Update != nil:
+		if existing == nil {
+			return nil, http.StatusNotFound, fmt.Errorf("document not found")
+		}
+		doc, err = mergeDocument(collection, existing, item.Update)
+		if err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+	case item.Delete != nil:
+		return &index_proto.BulkOperation{DeleteId: docId}, 0, nil
+	default:
+		return nil, http.StatusBadRequest, fmt.Errorf("operation must be one of Index, Update and Delete")
+	}
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	indexDoc, err := indexing.IndexDocument(doc, collection.Schema, collection.Categories)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	return &index_proto.BulkOperation{Doc: indexDoc}, 0, nil
+}
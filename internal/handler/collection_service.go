@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/schema"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/source"
+)
+
+var (
+	DefaultCollection = indexing.DEFAULT_COLLECTION // collection or alias served by the routes without :collection
+	DataDir           string                        // directory of the csv files collections are built from
+	BuildDefaults     indexing.BuildOptions         // mapping and id derivation of BuildCollection, the schema and categories are the collection's
+	LoadEmbeddings    func() map[string][]float32   // vectors of the documents built by BuildCollection, may be nil
+	builds            sync.Map                      // names of the collections being built
+	Ingest            *indexing.IngestMonitor       // progress of coordinated builds, nil in standalone mode
+)
+
+// getCollection returns the collection named by the :collection path parameter, DefaultCollection if the route has none.
+// Aliases are resolved to the collection they point to. It responds 404 if the collection does not exist.
+func getCollection(ctx *gin.Context) (*indexing.Collection, bool) {
+	name := ctx.Param("collection")
+	if len(name) == 0 {
+		name = DefaultCollection
+	}
+
+	collection, exists := Collections.Get(name)
+	if !exists {
+		ctx.String(http.StatusNotFound, "collection %s not found", name)
+	}
+	return collection, exists
+}
+
+// CreateCollection creates the collection :collection, the request body is its json schema
+func CreateCollection(ctx *gin.Context) {
+	bs, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, "read schema failed")
+		return
+	}
+	docSchema, err := schema.ParseSchema(bs)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, "invalid schema: %s", err)
+		return
+	}
+
+	if err := Collections.Create(ctx.Param("collection"), docSchema); err != nil {
+		ctx.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	ctx.JSON(http.StatusCreated, docSchema)
+}
+
+// DropCollection deletes the collection :collection with all its documents
+func DropCollection(ctx *gin.Context) {
+	if err := Collections.Drop(ctx.Param("collection")); err != nil {
+		switch {
+		case errors.Is(err, indexing.ErrCollectionNotFound):
+			ctx.String(http.StatusNotFound, err.Error())
+		case errors.Is(err, indexing.ErrCollectionAliased):
+			ctx.String(http.StatusConflict, err.Error())
+		case ctx.Param("collection") == indexing.DEFAULT_COLLECTION:
+			ctx.String(http.StatusBadRequest, err.Error())
+		default:
+			ctx.String(http.StatusInternalServerError, "drop collection failed: %s", err)
+		}
+		return
+	}
+	ctx.String(http.StatusOK, "ok")
+}
+
+func ListCollections(ctx *gin.Context) {
+	names, err := Collections.List()
+	if err != nil {
+		ctx.String(http.StatusInternalServerError, "list collections failed")
+		return
+	}
+	sort.Strings(names)
+	ctx.JSON(http.StatusOK, names)
+}
+
+// GetCollection returns the schema of the collection :collection
+func GetCollection(ctx *gin.Context) {
+	collection, exists := getCollection(ctx)
+	if !exists {
+		return
+	}
+	ctx.JSON(http.StatusOK, collection.Schema)
+}
+
+// BuildRequest is the optional body of BuildCollection, it overrides the mapping and id derivation of BuildDefaults
+type BuildRequest struct {
+	Mapping  *source.Mapping
+	IdField  string
+	IdFields []string
+}
+
+// BuildCollection indexes the csv files of DataDir into the collection :collection in the background, the collection keeps
+// serving meanwhile. Point an alias to the collection once the build is done to switch searches to it, SetAlias refuses
+// until the build completed.
+func BuildCollection(ctx *gin.Context) {
+	collection, exists := getCollection(ctx)
+	if !exists {
+		return
+	}
+	options := BuildDefaults
+	if ctx.Request.ContentLength > 0 {
+		var request BuildRequest
+		if err := ctx.ShouldBindJSON(&request); err != nil {
+			ctx.String(http.StatusBadRequest, "invalid build request")
+			return
+		}
+		options.IdField, options.IdFields = request.IdField, request.IdFields
+		if request.Mapping != nil {
+			options.Mapping = request.Mapping
+		}
+	}
+	if _, running := builds.LoadOrStore(collection.Name, true); running {
+		ctx.String(http.StatusConflict, "collection %s is being built", collection.Name)
+		return
+	}
+	if err := Collections.SetBuilding(collection.Name, true); err != nil {
+		builds.Delete(collection.Name)
+		ctx.String(http.StatusInternalServerError, "mark collection %s as building failed: %s", collection.Name, err)
+		return
+	}
+
+	go func() {
+		defer builds.Delete(collection.Name)
+		options.Schema, options.Categories, options.TrieDB = collection.Schema, collection.Categories, TrieDB
+		if LoadEmbeddings != nil {
+			options.Embeddings = LoadEmbeddings()
+		}
+		log.Printf("start to build collection %s from %s", collection.Name, DataDir)
+		report := indexing.BuildIndexFromDir(DataDir, collection.Indexer, options)
+		if !report.Complete {
+			log.Printf("build of collection %s did not complete, no alias can point to it until a build completes", collection.Name)
+			return
+		}
+		if err := Collections.SetBuilding(collection.Name, false); err != nil {
+			log.Printf("mark collection %s as built failed: %s", collection.Name, err)
+			return
+		}
+		log.Printf("collection %s is built", collection.Name)
+	}()
+	ctx.String(http.StatusAccepted, "building collection %s", collection.Name)
+}
+
+type AliasRequest struct {
+	Collection string // collection the alias points to
+	Expected   string // collection the alias must point to now, the swap fails otherwise, unchecked if empty
+}
+
+// SetAlias points the alias :alias to a collection, searches through the alias switch to it at once. It responds 409 if the
+// collection is being built or the alias does not point to Expected.
+func SetAlias(ctx *gin.Context) {
+	var request AliasRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil || len(request.Collection) == 0 {
+		ctx.String(http.StatusBadRequest, "invalid alias request")
+		return
+	}
+
+	if err := Collections.SetAlias(ctx.Param("alias"), request.Collection, request.Expected); err != nil {
+		ctx.String(http.StatusConflict, err.Error())
+		return
+	}
+	ctx.String(http.StatusOK, "ok")
+}
+
+func RemoveAlias(ctx *gin.Context) {
+	if err := Collections.RemoveAlias(ctx.Param("alias")); err != nil {
+		ctx.String(http.StatusNotFound, err.Error())
+		return
+	}
+	ctx.String(http.StatusOK, "ok")
+}
+
+// IngestStatus returns the progress of the running or the last build of the ingestion coordinator
+func IngestStatus(ctx *gin.Context) {
+	if Ingest == nil {
+		ctx.String(http.StatusNotFound, "coordinated builds run in distributed mode only")
+		return
+	}
+	progress, err := Ingest.Progress()
+	if err != nil {
+		ctx.String(http.StatusServiceUnavailable, "read ingest progress failed: %s", err)
+		return
+	}
+	if progress == nil {
+		ctx.String(http.StatusNotFound, "no coordinated build has run")
+		return
+	}
+	ctx.JSON(http.StatusOK, progress)
+}
+
+// ListAliases returns the collection each alias points to
+func ListAliases(ctx *gin.Context) {
+	aliases, err := Collections.Aliases()
+	if err != nil {
+		ctx.String(http.StatusInternalServerError, "list aliases failed")
+		return
+	}
+	ctx.JSON(http.StatusOK, aliases)
+}
@@ -0,0 +1,259 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	index_proto "github.com/m1i3k0e7/distributed-search-engine/api/proto/index"
+	search_proto "github.com/m1i3k0e7/distributed-search-engine/api/proto/search"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/schema"
+)
+
+const (
+	DEFAULT_SIMILAR_LIMIT = 10
+	MAX_MULTI_GET         = 1000 // most documents a multi get request may ask for
+)
+
+type MultiGetRequest struct {
+	Ids []string
+}
+
+type MultiGetResponse struct {
+	Documents []schema.Document // the documents found, in the order of the request
+	Missing   []string          // ids of the documents that do not exist
+}
+
+// SimilarProducts returns products similar to the product :id, for the recommendations strip of product detail pages
+func SimilarProducts(ctx *gin.Context) {
+	collection, exists := getCollection(ctx)
+	if !exists {
+		return
+	}
+	limit := DEFAULT_SIMILAR_LIMIT
+	if l, err := strconv.Atoi(ctx.Query("limit")); err == nil && l > 0 {
+		limit = l
+	}
+	like, ok := loadDocument(ctx, collection) // 404 for an unknown id, like GET
+	if !ok {
+		return
+	}
+
+	docs, _ := collection.Indexer.MoreLikeThisDoc(like, limit)
+	documents := make([]schema.Document, 0, len(docs))
+	for _, doc := range docs {
+		if document, err := collection.Schema.Unmarshal(doc.Bytes); err == nil {
+			documents = append(documents, document)
+		}
+	}
+
+	ctx.JSON(http.StatusOK, documents)
+}
+
+// GetDocument returns the document :id
+func GetDocument(ctx *gin.Context) {
+	collection, exists := getCollection(ctx)
+	if !exists {
+		return
+	}
+	doc, ok := loadDocument(ctx, collection)
+	if !ok {
+		return
+	}
+	document, err := collection.Schema.Unmarshal(doc.Bytes)
+	if err != nil {
+		ctx.String(http.StatusInternalServerError, "decode document failed: %s", err)
+		return
+	}
+	ctx.JSON(http.StatusOK, document)
+}
+
+// MultiGetDocuments returns the documents of the ids in the request body, for product detail and listing pages
+func MultiGetDocuments(ctx *gin.Context) {
+	collection, exists := getCollection(ctx)
+	if !exists {
+		return
+	}
+	var request MultiGetRequest
+	if err := ctx.ShouldBindJSON(&request); err != nil || len(request.Ids) == 0 {
+		ctx.String(http.StatusBadRequest, "invalid multi get request")
+		return
+	}
+	if len(request.Ids) > MAX_MULTI_GET {
+		ctx.String(http.StatusBadRequest, "at most %d documents can be requested at once", MAX_MULTI_GET)
+		return
+	}
+
+	docs, err := collection.Indexer.MultiGetDoc(request.Ids)
+	if err != nil {
+		ctx.String(http.StatusServiceUnavailable, "get documents failed: %s", err)
+		return
+	}
+	response := MultiGetResponse{Documents: make([]schema.Document, 0, len(docs)), Missing: make([]string, 0)}
+	found := make(map[string]bool, len(docs))
+	for _, doc := range docs {
+		if document, err := collection.Schema.Unmarshal(doc.Bytes); err == nil {
+			response.Documents = append(response.Documents, document)
+			found[doc.Id] = true
+		}
+	}
+	for _, docId := range request.Ids {
+		if !found[docId] {
+			response.Missing = append(response.Missing, docId)
+		}
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+// PutDocument indexes the json body as the document :id, replacing the document if it exists.
+// It responds 201 if the document is new and 200 if it was replaced.
+func PutDocument(ctx *gin.Context) {
+	collection, exists := getCollection(ctx)
+	if !exists {
+		return
+	}
+	var fields map[string]any
+	if err := ctx.ShouldBindJSON(&fields); err != nil {
+		ctx.String(http.StatusBadRequest, "invalid document: %s", err)
+		return
+	}
+	doc, err := collection.Schema.NewDocument(fields)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, "invalid document: %s", err)
+		return
+	}
+	docId := ctx.Param("id")
+	if id := doc.Id(); len(id) > 0 && id != docId {
+		ctx.String(http.StatusBadRequest, "document id %s does not match %s", id, docId)
+		return
+	}
+	doc[schema.ID_FIELD] = docId
+
+	existing, err := collection.Indexer.GetDoc(docId)
+	if err != nil {
+		ctx.String(http.StatusServiceUnavailable, "get document failed: %s", err)
+		return
+	}
+	if !indexDocument(ctx, collection, doc) {
+		return
+	}
+	if existing == nil {
+		ctx.JSON(http.StatusCreated, doc)
+	} else {
+		ctx.JSON(http.StatusOK, doc)
+	}
+}
+
+// PatchDocument updates the fields in the json body of the document :id and keeps the others, a null value removes a field
+func PatchDocument(ctx *gin.Context) {
+	collection, exists := getCollection(ctx)
+	if !exists {
+		return
+	}
+	var fields map[string]any
+	if err := ctx.ShouldBindJSON(&fields); err != nil {
+		ctx.String(http.StatusBadRequest, "invalid document: %s", err)
+		return
+	}
+
+	existing, ok := loadDocument(ctx, collection)
+	if !ok {
+		return
+	}
+	doc, err := mergeDocument(collection, existing, fields)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, "invalid document: %s", err)
+		return
+	}
+
+	if !indexDocument(ctx, collection, doc) {
+		return
+	}
+	ctx.JSON(http.StatusOK, doc)
+}
+
+// DeleteDocument removes the document :id from the index
+func DeleteDocument(ctx *gin.Context) {
+	collection, exists := getCollection(ctx)
+	if !exists {
+		return
+	}
+	// a bulk result tells a missing document from a worker that failed to delete it
+	result := collection.Indexer.Bulk([]*index_proto.BulkOperation{{DeleteId: ctx.Param("id")}})[0]
+	if len(result.Error) > 0 {
+		ctx.String(http.StatusServiceUnavailable, "delete document failed: %s", result.Error)
+		return
+	}
+	if result.Count == 0 {
+		ctx.String(http.StatusNotFound, "document %s not found", ctx.Param("id"))
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// loadDocument reads the document :id, it responds 404 if the document does not exist
+func loadDocument(ctx *gin.Context, collection *indexing.Collection) (*search_proto.Document, bool) {
+	docId := ctx.Param("id")
+	doc, err := collection.Indexer.GetDoc(docId)
+	if err != nil {
+		ctx.String(http.StatusServiceUnavailable, "get document failed: %s", err)
+		return nil, false
+	}
+	if doc == nil {
+		ctx.String(http.StatusNotFound, "document %s not found", docId)
+		return nil, false
+	}
+	return doc, true
+}
+
+// mergeDocument updates the stored fields of existing with fields, a nil value removes a field
+func mergeDocument(collection *indexing.Collection, existing *search_proto.Document, fields map[string]any) (schema.Document, error) {
+	removed := make([]string, 0)
+	for name, value := range fields {
+		if value == nil {
+			removed = append(removed, name)
+			delete(fields, name)
+		}
+	}
+	patch, err := collection.Schema.NewDocument(fields)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := collection.Schema.Unmarshal(existing.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, value := range patch {
+		if name != schema.ID_FIELD {
+			doc[name] = value
+		}
+	}
+	for _, name := range removed {
+		delete(doc, name)
+	}
+	// keywords derived from the name are derived again, the vector is not stored with the document
+	if field, exists := collection.Schema.Field("Keywords"); exists && !field.Indexed && patch["Keywords"] == nil {
+		delete(doc, "Keywords")
+	}
+	if field, exists := collection.Schema.VectorField(); exists && doc[field.Name] == nil && len(existing.Vector) > 0 {
+		doc[field.Name] = existing.Vector
+	}
+
+	return doc, nil
+}
+
+// indexDocument replaces the document in the index with doc, with the same keywords and categories as the csv build
+func indexDocument(ctx *gin.Context, collection *indexing.Collection, doc schema.Document) bool {
+	indexDoc, err := indexing.IndexDocument(doc, collection.Schema, collection.Categories)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, "invalid document: %s", err)
+		return false
+	}
+	if result := collection.Indexer.Bulk([]*index_proto.BulkOperation{{Doc: indexDoc}})[0]; len(result.Error) > 0 {
+		ctx.String(http.StatusServiceUnavailable, "index document failed: %s", result.Error)
+		return false
+	}
+	return true
+}
@@ -4,10 +4,12 @@ import (
 	stdctx "context"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/category"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/schema"
 	"github.com/m1i3k0e7/distributed-search-engine/pkg/logger"
-	proto "google.golang.org/protobuf/proto"
 
 	"github.com/gin-gonic/gin"
 	search_proto "github.com/m1i3k0e7/distributed-search-engine/api/proto/search"
@@ -15,14 +17,20 @@ import (
 	"github.com/m1i3k0e7/distributed-search-engine/internal/search"
 	"github.com/m1i3k0e7/distributed-search-engine/internal/search/common"
 	"github.com/m1i3k0e7/distributed-search-engine/internal/search/context"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/search/filter"
 	"github.com/m1i3k0e7/distributed-search-engine/pkg/preprocessing"
 	"github.com/m1i3k0e7/distributed-search-engine/pkg/ranking"
 )
 
-var Indexer indexing.IIndexer
+var Collections indexing.ICollections
 var TrieDB  *storage.TrieDB
+var SearchTimeout time.Duration // deadline of a search whose request names none, no deadline if 0
 
 func Search(ctx *gin.Context) {
+	collection, exists := getCollection(ctx)
+	if !exists {
+		return
+	}
 	var request common.SearchRequest
 	if err := ctx.ShouldBindJSON(&request); err != nil {
 		log.Printf("bind request parameter failed: %s", err)
@@ -39,29 +47,43 @@ func Search(ctx *gin.Context) {
 	query := new(search_proto.TermQuery)
 	if len(keywords) > 0 {
 		for _, word := range keywords {
-			query = query.And(search_proto.NewTermQuery("content", word))
+			query = query.And(search_proto.NewTermQuery(schema.CONTENT_FIELD, word))
+		}
+	}
+
+	if len(request.Classes) > 0 && collection.Categories != nil {
+		categoryQuery, exists := category.Query(collection.Categories, request.Classes)
+		if !exists {
+			ctx.JSON(http.StatusOK, []schema.Document{})
+			return
 		}
+		query = query.And(categoryQuery)
 	}
 
-	orFlags := []uint64{common.GetClassBits(request.Classes)}
-	// logger.Log.Printf("search query: %s, orFlags: %b", query, orFlags)
-	docs := Indexer.Search(query, 0, 0, orFlags)
+	// logger.Log.Printf("search query: %s", query)
+	searchCtx, cancel := searchDeadline(ctx, &request)
+	defer cancel()
+	docs, status := collection.Indexer.SearchContext(searchCtx, query, 0, 0, nil)
 
-	products := make([]search_proto.Product, 0, len(docs))
+	documents := make([]schema.Document, 0, len(docs))
 	for _, doc := range docs {
-		var product search_proto.Product
-		if err := proto.Unmarshal(doc.Bytes, &product); err == nil {
-			if product.DiscountPrice >= float64(request.PriceTo) && (request.PriceTo <= 0 || product.DiscountPrice <= float64(request.PriceTo)) {
-				products = append(products, product)
+		if document, err := collection.Schema.Unmarshal(doc.Bytes); err == nil {
+			price, _ := document.Float(filter.PRICE_FIELD)
+			if price >= float64(request.PriceFrom) && (request.PriceTo <= 0 || price <= float64(request.PriceTo)) {
+				documents = append(documents, document)
 			}
 		}
 	}
 
-	logger.Log.Printf("return %d products", len(products))
-	ctx.JSON(http.StatusOK, products)
+	logger.Log.Printf("return %d documents", len(documents))
+	respond(ctx, &request, status, documents)
 }
 
 func SearchAll(ctx *gin.Context) {
+	collection, exists := getCollection(ctx)
+	if !exists {
+		return
+	}
 	var request common.SearchRequest
 	if err := ctx.ShouldBindJSON(&request); err != nil {
 		log.Printf("bind request parameter failed: %s", err)
@@ -69,24 +91,103 @@ func SearchAll(ctx *gin.Context) {
 		return
 	}
 
+	if !ranking.ValidFusion(request.Fusion) {
+		ctx.String(http.StatusBadRequest, "unknown fusion %s, use %s or %s", request.Fusion, ranking.FUSION_RRF, ranking.FUSION_WEIGHTED)
+		return
+	}
+
 	keywords := preprocessing.PreprocessForLargeDataset(request.Query)
 	request.Keywords = keywords
-	if len(request.Keywords) == 0 {
+	if len(request.Keywords) == 0 && len(request.Vector) == 0 {
 		ctx.String(http.StatusOK, "[]")
 		return
 	}
 
+	rankingConfig := collection.Ranking.WithWeights(request.FieldWeights)
+	deadline, cancel := searchDeadline(ctx, &request)
+	defer cancel()
 	searchCtx := &context.ProductSearchContext{
-		Ctx:     stdctx.Background(),
+		Ctx:     deadline,
 		Request: &request,
-		Indexer: Indexer,
+		Indexer: collection.Indexer,
+		Ranking: &rankingConfig,
+		Categories: collection.Categories,
+		Schema:  collection.Schema,
 	}
 	searcher := search.NewAllProductSearcher()
-	products := searcher.Search(searchCtx)
+	documents := searcher.Search(searchCtx) // already ranked by fusing the rankings of all recallers
 
-	products = ranking.RankDocumentByBM25(request.Query, products)
+	var result any = documents
+	if request.Explain {
+		result = explainDocuments(searchCtx, documents)
+	}
+	if request.Facets {
+		result = common.FacetedResult{Documents: result, Facets: common.Facets(documents, collection.Schema)}
+	}
+
+	respond(ctx, &request, searchCtx.Status, result)
+}
+
+// searchDeadline bounds a search by the timeout of the request, or SearchTimeout, and cancels it if the client goes away
+func searchDeadline(ctx *gin.Context, request *common.SearchRequest) (stdctx.Context, stdctx.CancelFunc) {
+	timeout := SearchTimeout
+	if request.TimeoutMs > 0 {
+		timeout = time.Duration(request.TimeoutMs) * time.Millisecond
+	}
+	if timeout <= 0 {
+		return stdctx.WithCancel(ctx.Request.Context())
+	}
+	return stdctx.WithTimeout(ctx.Request.Context(), timeout)
+}
+
+// respond returns the result of a complete search. A search missing shards returns its result and the missing shards if
+// the request allows partial results, 504 if shards timed out and 503 if shards failed otherwise.
+func respond(ctx *gin.Context, request *common.SearchRequest, status indexing.SearchStatus, result any) {
+	switch {
+	case !status.Partial():
+		ctx.JSON(http.StatusOK, result)
+	case request.AllowPartial:
+		ctx.JSON(http.StatusOK, common.PartialResult{Documents: result, TimedOut: status.TimedOut, Failed: status.Failed})
+	case len(status.TimedOut) > 0:
+		ctx.String(http.StatusGatewayTimeout, "shards %v timed out", status.TimedOut)
+	default:
+		ctx.String(http.StatusServiceUnavailable, "no replica of shards %v answered", status.Failed)
+	}
+}
+
+// CategoryTree returns the taxonomy of all categories seen while building the index
+func CategoryTree(ctx *gin.Context) {
+	collection, exists := getCollection(ctx)
+	if !exists {
+		return
+	}
+	if collection.Categories == nil {
+		ctx.String(http.StatusInternalServerError, "category registry is not initialized")
+		return
+	}
+
+	names, err := collection.Categories.Names()
+	if err != nil {
+		log.Printf("list categories failed: %s", err)
+		ctx.String(http.StatusInternalServerError, "list categories failed")
+		return
+	}
+
+	counts := make(map[string]int, len(names))
+	for _, name := range names {
+		counts[name] = 0
+	}
+	ctx.JSON(http.StatusOK, category.Tree(counts))
+}
+
+// explainDocuments pairs each document with the explanation collected while searching
+func explainDocuments(searchCtx *context.ProductSearchContext, documents []schema.Document) []common.ExplainedDocument {
+	result := make([]common.ExplainedDocument, 0, len(documents))
+	for _, document := range documents {
+		result = append(result, common.ExplainedDocument{Document: document, Explanation: searchCtx.Explanations[document.Id()]})
+	}
 
-	ctx.JSON(http.StatusOK, products)
+	return result
 }
 
 func AssociateQuery(ctx *gin.Context) {
@@ -1,12 +1,53 @@
 package indexing
 
-import search_proto "github.com/m1i3k0e7/distributed-search-engine/api/proto/search"
+import (
+	"context"
+	"slices"
+
+	index "github.com/m1i3k0e7/distributed-search-engine/api/proto/index"
+	search_proto "github.com/m1i3k0e7/distributed-search-engine/api/proto/search"
+)
 
 type IIndexer interface {
 	AddDoc(doc search_proto.Document) (int, error)
 	UpdateDoc(doc search_proto.Document) (int, error)
 	DeleteDoc(docId string) int
+	GetDoc(docId string) (*search_proto.Document, error) // nil if the document does not exist
+	MultiGetDoc(docIds []string) ([]*search_proto.Document, error) // the documents that exist, in the order of docIds
+	Bulk(operations []*index.BulkOperation) []*index.BulkResult // one result per operation, in order
 	Search(query *search_proto.TermQuery, onFlag uint64, offFlag uint64, orFlags []uint64) []*search_proto.Document
+	SearchVector(vector []float32, k int) ([]*search_proto.Document, []float32) // approximate nearest neighbors and their similarity
+	// SearchContext and SearchVectorContext stop waiting for workers at the deadline of ctx, the status lists the shards left out
+	SearchContext(ctx context.Context, query *search_proto.TermQuery, onFlag uint64, offFlag uint64, orFlags []uint64) ([]*search_proto.Document, SearchStatus)
+	SearchVectorContext(ctx context.Context, vector []float32, k int) ([]*search_proto.Document, []float32, SearchStatus)
+	MoreLikeThis(docId string, limit int) ([]*search_proto.Document, []float32) // similar documents, excluding docId itself
+	MoreLikeThisDoc(like *search_proto.Document, limit int) ([]*search_proto.Document, []float32) // MoreLikeThis of a document already read
 	Count() int
 	Close() error
 }
+
+// SearchStatus lists the shards missing from the results of a distributed search, a local index always answers completely
+type SearchStatus struct {
+	TimedOut []int // shards whose replicas did not answer before the deadline
+	Failed   []int // shards without an alive replica, or whose replicas all failed
+}
+
+// Partial tells whether some shards are missing from the results
+func (status SearchStatus) Partial() bool {
+	return len(status.TimedOut) > 0 || len(status.Failed) > 0
+}
+
+// Merge adds the missing shards of other, the shards of parallel searches are only listed once
+func (status *SearchStatus) Merge(other SearchStatus) {
+	status.TimedOut = mergeShards(status.TimedOut, other.TimedOut)
+	status.Failed = mergeShards(status.Failed, other.Failed)
+}
+
+func mergeShards(shards []int, more []int) []int {
+	for _, shard := range more {
+		if !slices.Contains(shards, shard) {
+			shards = append(shards, shard)
+		}
+	}
+	return shards
+}
@@ -0,0 +1,132 @@
+package indexing
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/m1i3k0e7/distributed-search-engine/pkg/logger"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	BREAKER_FAILURES  = 5                // consecutive failures of a worker that open its circuit
+	BREAKER_COOLDOWN  = 10 * time.Second // no request goes to a worker while its circuit is open, then one request probes it
+	HEDGE_WINDOW      = 256              // latest read latencies the p95 of an operation is computed from
+	HEDGE_MIN_SAMPLES = 20               // reads are not hedged before an operation has this many latencies
+)
+
+// circuitBreaker counts the consecutive failures of the requests to a worker. After BREAKER_FAILURES of them the circuit is
+// open and the worker gets no request for BREAKER_COOLDOWN. Then a single request is let through, its success closes the
+// circuit and its failure opens it for another cooldown.
+type circuitBreaker struct {
+	lock      sync.Mutex
+	failures  int
+	openUntil time.Time
+}
+
+// allow tells whether a request may be sent, it lets one request through every cooldown while the circuit is open
+func (breaker *circuitBreaker) allow() bool {
+	breaker.lock.Lock()
+	defer breaker.lock.Unlock()
+	if breaker.failures < BREAKER_FAILURES {
+		return true
+	}
+	now := time.Now()
+	if now.Before(breaker.openUntil) {
+		return false
+	}
+	breaker.openUntil = now.Add(BREAKER_COOLDOWN) // the probe, the next one waits for another cooldown
+	return true
+}
+
+// open tells whether the worker is cooling down, without taking the probe
+func (breaker *circuitBreaker) open() bool {
+	breaker.lock.Lock()
+	defer breaker.lock.Unlock()
+	return breaker.failures >= BREAKER_FAILURES && time.Now().Before(breaker.openUntil)
+}
+
+// done records the outcome of a request and returns true if it opened the circuit
+func (breaker *circuitBreaker) done(err error) bool {
+	breaker.lock.Lock()
+	defer breaker.lock.Unlock()
+	if err == nil {
+		breaker.failures = 0
+		return false
+	}
+	breaker.failures++
+	if breaker.failures >= BREAKER_FAILURES {
+		breaker.openUntil = time.Now().Add(BREAKER_COOLDOWN)
+		return true
+	}
+	return false
+}
+
+func (sentinel *Sentinel) breaker(endpoint string) *circuitBreaker {
+	v, _ := sentinel.breakers.LoadOrStore(endpoint, &circuitBreaker{})
+	return v.(*circuitBreaker)
+}
+
+// report records the outcome of a request to a worker. A request canceled by the sentinel, because the deadline of ctx passed
+// or a hedged request answered first, says nothing about the worker.
+func (sentinel *Sentinel) report(ctx context.Context, endpoint string, err error) {
+	if err != nil && (ctx.Err() != nil || status.Code(err) == codes.Canceled) {
+		return
+	}
+	if sentinel.breaker(endpoint).done(err) {
+		logger.Log.Printf("worker %s failed %d times in a row, stop sending it requests for %s", endpoint, BREAKER_FAILURES, BREAKER_COOLDOWN)
+	}
+}
+
+// order shuffles the replicas to spread the load over them, the ones whose circuit is open come last
+func (sentinel *Sentinel) order(replicas []string) []string {
+	ordered := make([]string, len(replicas))
+	for i, j := range rand.Perm(len(replicas)) {
+		ordered[i] = replicas[j]
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return !sentinel.breaker(ordered[i]).open() && sentinel.breaker(ordered[j]).open()
+	})
+	return ordered
+}
+
+// latencyWindow keeps the latest latencies of the reads of an operation, whose p95 is the delay before a read is hedged
+type latencyWindow struct {
+	lock    sync.Mutex
+	samples []time.Duration
+	next    int // ring position of the next sample once the window is full
+}
+
+func (window *latencyWindow) add(latency time.Duration) {
+	window.lock.Lock()
+	defer window.lock.Unlock()
+	if len(window.samples) < HEDGE_WINDOW {
+		window.samples = append(window.samples, latency)
+		return
+	}
+	window.samples[window.next] = latency
+	window.next = (window.next + 1) % HEDGE_WINDOW
+}
+
+// p95 returns the 95th percentile of the latencies, 0 while there are fewer than HEDGE_MIN_SAMPLES of them
+func (window *latencyWindow) p95() time.Duration {
+	window.lock.Lock()
+	samples := append([]time.Duration(nil), window.samples...)
+	window.lock.Unlock()
+	if len(samples) < HEDGE_MIN_SAMPLES {
+		return 0
+	}
+	sort.Slice(samples, func(i, j int) bool {
+		return samples[i] < samples[j]
+	})
+	return samples[len(samples)*95/100]
+}
+
+func (sentinel *Sentinel) latency(operation string) *latencyWindow {
+	v, _ := sentinel.latencies.LoadOrStore(operation, &latencyWindow{})
+	return v.(*latencyWindow)
+}
@@ -0,0 +1,214 @@
+package indexing
+
+import (
+	"context"
+	"math/rand"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	cases := []struct {
+		name     string
+		failures int
+		cooled   bool   // the cooldown passed
+		probe    string // outcome of the request let through after the cooldown, if any
+		open     bool
+		allow    []bool // answers of the next calls to allow
+	}{
+		{name: "closed below the threshold", failures: BREAKER_FAILURES - 1, allow: []bool{true, true}},
+		{name: "opens at the threshold", failures: BREAKER_FAILURES, open: true, allow: []bool{false, false}},
+		{name: "lets one probe through after the cooldown", failures: BREAKER_FAILURES, cooled: true, allow: []bool{true, false}},
+		{name: "closes when the probe succeeds", failures: BREAKER_FAILURES, cooled: true, probe: "ok", allow: []bool{true, true}},
+		{name: "opens again when the probe fails", failures: BREAKER_FAILURES, cooled: true, probe: "fail", open: true, allow: []bool{false}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			breaker := &circuitBreaker{}
+			for i := 0; i < c.failures; i++ {
+				if !breaker.allow() {
+					t.Fatalf("expect request %d to be allowed", i)
+				}
+				if opened := breaker.done(errWorker); opened != (i == BREAKER_FAILURES-1) {
+					t.Fatalf("expect failure %d to open the circuit: %v, got %v", i+1, i == BREAKER_FAILURES-1, opened)
+				}
+			}
+			if c.cooled {
+				breaker.openUntil = time.Now().Add(-time.Millisecond)
+			}
+			switch c.probe {
+			case "ok":
+				if !breaker.allow() {
+					t.Fatalf("expect the probe to be allowed")
+				}
+				breaker.done(nil)
+			case "fail":
+				if !breaker.allow() {
+					t.Fatalf("expect the probe to be allowed")
+				}
+				breaker.done(errWorker)
+			}
+
+			if breaker.open() != c.open {
+				t.Errorf("expect open %v, got %v", c.open, breaker.open())
+			}
+			for i, expect := range c.allow {
+				if allowed := breaker.allow(); allowed != expect {
+					t.Errorf("expect call %d of allow to return %v, got %v", i+1, expect, allowed)
+				}
+			}
+		})
+	}
+}
+
+func TestReportIgnoresCanceledReads(t *testing.T) {
+	sentinel := &Sentinel{}
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	for i := 0; i < BREAKER_FAILURES; i++ {
+		sentinel.report(canceled, "a:1", context.Canceled)
+	}
+	if sentinel.breaker("a:1").open() {
+		t.Errorf("expect reads canceled by the sentinel not to open the circuit")
+	}
+	for i := 0; i < BREAKER_FAILURES; i++ {
+		sentinel.report(context.Background(), "a:1", errWorker)
+	}
+	if !sentinel.breaker("a:1").open() {
+		t.Errorf("expect %d failures to open the circuit", BREAKER_FAILURES)
+	}
+	if ordered := sentinel.order([]string{"a:1", "b:1", "c:1"}); ordered[2] != "a:1" {
+		t.Errorf("expect the replica with an open circuit last, got %v", ordered)
+	}
+}
+
+func TestLatencyWindow(t *testing.T) {
+	window := &latencyWindow{}
+	for i := 1; i < HEDGE_MIN_SAMPLES; i++ {
+		window.add(time.Millisecond)
+	}
+	if p95 := window.p95(); p95 != 0 {
+		t.Errorf("expect no p95 below %d samples, got %s", HEDGE_MIN_SAMPLES, p95)
+	}
+
+	// 1ms to 256ms in random order fill the window, 243 samples are below the p95
+	window = &latencyWindow{}
+	for _, i := range rand.Perm(HEDGE_WINDOW) {
+		window.add(time.Duration(i+1) * time.Millisecond)
+	}
+	if p95 := window.p95(); p95 != time.Duration(HEDGE_WINDOW*95/100+1)*time.Millisecond {
+		t.Errorf("expect a p95 of %dms, got %s", HEDGE_WINDOW*95/100+1, p95)
+	}
+
+	// the window keeps the latest samples only
+	for i := 0; i < HEDGE_WINDOW-10; i++ {
+		window.add(time.Millisecond)
+	}
+	if p95 := window.p95(); p95 != time.Millisecond {
+		t.Errorf("expect the old latencies to leave the window, got a p95 of %s", p95)
+	}
+	if len(window.samples) != HEDGE_WINDOW {
+		t.Errorf("expect %d samples, got %d", HEDGE_WINDOW, len(window.samples))
+	}
+}
+
+// testScatter runs a scatter over 4 shards on two replicas, the first read is answered by first and the others by rest
+func testScatter(sentinel *Sentinel, first func(read shardRead) error, rest func(read shardRead) error) (SearchStatus, []string, []string) {
+	shardMap := NewShardMap(4, 2)
+	for shard := range shardMap.Replicas {
+		shardMap.Replicas[shard] = []string{"127.0.0.1:1", "127.0.0.1:2"}
+	}
+	sentinel.hub = &fakeHub{endpoints: []string{"127.0.0.1:1", "127.0.0.1:2"}}
+	sentinel.shards = &ShardRouter{}
+	sentinel.shards.offer(shardMap)
+
+	lock := sync.Mutex{}
+	var reads, merged []string
+	status := sentinel.scatter(context.Background(), "search", func(read shardRead) (func(), error) {
+		lock.Lock()
+		reads = append(reads, read.endpoint)
+		answer := rest
+		if len(reads) == 1 {
+			answer = first
+		}
+		lock.Unlock()
+		if err := answer(read); err != nil {
+			return nil, err
+		}
+		return func() { merged = append(merged, read.endpoint) }, nil
+	})
+	return status, reads, merged
+}
+
+func TestScatterHedging(t *testing.T) {
+	fast := func(read shardRead) error { return nil }
+	slow := func(read shardRead) error {
+		select {
+		case <-read.ctx.Done():
+			return read.ctx.Err()
+		case <-time.After(200 * time.Millisecond):
+			return nil
+		}
+	}
+	failing := func(read shardRead) error { return errWorker }
+	withLatencies := func() *Sentinel {
+		sentinel := &Sentinel{}
+		for i := 0; i < HEDGE_MIN_SAMPLES; i++ {
+			sentinel.latency("search").add(5 * time.Millisecond)
+		}
+		return sentinel
+	}
+
+	// a read slower than the p95 is hedged to the other replica, whose answer wins and cancels the slow read
+	sentinel := withLatencies()
+	begin := time.Now()
+	status, reads, merged := testScatter(sentinel, slow, fast)
+	if len(status.Failed) > 0 || len(status.TimedOut) > 0 || len(reads) != 2 || reads[0] == reads[1] {
+		t.Fatalf("expect the read to be hedged to the other replica, got reads %v and status %+v", reads, status)
+	}
+	if !slices.Equal(merged, reads[1:]) {
+		t.Errorf("expect the results of the hedged read only, got %v", merged)
+	}
+	if elapsed := time.Since(begin); elapsed >= 200*time.Millisecond {
+		t.Errorf("expect the hedged read to answer before the slow one, took %s", elapsed)
+	}
+	for _, endpoint := range reads {
+		if sentinel.breaker(endpoint).failures != 0 {
+			t.Errorf("expect the canceled read not to count as a failure of %s", endpoint)
+		}
+	}
+
+	// no read is hedged before the operation has HEDGE_MIN_SAMPLES latencies
+	status, reads, merged = testScatter(&Sentinel{}, slow, fast)
+	if len(reads) != 1 || !slices.Equal(merged, reads) {
+		t.Errorf("expect a single read without latencies, got reads %v merged %v", reads, merged)
+	}
+
+	// no read is hedged to a replica whose circuit is open
+	sentinel = withLatencies()
+	for i := 0; i < BREAKER_FAILURES; i++ {
+		sentinel.breaker("127.0.0.1:2").done(errWorker)
+	}
+	status, reads, merged = testScatter(sentinel, slow, fast)
+	if !slices.Equal(reads, []string{"127.0.0.1:1"}) || !slices.Equal(merged, reads) {
+		t.Errorf("expect a single read of the replica with a closed circuit, got reads %v merged %v", reads, merged)
+	}
+
+	// a failed read fails over to the other replica and counts as a failure of its worker
+	sentinel = withLatencies()
+	status, reads, merged = testScatter(sentinel, failing, fast)
+	if len(status.Failed) > 0 || len(reads) != 2 || !slices.Equal(merged, reads[1:]) {
+		t.Errorf("expect the read to fail over, got reads %v merged %v status %+v", reads, merged, status)
+	}
+	if len(reads) > 0 && sentinel.breaker(reads[0]).failures != 1 {
+		t.Errorf("expect one failure of %s, got %d", reads[0], sentinel.breaker(reads[0]).failures)
+	}
+
+	// the shards are lost when every replica fails
+	status, reads, _ = testScatter(&Sentinel{}, failing, failing)
+	if len(reads) != 2 || !slices.Equal(status.Failed, []int{0, 1, 2, 3}) {
+		t.Errorf("expect every shard to fail, got reads %v status %+v", reads, status)
+	}
+}
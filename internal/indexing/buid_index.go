@@ -1,155 +1,197 @@
 package indexing
 
 import (
-	"encoding/csv"
-	"io"
 	"log"
 	"os"
-	"strconv"
 	"strings"
 
 	// "time"
 	uuid "github.com/google/uuid"
-	farmhash "github.com/leemcloughlin/gofarmhash"
 	search_proto "github.com/m1i3k0e7/distributed-search-engine/api/proto/search"
-	"github.com/m1i3k0e7/distributed-search-engine/internal/search/common"
-	"github.com/m1i3k0e7/distributed-search-engine/pkg/logger"
-	"github.com/m1i3k0e7/distributed-search-engine/pkg/preprocessing"
-	"github.com/m1i3k0e7/distributed-search-engine/pkg/trie"
-	proto "google.golang.org/protobuf/proto"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/category"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/schema"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/source"
 	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/trie"
 )
 
-func BuildIndexFromDir(csvFilesDir string, indexer IIndexer, totalWorkers, workerIndex int) {
+// BuildOptions controls how BuildIndexFromDir and BuildIndexFromFile ingest csv, tsv and jsonl files
+type BuildOptions struct {
+	Shards       *ShardRouter               // shards of the cluster, only the documents of the shards Worker hosts or receives are built, all if nil
+	Worker       string                     // endpoint of this worker in the shard map
+	Schema       *schema.Schema             // fields of the documents, schema.ProductSchema if nil
+	Mapping      *source.Mapping            // maps columns to fields, csv/tsv columns are mapped by position if nil
+	Embeddings   map[string][]float32       // optional vectors keyed by the lower-cased first text field, see LoadEmbeddings
+	Categories   category.ICategoryRegistry // assigns ids to categories, documents are not filterable by category if nil
+	TrieDB       *storage.TrieDB            // stores the query suggestions, the trie db at TrieDBPath is opened if nil
+	TrieDBPath   string                     // file of the query suggestions when TrieDB is nil, they are not stored if both are empty
+	IdField      string                     // field whose value is the document id, e.g. a sku column
+	IdFields     []string                   // fields hashed into the document id when IdField is empty, the whole row if both are empty
+	Checkpoints  *Checkpoints               // progress of the build, an unfinished build resumes from it, the build starts over if nil
+	Parallelism  int                        // goroutines analyzing rows, runtime.NumCPU() if 0
+	BatchSize    int                        // documents written to the indexer at once, BULK_BATCH_SIZE if 0
+	Progress     BuildProgress              // called after every batch written, may be nil
+	Changed      RowFilter                  // rows it returns false for are not written again, every row is written if nil
+}
+
+// BuildIndexFromDir writes the documents of all csv, tsv and jsonl files in the directory to indexer, see BuildIndexFromFile.
+// The files go through one pipeline in name order, the analyzers do not wait for a file to be written before the next one is read.
+func BuildIndexFromDir(csvFilesDir string, indexer IIndexer, options BuildOptions) *BuildReport {
+	report := NewBuildReport()
 	files, err := os.ReadDir(csvFilesDir)
 	if err != nil {
 		log.Printf("read dir %s failed: %s", csvFilesDir, err)
-		return
+		return report
+	}
+	if !beginBuild(options) {
+		return report
 	}
 
+	sourceFiles := make([]string, 0, len(files))
 	for _, file := range files {
-		if file.IsDir() || !strings.HasSuffix(file.Name(), ".csv") {
+		if file.IsDir() || !source.Supported(file.Name()) {
 			continue
 		}
-		csvFile := csvFilesDir + "/" + file.Name()
-		log.Printf("start to build index from file: %s", csvFile)
-		BuildIndexFromFile(csvFile, indexer, totalWorkers, workerIndex)
+		sourceFiles = append(sourceFiles, csvFilesDir+"/"+file.Name())
 	}
+	completeBuild(options, build(sourceFiles, indexer, options, report))
+	report.Log()
+
+	return report
 }
 
-// Write all documents in csvFile to indexer
-//
-// totalWorkers: total number of workers; workerIndex: index of this worker, set to 0 if only one worker
-func BuildIndexFromFile(csvFile string, indexer IIndexer, totalWorkers, workerIndex int) {
-	file, err := os.Open(csvFile)
-	if err != nil {
-		log.Printf("open file %s failed: %s", csvFile, err)
+// ShardMap returns the current shard map of the build, nil if every document is built
+func (options BuildOptions) ShardMap() *ShardMap {
+	if options.Shards == nil {
+		return nil
+	}
+	return options.Shards.ShardMap()
+}
+
+func beginBuild(options BuildOptions) bool {
+	if options.Checkpoints == nil {
+		return true
+	}
+	if err := options.Checkpoints.Begin(); err != nil {
+		log.Printf("begin build failed: %s", err)
+		return false
+	}
+	return true
+}
+
+// completeBuild marks the index complete if every file was read to the end, the next start resumes the build otherwise
+func completeBuild(options BuildOptions, complete bool) {
+	if options.Checkpoints == nil {
+		return
+	}
+	if !complete {
+		log.Printf("build is not complete, it resumes at the next start")
 		return
 	}
-	defer file.Close()
+	if err := options.Checkpoints.Complete(); err != nil {
+		log.Printf("mark build complete failed: %s", err)
+	}
+}
 
-	queryTrie := trie.NewTrie();
-	reader := csv.NewReader(file)
-	progress := 0
-	for {
-		record, err := reader.Read()
-		if err != nil {
-			if err != io.EOF {
-				log.Printf("read record failed: %s", err)
-			}
-			break
-		}
+// Write all documents in sourceFile to indexer, in distributed mode only the documents of the shards of options.Worker.
+// Document ids are derived from the row, so building again replaces the documents instead of adding them twice.
+func BuildIndexFromFile(sourceFile string, indexer IIndexer, options BuildOptions) *BuildReport {
+	report := NewBuildReport()
+	if !beginBuild(options) {
+		return report
+	}
+	completeBuild(options, build([]string{sourceFile}, indexer, options, report))
+	report.Log()
 
-		if len(record) < 9 {
-			continue
-		}
+	return report
+}
 
-		docId := uuid.New().String()
-		
-		if totalWorkers > 0 && int(farmhash.Hash32WithSeed([]byte(docId), 0)) % totalWorkers != workerIndex {
-			log.Printf("skip document %s for worker %d", docId, workerIndex)
-			continue
-		}
-		
-		product := &search_proto.Product{
-			Id:     docId,
-			Name:  record[0],
-			Category: record[1],
-			Image: record[3],
+func firstTextField(docSchema *schema.Schema) string {
+	for _, field := range docSchema.Fields {
+		if field.Type == schema.TYPE_TEXT {
+			return field.Name
 		}
-		
-		n, _ := strconv.ParseFloat(record[5], 64)
-		product.Ratings = float64(n)
-		m, _ := strconv.Atoi(record[6])
-		product.NoRatings = int32(m)
-		
-		n, _ = strconv.ParseFloat(record[7], 64)
-		product.DiscountPrice = float64(n)
-		n, _ = strconv.ParseFloat(record[8], 64)
-		product.ActualPrice = float64(n)
-		
-		queryTrie.Insert(record[0]);
-	
-		keywords := preprocessing.PreprocessForLargeDataset(record[0])
-		if len(keywords) > 0 {
-			for _, word := range keywords {
-				word = strings.TrimSpace(word)
-				if len(word) > 0 {
-					product.Keywords = append(product.Keywords, strings.ToLower(word))
-				}
-			}
-		}
-		AddProduct2Index(product, indexer)
-		progress++
-		if progress % 100 == 0 {
-			logger.Log.Printf("processed %d documents", progress)
+	}
+	return ""
+}
+
+// DocumentId derives the id of a row from options.IdField or a hash of options.IdFields. Without them the Id of a
+// jsonl row is kept, other rows get a hash of the whole row. Hashes are name-based uuids, the same row gets the same id in every build.
+func DocumentId(doc schema.Document, record []string, options BuildOptions) string {
+	if len(options.IdField) > 0 {
+		return strings.TrimSpace(doc.String(options.IdField))
+	}
+	if len(options.IdFields) == 0 && len(doc.Id()) > 0 {
+		return strings.TrimSpace(doc.Id())
+	}
+
+	values := record
+	if len(options.IdFields) > 0 {
+		values = make([]string, 0, len(options.IdFields))
+		for _, field := range options.IdFields {
+			values = append(values, doc.String(field))
 		}
 	}
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(strings.Join(values, "\x1f"))).String()
+}
 
-	err = storeTrieToDB(queryTrie)
+// AddDocument2Index indexes the indexed fields of doc, and its category with all ancestors as posting lists when categories is not nil.
+// A document with the same id is replaced, replaced tells whether there was one.
+func AddDocument2Index(doc schema.Document, docSchema *schema.Schema, indexer IIndexer, categories category.ICategoryRegistry) (replaced bool, err error) {
+	indexDoc, err := IndexDocument(doc, docSchema, categories)
 	if err != nil {
-		panic(err)
+		return false, err
 	}
-
-	logger.Log.Printf("add %d documents to index totally", progress)
+	replaced = indexer.DeleteDoc(indexDoc.Id) > 0
+	_, err = indexer.AddDoc(*indexDoc)
+	return replaced, err
 }
 
-func AddProduct2Index(product *search_proto.Product, indexer IIndexer) {
-	doc := search_proto.Document{Id: product.Id}
-	bs, err := proto.Marshal(product)
-	if err == nil {
-		doc.Bytes = bs
-	} else {
-		log.Printf("serielize video failed: %s", err)
-		return
+// IndexDocument encodes doc and extracts its keywords the same way for the csv build and for documents pushed over the api
+func IndexDocument(doc schema.Document, docSchema *schema.Schema, categories category.ICategoryRegistry) (*search_proto.Document, error) {
+	// products keep the terms of their name for ranking
+	if field, exists := docSchema.Field("Keywords"); exists && !field.Indexed && doc["Keywords"] == nil {
+		doc["Keywords"] = docSchema.Tokens(doc, firstTextField(docSchema))
+	}
+	bs, err := docSchema.Marshal(doc)
+	if err != nil {
+		return nil, err
 	}
 
-	keywords := make([]*search_proto.Keyword, 0, len(product.Keywords))
-	for _, word := range product.Keywords {
-		keywords = append(keywords, &search_proto.Keyword{Field: "content", Word: strings.ToLower(word)})
+	keywords := docSchema.Keywords(doc)
+	if categories != nil {
+		path := docSchema.CategoryPath(doc)
+		ids, err := category.Register(categories, path)
+		if err != nil {
+			log.Printf("register category %s failed: %s", path, err)
+		}
+		for _, id := range ids {
+			keywords = append(keywords, category.Keyword(id))
+		}
 	}
-	
-	doc.Keywords = keywords
-	doc.BitsFeature = common.GetClassBits([]string{product.Category}) | common.GetClassBits(product.Keywords)
 
-	indexer.AddDoc(doc)
+	// the embedding goes to Document.Vector, it is not stored with the document
+	return &search_proto.Document{Id: doc.Id(), Bytes: bs, Keywords: keywords, Vector: docSchema.Vector(doc)}, nil
 }
 
-func storeTrieToDB(trie *trie.Trie) error {
-	trieDB, err := storage.NewTrieDB("../../internal/indexing/trie/storage/trie_bolt" )
-	if err != nil {
-		return err
+// withTrieDB runs fn on the trie db of the options, the db at TrieDBPath is opened for the call if TrieDB is nil
+func withTrieDB(options BuildOptions, fn func(trieDB *storage.TrieDB) error) error {
+	if options.TrieDB != nil {
+		return fn(options.TrieDB) // the server holds the db open while serving suggestions
+	}
+	if len(options.TrieDBPath) == 0 {
+		return nil
 	}
 
-	err = trieDB.StoreTrie(trie)
+	trieDB, err := storage.NewTrieDB(options.TrieDBPath)
 	if err != nil {
 		return err
 	}
 
-	err = trieDB.Close()
+	err = fn(trieDB)
 	if err != nil {
+		trieDB.Close()
 		return err
 	}
 
-	return nil
+	return trieDB.Close()
 }
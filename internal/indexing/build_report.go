@@ -0,0 +1,99 @@
+package indexing
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	MAX_REPORTED_DUPLICATES = 20 // duplicates listed in the log, all of them are counted
+	MAX_REPORTED_ERRORS     = 20 // row errors listed in the log and kept in the report, all of them are counted
+)
+
+// Duplicate is a row whose id was already used by an earlier row of the same build, the later row replaced the earlier one
+type Duplicate struct {
+	Id    string
+	Row   string // file:line of the row
+	First string // file:line of the first row with the id
+}
+
+// RowError is a row that could not be parsed, or a value of it that could not be converted to the type of its field
+type RowError struct {
+	Row   string // file:line of the row
+	Error string
+}
+
+// BuildReport summarizes a build
+type BuildReport struct {
+	Documents  int  // documents indexed, duplicates included
+	Replaced   int  // documents that replaced a document with the same id, of an earlier build or an earlier row
+	Skipped    int  // rows that could not be parsed, without id or failed to index
+	Unchanged  int  // rows not written since they did not change, see BuildOptions.Changed
+	Complete   bool // every file was read to the end and written, an incomplete build resumes from its checkpoints
+	Duplicates []Duplicate
+	ErrorCount int        // errors of all rows
+	Errors     []RowError // the first MAX_REPORTED_ERRORS errors
+
+	// throughput of the pipeline
+	Rows        int           // rows read, the rows before a checkpoint are not read again
+	Batches     int           // batches written to the indexer
+	Parallelism int           // analyzer goroutines
+	Elapsed     time.Duration // duration of the whole build
+	ReadWait    time.Duration // time the reader waited for the analyzers and the writer, high if the build is CPU-bound
+	AnalyzeTime time.Duration // time spent tokenizing and encoding rows, summed over the analyzers
+	WriteTime   time.Duration // time spent writing batches to the indexer
+
+	seen    map[string]string // id -> file:line of its first row
+	started time.Time
+}
+
+func NewBuildReport() *BuildReport {
+	return &BuildReport{Duplicates: make([]Duplicate, 0), Errors: make([]RowError, 0), seen: make(map[string]string), started: time.Now()}
+}
+
+// RowsPerSecond is the throughput of the build so far, or of the whole build once it is finished
+func (report *BuildReport) RowsPerSecond() float64 {
+	elapsed := report.Elapsed
+	if elapsed == 0 {
+		elapsed = time.Since(report.started)
+	}
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(report.Rows) / elapsed.Seconds()
+}
+
+func (report *BuildReport) error(file string, line int, err error) {
+	report.ErrorCount++
+	if len(report.Errors) < MAX_REPORTED_ERRORS {
+		report.Errors = append(report.Errors, RowError{Row: fmt.Sprintf("%s:%d", file, line), Error: err.Error()})
+	}
+}
+
+func (report *BuildReport) see(docId string, file string, line int) {
+	row := fmt.Sprintf("%s:%d", file, line)
+	if first, exists := report.seen[docId]; exists {
+		report.Duplicates = append(report.Duplicates, Duplicate{Id: docId, Row: row, First: first})
+		return
+	}
+	report.seen[docId] = row
+}
+
+// Log prints the counts and the first duplicates
+func (report *BuildReport) Log() {
+	log.Printf("build finished: %d documents indexed, %d replaced, %d skipped, %d duplicate ids, %d row errors",
+		report.Documents, report.Replaced, report.Skipped, len(report.Duplicates), report.ErrorCount)
+	log.Printf("read %d rows in %s, %.0f rows/s, %d analyzers busy for %s, %d batches written in %s, reader waited %s",
+		report.Rows, report.Elapsed, report.RowsPerSecond(), report.Parallelism, report.AnalyzeTime, report.Batches, report.WriteTime, report.ReadWait)
+	for _, rowErr := range report.Errors {
+		log.Printf("row %s: %s", rowErr.Row, rowErr.Error)
+	}
+	for i, duplicate := range report.Duplicates {
+		if i == MAX_REPORTED_DUPLICATES {
+			log.Printf("... %d more duplicates", len(report.Duplicates)-i)
+			break
+		}
+		log.Printf("duplicate id %s at %s, first seen at %s", duplicate.Id, duplicate.Row, duplicate.First)
+	}
+}
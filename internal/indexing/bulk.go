@@ -0,0 +1,151 @@
+package indexing
+
+import (
+	"bytes"
+	"encoding/gob"
+	"strings"
+	"sync/atomic"
+
+	index "github.com/m1i3k0e7/distributed-search-engine/api/proto/index"
+	search_proto "github.com/m1i3k0e7/distributed-search-engine/api/proto/search"
+	"github.com/m1i3k0e7/distributed-search-engine/pkg/logger"
+)
+
+const (
+	BULK_BATCH_SIZE = 1000 // operations written to the forward index and the inverted index at once
+)
+
+func bulkDocId(operation *index.BulkOperation) string {
+	if operation.Doc != nil {
+		return strings.TrimSpace(operation.Doc.Id)
+	}
+	return strings.TrimSpace(operation.DeleteId)
+}
+
+// Bulk applies the operations in order and returns the result of each. Operations are applied in batches,
+// a document changed twice starts a new batch so the later operation wins.
+func (indexer *Indexer) Bulk(operations []*index.BulkOperation) []*index.BulkResult {
+	results := make([]*index.BulkResult, 0, len(operations))
+	start := 0
+	batch := make(map[string]struct{}, len(operations))
+	for i, operation := range operations {
+		docId := bulkDocId(operation)
+		if _, exists := batch[docId]; exists {
+			results = append(results, indexer.bulk(operations[start:i])...)
+			start = i
+			clear(batch)
+		}
+		batch[docId] = struct{}{}
+	}
+
+	return append(results, indexer.bulk(operations[start:])...)
+}
+
+// bulk applies operations on distinct documents with one read, one delete and one write of the forward index
+func (indexer *Indexer) bulk(operations []*index.BulkOperation) []*index.BulkResult {
+	results := make([]*index.BulkResult, len(operations))
+	for i := range results {
+		results[i] = &index.BulkResult{}
+	}
+	if len(operations) == 0 {
+		return results
+	}
+
+	keys := make([][]byte, 0, len(operations))
+	for _, operation := range operations {
+		keys = append(keys, []byte(bulkDocId(operation)))
+	}
+	olds, err := indexer.forwardIndex.BatchGet(keys)
+	if err != nil {
+		logger.Log.Printf("read kvdb failed: %s", err)
+		for _, result := range results {
+			result.Error = err.Error()
+		}
+		return results
+	}
+
+	// remove the current version of every document of the batch from the inverted index and the vector index
+	existing := make(map[string]bool, len(olds))
+	reader := bytes.NewReader([]byte{})
+	for _, docBs := range olds {
+		if len(docBs) == 0 {
+			continue
+		}
+		reader.Reset(docBs)
+		var doc search_proto.Document
+		if err := gob.NewDecoder(reader).Decode(&doc); err != nil {
+			continue
+		}
+		for _, kw := range doc.Keywords {
+			indexer.reverseIndex.Delete(doc.IntId, kw)
+		}
+		indexer.vectorIndex.Delete(doc.Id)
+		existing[strings.TrimSpace(doc.Id)] = true
+	}
+
+	deleteKeys := make([][]byte, 0, len(operations))
+	setKeys := make([][]byte, 0, len(operations))
+	values := make([][]byte, 0, len(operations))
+	docs := make([]*search_proto.Document, 0, len(operations))
+	indexed := make([]int, 0, len(operations)) // position of each doc in operations
+	var docNum int64
+	for i, operation := range operations {
+		docId := bulkDocId(operation)
+		if len(docId) == 0 {
+			results[i].Error = "document id is empty"
+			continue
+		}
+		if existing[docId] {
+			docNum--
+		}
+
+		if operation.Doc == nil {
+			deleteKeys = append(deleteKeys, []byte(docId))
+			if existing[docId] {
+				results[i].Count = 1
+			}
+			continue
+		}
+
+		doc := operation.Doc
+		doc.IntId = atomic.AddUint64(&indexer.maxIntId, 1) // assign a new IntId to the document
+		var value bytes.Buffer
+		if err := gob.NewEncoder(&value).Encode(doc); err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		setKeys = append(setKeys, []byte(docId))
+		values = append(values, value.Bytes())
+		docs = append(docs, doc)
+		indexed = append(indexed, i)
+		docNum++
+	}
+
+	if len(deleteKeys) > 0 {
+		if err := indexer.forwardIndex.BatchDelete(deleteKeys); err != nil {
+			for i, operation := range operations {
+				if operation.Doc == nil {
+					results[i].Count, results[i].Error = 0, err.Error()
+				}
+			}
+		}
+	}
+	if len(setKeys) > 0 {
+		if err := indexer.forwardIndex.BatchSet(setKeys, values); err != nil {
+			for _, i := range indexed {
+				results[i].Error = err.Error()
+			}
+			return results
+		}
+	}
+
+	indexer.reverseIndex.BatchAdd(docs)
+	for _, i := range indexed {
+		indexer.addVector(operations[i].Doc)
+		results[i].Count = 1
+		results[i].Replaced = existing[bulkDocId(operations[i])]
+	}
+	atomic.AddInt64(&indexer.docNum, docNum)
+
+	return results
+}
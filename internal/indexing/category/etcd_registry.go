@@ -0,0 +1,206 @@
+package category
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/m1i3k0e7/distributed-search-engine/pkg/logger"
+	etcdv3 "go.etcd.io/etcd/client/v3"
+)
+
+const (
+	CATEGORY_ROOT_PATH = "/radic/category" // prefix path in etcd key-value store
+	CATEGORY_MISS_TTL  = 5 * time.Second   // how long a category missing in etcd is not looked up again
+)
+
+// EtcdRegistry shares categories across the cluster through etcd, so that every worker and the sentinel agree on the ids
+type EtcdRegistry struct {
+	client *etcdv3.Client
+	root   string            // prefix of the keys of this registry
+	ids    map[string]uint32 // local cache, ids never change once assigned
+	names  map[uint32]string
+	misses map[string]time.Time // names missing in etcd -> when they are looked up again
+	lock   sync.RWMutex
+}
+
+// NewEtcdRegistry keeps the categories under root, CATEGORY_ROOT_PATH if empty
+func NewEtcdRegistry(etcdServers []string, root string) (*EtcdRegistry, error) {
+	if len(root) == 0 {
+		root = CATEGORY_ROOT_PATH
+	}
+
+	client, err := etcdv3.New(
+		etcdv3.Config{
+			Endpoints:   etcdServers,
+			DialTimeout: 3 * time.Second,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	registry := &EtcdRegistry{
+		client: client,
+		root:   root,
+		ids:    make(map[string]uint32, 100),
+		names:  make(map[uint32]string, 100),
+		misses: make(map[string]time.Time),
+	}
+	registry.Names() // warm up the cache
+
+	return registry, nil
+}
+
+func (registry *EtcdRegistry) nameKey(name string) string {
+	return registry.root + "/name/" + name
+}
+
+func (registry *EtcdRegistry) idKey(id uint32) string {
+	return registry.root + "/id/" + strconv.FormatUint(uint64(id), 10)
+}
+
+func (registry *EtcdRegistry) counterKey() string {
+	return registry.root + "/next_id"
+}
+
+func (registry *EtcdRegistry) cache(name string, id uint32) {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+	registry.ids[name] = id
+	registry.names[id] = name
+	delete(registry.misses, name)
+}
+
+// missed remembers for CATEGORY_MISS_TTL that the category is not in etcd
+func (registry *EtcdRegistry) missed(name string) {
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+	registry.misses[name] = time.Now().Add(CATEGORY_MISS_TTL)
+}
+
+// GetOrCreate assigns the next id with a compare-and-swap transaction on the counter, retrying when another node wins the race
+func (registry *EtcdRegistry) GetOrCreate(name string) (uint32, error) {
+	name = normalize(name)
+	if id, exists := registry.Lookup(name); exists {
+		return id, nil
+	}
+
+	ctx := context.Background()
+	for {
+		resp, err := registry.client.Get(ctx, registry.counterKey())
+		if err != nil {
+			return 0, err
+		}
+		var next uint64 = 1 // 0 is never assigned
+		var counterCmp etcdv3.Cmp
+		if len(resp.Kvs) > 0 {
+			next, _ = strconv.ParseUint(string(resp.Kvs[0].Value), 10, 32)
+			counterCmp = etcdv3.Compare(etcdv3.ModRevision(registry.counterKey()), "=", resp.Kvs[0].ModRevision)
+		} else {
+			counterCmp = etcdv3.Compare(etcdv3.CreateRevision(registry.counterKey()), "=", 0)
+		}
+
+		txn, err := registry.client.Txn(ctx).
+			If(counterCmp, etcdv3.Compare(etcdv3.CreateRevision(registry.nameKey(name)), "=", 0)).
+			Then(
+				etcdv3.OpPut(registry.nameKey(name), strconv.FormatUint(next, 10)),
+				etcdv3.OpPut(registry.idKey(uint32(next)), name),
+				etcdv3.OpPut(registry.counterKey(), strconv.FormatUint(next+1, 10)),
+			).
+			Else(etcdv3.OpGet(registry.nameKey(name))).
+			Commit()
+		if err != nil {
+			return 0, err
+		}
+		if txn.Succeeded {
+			registry.cache(name, uint32(next))
+			logger.Log.Printf("assign id %d to category %s", next, name)
+			return uint32(next), nil
+		}
+
+		// either the category was created by another node, or the counter moved
+		if kvs := txn.Responses[0].GetResponseRange().Kvs; len(kvs) > 0 {
+			id, err := strconv.ParseUint(string(kvs[0].Value), 10, 32)
+			if err != nil {
+				return 0, err
+			}
+			registry.cache(name, uint32(id))
+			return uint32(id), nil
+		}
+	}
+}
+
+// Lookup reads a category missing in the cache from etcd, a category another node creates is seen within CATEGORY_MISS_TTL
+func (registry *EtcdRegistry) Lookup(name string) (uint32, bool) {
+	name = normalize(name)
+	registry.lock.RLock()
+	id, exists := registry.ids[name]
+	retry := registry.misses[name]
+	registry.lock.RUnlock()
+	if exists {
+		return id, true
+	}
+	if time.Now().Before(retry) {
+		return 0, false
+	}
+
+	// the category may have been created by another node, a search for unknown categories does not reach etcd every time
+	resp, err := registry.client.Get(context.Background(), registry.nameKey(name))
+	if err != nil {
+		return 0, false
+	}
+	if len(resp.Kvs) == 0 {
+		registry.missed(name)
+		return 0, false
+	}
+	parsed, err := strconv.ParseUint(string(resp.Kvs[0].Value), 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	registry.cache(name, uint32(parsed))
+
+	return uint32(parsed), true
+}
+
+func (registry *EtcdRegistry) Name(id uint32) (string, bool) {
+	registry.lock.RLock()
+	name, exists := registry.names[id]
+	registry.lock.RUnlock()
+	if exists {
+		return name, true
+	}
+
+	resp, err := registry.client.Get(context.Background(), registry.idKey(id))
+	if err != nil || len(resp.Kvs) == 0 {
+		return "", false
+	}
+	registry.cache(string(resp.Kvs[0].Value), id)
+
+	return string(resp.Kvs[0].Value), true
+}
+
+// Names reads all categories from etcd, other nodes may have created categories this node has not seen
+func (registry *EtcdRegistry) Names() ([]string, error) {
+	resp, err := registry.client.Get(context.Background(), registry.nameKey(""), etcdv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		if id, err := strconv.ParseUint(string(kv.Value), 10, 32); err == nil {
+			name := strings.TrimPrefix(string(kv.Key), registry.nameKey(""))
+			registry.cache(name, uint32(id))
+			names = append(names, name)
+		}
+	}
+
+	return names, nil
+}
+
+func (registry *EtcdRegistry) Close() error {
+	return registry.client.Close()
+}
@@ -0,0 +1,105 @@
+package category
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/kvdb"
+	"github.com/m1i3k0e7/distributed-search-engine/pkg/numeric"
+)
+
+var (
+	nextIdKey  = []byte("next_id")
+	namePrefix = "name/" // keys of the categories, so a category named next_id does not overwrite the counter
+)
+
+// LocalRegistry keeps categories in a local kvdb, for standalone mode
+type LocalRegistry struct {
+	db    kvdb.IKeyValueDB
+	ids   map[string]uint32
+	names map[uint32]string
+	next  uint32
+	lock  sync.RWMutex
+}
+
+func NewLocalRegistry(dbtype int, dbPath string) (*LocalRegistry, error) {
+	db, err := kvdb.GetKvDb(dbtype, dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	registry := &LocalRegistry{
+		db:    db,
+		ids:   make(map[string]uint32, 100),
+		names: make(map[uint32]string, 100),
+		next:  1, // 0 is never assigned
+	}
+	if bs, err := db.Get(nextIdKey); err == nil && len(bs) > 0 {
+		registry.next = uint32(numeric.BytesToInt(bs))
+	}
+	db.IterDB(func(k, v []byte) error {
+		if string(k) == string(nextIdKey) {
+			return nil
+		}
+		// categories stored before the prefix was introduced are keyed by their name only
+		name := strings.TrimPrefix(string(k), namePrefix)
+		id := uint32(numeric.BytesToInt(v))
+		registry.ids[name] = id
+		registry.names[id] = name
+		return nil
+	})
+
+	return registry, nil
+}
+
+func (registry *LocalRegistry) GetOrCreate(name string) (uint32, error) {
+	name = normalize(name)
+	if id, exists := registry.Lookup(name); exists {
+		return id, nil
+	}
+
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+	if id, exists := registry.ids[name]; exists { // double check, another goroutine may have created it
+		return id, nil
+	}
+
+	id := registry.next
+	err := registry.db.BatchSet([][]byte{[]byte(namePrefix + name), nextIdKey}, [][]byte{numeric.IntToBytes(int(id)), numeric.IntToBytes(int(id + 1))})
+	if err != nil {
+		return 0, err
+	}
+	registry.next++
+	registry.ids[name] = id
+	registry.names[id] = name
+
+	return id, nil
+}
+
+func (registry *LocalRegistry) Lookup(name string) (uint32, bool) {
+	registry.lock.RLock()
+	defer registry.lock.RUnlock()
+	id, exists := registry.ids[normalize(name)]
+	return id, exists
+}
+
+func (registry *LocalRegistry) Name(id uint32) (string, bool) {
+	registry.lock.RLock()
+	defer registry.lock.RUnlock()
+	name, exists := registry.names[id]
+	return name, exists
+}
+
+func (registry *LocalRegistry) Names() ([]string, error) {
+	registry.lock.RLock()
+	defer registry.lock.RUnlock()
+	names := make([]string, 0, len(registry.ids))
+	for name := range registry.ids {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func (registry *LocalRegistry) Close() error {
+	return registry.db.Close()
+}
@@ -0,0 +1,60 @@
+package category
+
+import (
+	"strconv"
+	"strings"
+
+	search_proto "github.com/m1i3k0e7/distributed-search-engine/api/proto/search"
+)
+
+const (
+	FIELD = "category" // Keyword.Field of category postings, Keyword.Word is the category id
+)
+
+// ICategoryRegistry assigns a stable id to every category the first time it is seen.
+// Categories are indexed as posting lists keyed by their id, so there is no limit on the number of categories.
+type ICategoryRegistry interface {
+	GetOrCreate(name string) (uint32, error) // id of the category, a new id is assigned on first sight
+	Lookup(name string) (uint32, bool)       // id of the category, false if it has never been seen
+	Name(id uint32) (string, bool)           // reverse of Lookup
+	Names() ([]string, error)                // all categories ever seen
+	Close() error
+}
+
+func normalize(name string) string {
+	return strings.TrimSpace(name)
+}
+
+// Keyword is the inverted index keyword of a category id
+func Keyword(id uint32) *search_proto.Keyword {
+	return &search_proto.Keyword{Field: FIELD, Word: strconv.FormatUint(uint64(id), 10)}
+}
+
+// ParseKeyword is the reverse of Keyword, it returns false for keywords of other fields
+func ParseKeyword(keyword *search_proto.Keyword) (uint32, bool) {
+	if keyword == nil || keyword.Field != FIELD {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(keyword.Word, 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return uint32(id), true
+}
+
+// Query builds a Should query matching any of the categories. It returns false if none of the categories has been seen,
+// in which case nothing can match.
+func Query(registry ICategoryRegistry, names []string) (*search_proto.TermQuery, bool) {
+	should := make([]*search_proto.TermQuery, 0, len(names))
+	for _, name := range names {
+		if id, exists := registry.Lookup(name); exists {
+			keyword := Keyword(id)
+			should = append(should, search_proto.NewTermQuery(keyword.Field, keyword.Word))
+		}
+	}
+	if len(should) == 0 {
+		return nil, false
+	}
+
+	return &search_proto.TermQuery{Should: should}, true
+}
@@ -0,0 +1,79 @@
+package category
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/kvdb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	etcdv3 "go.etcd.io/etcd/client/v3"
+)
+
+func TestLocalRegistryNextId(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "categories")
+	registry, err := NewLocalRegistry(kvdb.BOLT, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"next_id", "shoes", "hats"} {
+		if _, err := registry.GetOrCreate(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	registry.Close()
+
+	// a category named next_id does not reset the counter when the registry is opened again
+	registry, err = NewLocalRegistry(kvdb.BOLT, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer registry.Close()
+	if id, exists := registry.Lookup("next_id"); !exists || id != 1 {
+		t.Errorf("expect next_id to keep id 1, got %d %v", id, exists)
+	}
+	if id, err := registry.GetOrCreate("shirts"); err != nil || id != 4 {
+		t.Errorf("expect the next id 4, got %d %v", id, err)
+	}
+}
+
+// countingKV holds the categories of an etcd registry and counts the reads
+type countingKV struct {
+	etcdv3.KV
+	values map[string]string
+	gets   int
+}
+
+func (kv *countingKV) Get(ctx context.Context, key string, opts ...etcdv3.OpOption) (*etcdv3.GetResponse, error) {
+	kv.gets++
+	resp := &etcdv3.GetResponse{}
+	if value, exists := kv.values[key]; exists {
+		resp.Kvs = append(resp.Kvs, &mvccpb.KeyValue{Key: []byte(key), Value: []byte(value)})
+	}
+	return resp, nil
+}
+
+func TestEtcdLookupCachesMisses(t *testing.T) {
+	kv := &countingKV{values: map[string]string{}}
+	registry := &EtcdRegistry{client: &etcdv3.Client{KV: kv}, root: CATEGORY_ROOT_PATH, ids: map[string]uint32{}, names: map[uint32]string{}, misses: map[string]time.Time{}}
+
+	for i := 0; i < 3; i++ {
+		if _, exists := registry.Lookup("shoes"); exists {
+			t.Fatalf("expect shoes to be missing")
+		}
+	}
+	if kv.gets != 1 {
+		t.Errorf("expect a missing category to be read from etcd once, got %d reads", kv.gets)
+	}
+
+	// another node creates the category, it is seen once the miss expires
+	kv.values[registry.nameKey("shoes")] = "7"
+	registry.misses["shoes"] = time.Now().Add(-time.Millisecond)
+	if id, exists := registry.Lookup("shoes"); !exists || id != 7 {
+		t.Errorf("expect id 7 after the miss expired, got %d %v", id, exists)
+	}
+	if id, exists := registry.Lookup("shoes"); !exists || id != 7 || kv.gets != 2 {
+		t.Errorf("expect the cached id 7 without reading etcd again, got %d %v after %d reads", id, exists, kv.gets)
+	}
+}
@@ -0,0 +1,114 @@
+package category
+
+import (
+	"sort"
+	"strings"
+)
+
+const (
+	SEPARATOR = "/" // separates the levels of a category path, e.g. "appliances/Air Conditioners"
+)
+
+var (
+	// a separator in a level name is escaped, so "Headphones/Earphones" stays one level
+	levelEscaper   = strings.NewReplacer("%", "%25", SEPARATOR, "%2F")
+	levelUnescaper = strings.NewReplacer("%2F", SEPARATOR, "%25", "%")
+)
+
+// Node is a category in the taxonomy tree, Path is escaped as by Path and Count is the number of products under it including all descendants
+type Node struct {
+	Name     string
+	Path     string
+	Level    int // 0 for main categories
+	Count    int
+	Children []*Node
+}
+
+// Path joins the levels of a category from the top down, empty levels end the path.
+// Separators and % in a level are escaped as %2F and %25.
+func Path(levels ...string) string {
+	parts := make([]string, 0, len(levels))
+	for _, level := range levels {
+		level = normalize(level)
+		if len(level) == 0 {
+			break
+		}
+		parts = append(parts, levelEscaper.Replace(level))
+	}
+
+	return strings.Join(parts, SEPARATOR)
+}
+
+// Ancestors returns every prefix of path, from the main category down to path itself.
+// A product is indexed under all of them, so filtering by a node also matches its descendants.
+func Ancestors(path string) []string {
+	path = normalize(path)
+	if len(path) == 0 {
+		return nil
+	}
+
+	parts := strings.Split(path, SEPARATOR)
+	ancestors := make([]string, 0, len(parts))
+	for i := range parts {
+		ancestors = append(ancestors, strings.Join(parts[:i+1], SEPARATOR))
+	}
+
+	return ancestors
+}
+
+// Register assigns ids to path and all its ancestors, in the same order as Ancestors
+func Register(registry ICategoryRegistry, path string) ([]uint32, error) {
+	ancestors := Ancestors(path)
+	ids := make([]uint32, 0, len(ancestors))
+	for _, ancestor := range ancestors {
+		id, err := registry.GetOrCreate(ancestor)
+		if err != nil {
+			return ids, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// Tree builds the taxonomy from category paths and their counts. Children are ordered by count descending, then by name.
+func Tree(counts map[string]int) []*Node {
+	root := &Node{Level: -1}
+	nodes := map[string]*Node{"": root}
+
+	paths := make([]string, 0, len(counts))
+	for path := range counts {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths) // parents sort before their children
+
+	for _, path := range paths {
+		parent := root
+		for _, ancestor := range Ancestors(path) {
+			node, exists := nodes[ancestor]
+			if !exists {
+				name := levelUnescaper.Replace(ancestor[strings.LastIndex(ancestor, SEPARATOR)+1:])
+				node = &Node{Name: name, Path: ancestor, Level: parent.Level + 1}
+				nodes[ancestor] = node
+				parent.Children = append(parent.Children, node)
+			}
+			parent = node
+		}
+		parent.Count = counts[path]
+	}
+
+	sortNodes(root.Children)
+	return root.Children
+}
+
+func sortNodes(nodes []*Node) {
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Count == nodes[j].Count {
+			return nodes[i].Name < nodes[j].Name
+		}
+		return nodes[i].Count > nodes[j].Count
+	})
+	for _, node := range nodes {
+		sortNodes(node.Children)
+	}
+}
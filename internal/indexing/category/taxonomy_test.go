@@ -0,0 +1,27 @@
+package category
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPathEscapesSeparator(t *testing.T) {
+	path := Path("electronics", "Headphones/Earphones", "")
+	if path != "electronics/Headphones%2FEarphones" {
+		t.Fatalf("expect the separator in a level to be escaped, got %s", path)
+	}
+	if ancestors := Ancestors(path); !slices.Equal(ancestors, []string{"electronics", path}) {
+		t.Errorf("expect 2 levels, got %v", ancestors)
+	}
+	if Path("100%", "50%2F") != "100%25/50%252F" {
+		t.Errorf("expect %% to be escaped, got %s", Path("100%", "50%2F"))
+	}
+
+	tree := Tree(map[string]int{"electronics": 2, path: 2, "100%25": 1, Path("100%", "50%2F"): 1})
+	if len(tree) != 2 || tree[0].Children[0].Name != "Headphones/Earphones" || tree[0].Children[0].Path != path {
+		t.Fatalf("unexpected tree %+v", tree[0].Children[0])
+	}
+	if node := tree[1]; node.Name != "100%" || node.Children[0].Name != "50%2F" {
+		t.Errorf("expect the names of the levels unescaped, got %s and %s", node.Name, node.Children[0].Name)
+	}
+}
@@ -0,0 +1,84 @@
+package indexing
+
+import (
+	"strconv"
+
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/kvdb"
+)
+
+const (
+	BUILD_STATE_KEY  = "state"
+	BUILD_BUILDING   = "building"
+	BUILD_COMPLETE   = "complete"
+	FILE_DONE        = "done"
+	checkpointPrefix = "file:"
+)
+
+// Checkpoints records the progress of a build in a kvdb next to the index: the state of the build and the number of rows
+// indexed of each file. A crashed build resumes from the last checkpoint, rows indexed again after it are replaced
+// since document ids are derived from the rows. Indexes built before checkpoints existed have no state and count as complete.
+type Checkpoints struct {
+	db kvdb.IKeyValueDB
+}
+
+func OpenCheckpoints(dbtype int, path string) (*Checkpoints, error) {
+	db, err := kvdb.GetKvDb(dbtype, path)
+	if err != nil {
+		return nil, err
+	}
+	return &Checkpoints{db: db}, nil
+}
+
+// Building tells whether a build started and did not complete, the index must not be served then
+func (checkpoints *Checkpoints) Building() bool {
+	state, _ := checkpoints.db.Get([]byte(BUILD_STATE_KEY))
+	return string(state) == BUILD_BUILDING
+}
+
+// Begin starts a build, or keeps the progress of an unfinished build to resume it
+func (checkpoints *Checkpoints) Begin() error {
+	if checkpoints.Building() {
+		return nil
+	}
+
+	keys := make([][]byte, 0)
+	checkpoints.db.IterKey(func(k []byte) error {
+		keys = append(keys, append([]byte(nil), k...))
+		return nil
+	})
+	if err := checkpoints.db.BatchDelete(keys); err != nil {
+		return err
+	}
+	return checkpoints.db.Set([]byte(BUILD_STATE_KEY), []byte(BUILD_BUILDING))
+}
+
+// Progress returns the number of rows of file indexed before, and whether the file is done
+func (checkpoints *Checkpoints) Progress(file string) (int, bool) {
+	value, err := checkpoints.db.Get([]byte(checkpointPrefix + file))
+	if err != nil {
+		return 0, false
+	}
+	if string(value) == FILE_DONE {
+		return 0, true
+	}
+	rows, _ := strconv.Atoi(string(value))
+	return rows, false
+}
+
+// Save records that the first rows of file are indexed
+func (checkpoints *Checkpoints) Save(file string, rows int) error {
+	return checkpoints.db.Set([]byte(checkpointPrefix+file), []byte(strconv.Itoa(rows)))
+}
+
+func (checkpoints *Checkpoints) FinishFile(file string) error {
+	return checkpoints.db.Set([]byte(checkpointPrefix+file), []byte(FILE_DONE))
+}
+
+// Complete marks the index ready to be served
+func (checkpoints *Checkpoints) Complete() error {
+	return checkpoints.db.Set([]byte(BUILD_STATE_KEY), []byte(BUILD_COMPLETE))
+}
+
+func (checkpoints *Checkpoints) Close() error {
+	return checkpoints.db.Close()
+}
@@ -0,0 +1,58 @@
+package indexing
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/kvdb"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/trie"
+)
+
+func TestResumeKeepsSuggestions(t *testing.T) {
+	dir := t.TempDir()
+	sourceFile := filepath.Join(dir, "products.csv")
+	if err := os.WriteFile(sourceFile, []byte("red shoe,shoes\nred shirt,shirts\nred hat,hats\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	indexer := new(Indexer)
+	if err := indexer.Init(100, kvdb.BOLT, filepath.Join(dir, "index")); err != nil {
+		t.Fatal(err)
+	}
+	defer indexer.Close()
+
+	// a build interrupted after the first 2 rows
+	checkpoints, err := OpenCheckpoints(kvdb.BOLT, filepath.Join(dir, "checkpoints"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer checkpoints.Close()
+	if err := checkpoints.Begin(); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkpoints.Save("products.csv", 2); err != nil {
+		t.Fatal(err)
+	}
+
+	trieDBPath := filepath.Join(dir, "trie")
+	report := BuildIndexFromFile(sourceFile, indexer, BuildOptions{Checkpoints: checkpoints, TrieDBPath: trieDBPath})
+	if !report.Complete || report.Documents != 1 {
+		t.Fatalf("expect the last row only to be written, got %d documents, complete %v", report.Documents, report.Complete)
+	}
+
+	// the suggestions of the file still hold the titles of the rows indexed before the checkpoint
+	trieDB, err := storage.NewTrieDB(trieDBPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer trieDB.Close()
+	suggestions, err := trieDB.AssociateQuery("red")
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(suggestions)
+	if expect := []string{" red hat", " red shirt", " red shoe"}; !slices.Equal(suggestions, expect) {
+		t.Errorf("expect suggestions %v, got %v", expect, suggestions)
+	}
+}
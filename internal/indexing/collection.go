@@ -0,0 +1,97 @@
+package indexing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/category"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/schema"
+	"github.com/m1i3k0e7/distributed-search-engine/pkg/ranking"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	DEFAULT_COLLECTION      = "default"           // the collection built from csv files at startup, it can not be dropped
+	COLLECTION_ROOT_PATH    = "/radic/collection" // prefix path of collection schemas in etcd
+	ALIAS_ROOT_PATH         = "/radic/alias"      // prefix path of aliases in etcd, the value is the collection name
+	COLLECTION_METADATA_KEY = "collection"        // grpc metadata naming the collection of a request to a worker
+)
+
+var collectionNamePattern = regexp.MustCompile(`^[a-z0-9_-]{1,64}$`)
+
+var (
+	ErrCollectionNotFound = errors.New("collection not found")
+	ErrCollectionAliased  = errors.New("collection is pointed to by an alias") // it can not be dropped
+	ErrCollectionBuilding = errors.New("collection is being built")            // no alias can point to it yet
+)
+
+// Collection is a named index with its own schema, analyzers and categories
+type Collection struct {
+	Name       string
+	Schema     *schema.Schema
+	Ranking    ranking.BM25FConfig // field weights of the collection, each request may override them
+	Indexer    IIndexer
+	Categories category.ICategoryRegistry
+}
+
+// ICollections manages the collections of a standalone server or of a cluster.
+// An alias points to a collection and can be repointed atomically, Get resolves aliases.
+type ICollections interface {
+	Create(name string, docSchema *schema.Schema) error
+	Drop(name string) error // collections pointed to by an alias can not be dropped, see ErrCollectionAliased
+	Get(name string) (*Collection, bool)
+	List() ([]string, error)
+	// SetBuilding marks a collection whose build started, until the build completes. The mark outlives a crash of the build.
+	SetBuilding(name string, building bool) error
+	// SetAlias fails for a collection being built, expected is the current target, checked unless empty
+	SetAlias(alias string, collection string, expected string) error
+	RemoveAlias(alias string) error
+	Aliases() (map[string]string, error)
+	Close() error
+}
+
+func CheckCollectionName(name string) error {
+	if !collectionNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid collection name %q, only lower case letters, digits, _ and - are allowed", name)
+	}
+	return nil
+}
+
+// CollectionService is the service name workers hosting the collection register in etcd
+func CollectionService(name string) string {
+	return INDEX_SERVICE + "/" + name
+}
+
+// WithCollection names the collection of an outgoing request to a worker
+func WithCollection(ctx context.Context, name string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, COLLECTION_METADATA_KEY, name)
+}
+
+// CollectionFromContext returns the collection of an incoming request, DEFAULT_COLLECTION if it is not named
+func CollectionFromContext(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(COLLECTION_METADATA_KEY); len(values) > 0 && len(values[0]) > 0 {
+			return values[0]
+		}
+	}
+	return DEFAULT_COLLECTION
+}
+
+func collectionKey(name string) string {
+	return COLLECTION_ROOT_PATH + "/" + name + "/schema"
+}
+
+// collectionBuildingKey exists while a build of the collection runs, workers only watch the schema key
+func collectionBuildingKey(name string) string {
+	return COLLECTION_ROOT_PATH + "/" + name + "/building"
+}
+
+func aliasKey(alias string) string {
+	return ALIAS_ROOT_PATH + "/" + alias
+}
+
+func collectionCategoryPath(name string) string {
+	return COLLECTION_ROOT_PATH + "/" + name + "/category"
+}
@@ -3,8 +3,12 @@ package indexing
 import (
 	context "context"
 	"fmt"
+	"io"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	search_proto "github.com/m1i3k0e7/distributed-search-engine/api/proto/search"
 	"github.com/m1i3k0e7/distributed-search-engine/api/proto/index"
@@ -16,19 +20,36 @@ import (
 )
 
 type Sentinel struct {
-	hub      service_hub.IServiceHub // get the set of IndexServiceWorker endpoints from ServiceHub or ServiceHubProxy
-	connPool sync.Map    // connection pool, key: endpoint, value: *grpc.ClientConn
+	hub        service_hub.IServiceHub // get the set of IndexServiceWorker endpoints from ServiceHub or ServiceHubProxy
+	connPool   sync.Map    // connection pool, key: endpoint, value: *grpc.ClientConn
+	collection string      // every request is scoped to this collection
+	service    string      // workers hosting the collection register under this service
+	shards     *ShardRouter // every document id is routed to the worker hosting its shard
+	breakers   sync.Map    // key: endpoint, value: *circuitBreaker
+	latencies  sync.Map    // key: read operation, value: *latencyWindow
 }
 
-func NewSentinel(etcdServers []string) *Sentinel {
+func NewSentinel(etcdServers []string, collection string) *Sentinel {
 	return &Sentinel{
 		// hub: GetServiceHub(etcdServers, 10)
-		hub:      service_hub.GetServiceHubProxy(etcdServers, 10, 100), // via Service Hub Proxy
-		connPool: sync.Map{},
+		hub:        service_hub.GetServiceHubProxy(etcdServers, 10, 100), // via Service Hub Proxy
+		connPool:   sync.Map{},
+		collection: collection,
+		service:    CollectionService(collection),
+		shards:     GetShardRouter(etcdServers),
 	}
 }
 
+// context names the collection of the sentinel in requests to workers
+func (sentinel *Sentinel) context() context.Context {
+	return WithCollection(context.Background(), sentinel.collection)
+}
+
+// GetGrpcConn returns nil while the circuit of the worker is open, see circuitBreaker
 func (sentinel *Sentinel) GetGrpcConn(endpoint string) *grpc.ClientConn {
+	if !sentinel.breaker(endpoint).allow() {
+		return nil
+	}
 	if v, exists := sentinel.connPool.Load(endpoint); exists {
 		conn := v.(*grpc.ClientConn)
 		// delete the connection if it is not in a connecting or ready state
@@ -40,7 +61,7 @@ func (sentinel *Sentinel) GetGrpcConn(endpoint string) *grpc.ClientConn {
 			return conn // return the existing connection if it is still valid
 		}
 	}
-	// ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	// ctx, cancel := context.WithTimeout(sentinel.context(), 200*time.Millisecond)
 	// defer cancel()
 	conn, err := grpc.NewClient(
 		endpoint,
@@ -58,121 +79,734 @@ func (sentinel *Sentinel) GetGrpcConn(endpoint string) *grpc.ClientConn {
 	return conn
 }
 
+// AddDoc writes the document to every alive replica of its shard
 func (sentinel *Sentinel) AddDoc(doc search_proto.Document) (int, error) {
-	endpoint := sentinel.hub.GetServiceEndpoint(INDEX_SERVICE) // select one IndexServiceWorker endpoint according to the load balancing policy
-	if len(endpoint) == 0 {
-		return 0, fmt.Errorf("there is no alive index worker")
-	}
-
-	conn := sentinel.GetGrpcConn(endpoint)
-	if conn == nil {
-		return 0, fmt.Errorf("connect to worker %s failed", endpoint)
-	}
-
-	client := index.NewIndexServiceClient(conn)
-	affected, err := client.AddDoc(context.Background(), &doc)
+	n, err := sentinel.replicate(doc.Id, func(client index.IndexServiceClient) (*index.AffectedCount, error) {
+		return client.AddDoc(sentinel.context(), &doc)
+	})
 	if err != nil {
 		return 0, err
 	}
 
-	logger.Log.Printf("add %d doc to worker %s", affected.Count, endpoint)
+	logger.Log.Printf("add %d doc to the replicas of shard %d", n, sentinel.shards.ShardMap().Shard(doc.Id))
 
-	return int(affected.Count), nil
+	return n, nil
 }
 
+// UpdateDoc replaces the document on the replicas of its shard, no other worker can hold a copy
 func (sentinel *Sentinel) UpdateDoc(doc search_proto.Document) (int, error) {
-	sentinel.DeleteDoc(doc.Id)
-	return sentinel.AddDoc(doc)
+	result := sentinel.Bulk([]*index.BulkOperation{{Doc: &doc}})[0]
+	if len(result.Error) > 0 {
+		return 0, fmt.Errorf("%s", result.Error)
+	}
+	return int(result.Count), nil
 }
 
 func (sentinel *Sentinel) DeleteDoc(docId string) int {
-	endpoints := sentinel.hub.GetServiceEndpoints(INDEX_SERVICE)
+	n, err := sentinel.replicate(docId, func(client index.IndexServiceClient) (*index.AffectedCount, error) {
+		return client.DeleteDoc(sentinel.context(), &index.DocId{DocId: docId})
+	})
+	if err != nil {
+		logger.Log.Printf("delete doc %s failed: %s", docId, err)
+	}
+	if n > 0 {
+		logger.Log.Printf("delete %d from the replicas of shard %d", n, sentinel.shards.ShardMap().Shard(docId))
+	}
+
+	return n
+}
+
+// replicate applies a write to every alive replica of the shard of docId in parallel. It fails if any replica failed,
+// a replica that is down misses the write.
+func (sentinel *Sentinel) replicate(docId string, write func(client index.IndexServiceClient) (*index.AffectedCount, error)) (int, error) {
+	endpoints := sentinel.hub.GetServiceEndpoints(sentinel.service)
 	if len(endpoints) == 0 {
-		return 0
+		return 0, fmt.Errorf("there is no alive index worker")
+	}
+	replicas, err := sentinel.replicas(docId, endpoints, true)
+	if err != nil {
+		return 0, err
 	}
 
 	var n int32
+	var lastErr error
+	lock := sync.Mutex{}
 	wg := sync.WaitGroup{}
-	wg.Add(len(endpoints))
-	for _, endpoint := range endpoints {
-		go func(endpoint string) { // parallel delete
+	wg.Add(len(replicas))
+	for _, endpoint := range replicas {
+		go func(endpoint string) { // parallel write
 			defer wg.Done()
+			var affected *index.AffectedCount
 			conn := sentinel.GetGrpcConn(endpoint)
+			err := fmt.Errorf("connect to worker %s failed", endpoint)
 			if conn != nil {
-				client := index.NewIndexServiceClient(conn)
-				affected, err := client.DeleteDoc(context.Background(), &index.DocId{DocId: docId})
-				if err != nil {
-					logger.Log.Printf("delete doc %s from worker %s failed: %s", docId, endpoint, err)
-				} else {
-					if affected.Count > 0 {
-						atomic.AddInt32(&n, affected.Count)
-						logger.Log.Printf("delete %d from worker %s", affected.Count, endpoint)
-					}
-				}
+				affected, err = write(index.NewIndexServiceClient(conn))
+				sentinel.report(sentinel.context(), endpoint, err)
 			}
+
+			lock.Lock()
+			defer lock.Unlock()
+			if err != nil {
+				logger.Log.Printf("write doc %s to worker %s failed: %s", docId, endpoint, err)
+				lastErr = err
+				return
+			}
+			n = max(n, affected.Count) // every replica applies the same write
 		}(endpoint)
 	}
 	wg.Wait()
-	
-	return int(atomic.LoadInt32(&n))
+
+	return int(n), lastErr
 }
 
-func (sentinel *Sentinel) Search(query *search_proto.TermQuery, onFlag uint64, offFlag uint64, orFlags []uint64) []*search_proto.Document {
-	endpoints := sentinel.hub.GetServiceEndpoints(INDEX_SERVICE)
+// replicas returns the alive workers hosting the shard of a document, see ShardMap. It fails if there is none.
+// The workers a rebalance copies the shard to are returned for writes only.
+func (sentinel *Sentinel) replicas(docId string, endpoints []string, writing bool) ([]string, error) {
+	shardMap := sentinel.shards.ShardMap()
+	if shardMap == nil {
+		return nil, fmt.Errorf("there is no shard map, no worker claimed shards yet")
+	}
+	shard := shardMap.Shard(docId)
+	if len(shardMap.Replicas[shard]) == 0 {
+		return nil, fmt.Errorf("shard %d of document %s has no worker", shard, docId)
+	}
+	alive := aliveReplicas(shardMap.Replicas[shard], endpoints, nil)
+	if len(alive) == 0 {
+		return nil, fmt.Errorf("no replica of shard %d is alive, its workers are %v", shard, shardMap.Replicas[shard])
+	}
+	if writing {
+		alive = append(alive, aliveReplicas(shardMap.Pending[shard], endpoints, nil)...)
+	}
+	return alive, nil
+}
+
+// aliveReplicas returns the replicas registered in endpoints, except the ones in failed
+func aliveReplicas(replicas []string, endpoints []string, failed map[string]bool) []string {
+	alive := make([]string, 0, len(replicas))
+	for _, replica := range replicas {
+		if failed[replica] {
+			continue
+		}
+		for _, endpoint := range endpoints {
+			if endpoint == replica {
+				alive = append(alive, replica)
+				break
+			}
+		}
+	}
+	return alive
+}
+
+// shardRead is a read of some shards from one of their replicas
+type shardRead struct {
+	ctx      context.Context // names the collection, and bounds the read by the deadline of the request
+	endpoint string
+	client   index.IndexServiceClient
+	filter   *index.ShardFilter
+	hosted   int // shards hosted by the replica, it answers for the shards of filter only
+}
+
+// limit scales up the number of nearest documents asked of the replica, which drops the documents of the shards it is not asked for
+func (read shardRead) limit(k int) int {
+	if read.hosted <= len(read.filter.Ids) {
+		return k
+	}
+	return (k*read.hosted + len(read.filter.Ids) - 1) / len(read.filter.Ids)
+}
+
+// scatter reads every shard from one alive replica, the shards with the same replicas are read together from one of them picked
+// at random. A read that did not answer within the p95 latency of the operation is hedged: the shards are read again from
+// another replica, and the first answer wins while the other read is canceled. read returns a function merging its results
+// that scatter calls for the winning read only, it may merge results itself as they arrive if merging them twice is harmless.
+// The shards of a replica whose read failed are read again from their other replicas, unless the deadline of ctx passed.
+// It returns the shards that no replica could be read from.
+func (sentinel *Sentinel) scatter(ctx context.Context, operation string, read func(read shardRead) (func(), error)) SearchStatus {
+	status := SearchStatus{}
+	shardMap := sentinel.shards.ShardMap()
+	if shardMap == nil {
+		logger.Log.Printf("there is no shard map, no worker claimed shards yet")
+		return status
+	}
+	endpoints := sentinel.hub.GetServiceEndpoints(sentinel.service)
+	ctx = WithCollection(ctx, sentinel.collection)
+	latency := sentinel.latency(operation)
+
+	pending := make([]int, shardMap.Shards)
+	for shard := range pending {
+		pending[shard] = shard
+	}
+	failed := make(map[string]bool)
+	for len(pending) > 0 {
+		if ctx.Err() != nil {
+			status.TimedOut = append(status.TimedOut, pending...)
+			break
+		}
+		groups := make(map[string][]int32) // alive replicas -> shards read from one of them
+		replicas := make(map[string][]string)
+		for _, shard := range pending {
+			alive := aliveReplicas(shardMap.Replicas[shard], endpoints, failed)
+			if len(alive) == 0 {
+				status.Failed = append(status.Failed, shard)
+				continue
+			}
+			key := strings.Join(alive, ",")
+			groups[key] = append(groups[key], int32(shard))
+			replicas[key] = alive
+		}
+
+		pending = pending[:0]
+		hedgeAfter := latency.p95()
+		lock := sync.Mutex{}
+		wg := sync.WaitGroup{}
+		wg.Add(len(groups))
+		for key, shards := range groups {
+			go func(replicas []string, shards []int32) {
+				defer wg.Done()
+				lost, err := sentinel.hedgedRead(ctx, shardMap, sentinel.order(replicas), shards, hedgeAfter, latency, read)
+				if err == nil {
+					return
+				}
+
+				lock.Lock()
+				defer lock.Unlock()
+				if ctx.Err() != nil {
+					logger.Log.Printf("read %d shards from workers %v timed out: %s", len(shards), lost, err)
+					for _, shard := range shards {
+						status.TimedOut = append(status.TimedOut, int(shard))
+					}
+					return
+				}
+				logger.Log.Printf("read %d shards from workers %v failed: %s, fail over to other replicas", len(shards), lost, err)
+				for _, endpoint := range lost {
+					failed[endpoint] = true
+				}
+				for _, shard := range shards {
+					pending = append(pending, int(shard))
+				}
+			}(replicas[key], shards)
+		}
+		wg.Wait()
+	}
+	sort.Ints(status.TimedOut)
+	sort.Ints(status.Failed)
+
+	return status
+}
+
+// hedgedRead reads the shards from the first of replicas, and also from the second one if the first did not answer after
+// hedgeAfter, 0 disables hedging. It merges the results of the first read that succeeded and returns the replicas that failed.
+func (sentinel *Sentinel) hedgedRead(ctx context.Context, shardMap *ShardMap, replicas []string, shards []int32, hedgeAfter time.Duration,
+	latency *latencyWindow, read func(read shardRead) (func(), error)) ([]string, error) {
+	type answer struct {
+		endpoint string
+		merge    func()
+		err      error
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // cancels the read that lost, and the rest of a stream the winner stopped reading
+	answers := make(chan answer, 2)
+	send := func(endpoint string) {
+		go func() {
+			begin := time.Now()
+			merge, err := sentinel.readShards(ctx, endpoint, shardMap, shards, read)
+			if err == nil {
+				latency.add(time.Since(begin))
+			}
+			answers <- answer{endpoint, merge, err}
+		}()
+	}
+
+	send(replicas[0])
+	inflight := 1
+	var hedge <-chan time.Time
+	if hedgeAfter > 0 && len(replicas) > 1 && !sentinel.breaker(replicas[1]).open() {
+		timer := time.NewTimer(hedgeAfter)
+		defer timer.Stop()
+		hedge = timer.C
+	}
+
+	lost := make([]string, 0, 2)
+	var err error
+	for inflight > 0 {
+		select {
+		case <-hedge:
+			logger.Log.Printf("worker %s did not answer within %s, hedge the read of %d shards to worker %s", replicas[0], hedgeAfter, len(shards), replicas[1])
+			hedge = nil
+			send(replicas[1])
+			inflight++
+		case answer := <-answers:
+			inflight--
+			if answer.err == nil {
+				answer.merge()
+				return nil, nil
+			}
+			lost = append(lost, answer.endpoint)
+			err = answer.err
+			hedge = nil // a failed read fails over to the other replicas in the next round
+		}
+	}
+
+	return lost, err
+}
+
+// readShards reads the shards from one worker and records the outcome in its circuit breaker
+func (sentinel *Sentinel) readShards(ctx context.Context, endpoint string, shardMap *ShardMap, shards []int32, read func(read shardRead) (func(), error)) (func(), error) {
+	conn := sentinel.GetGrpcConn(endpoint)
+	if conn == nil {
+		return nil, fmt.Errorf("connect to worker %s failed", endpoint)
+	}
+	merge, err := read(shardRead{
+		ctx:      ctx,
+		endpoint: endpoint,
+		client:   index.NewIndexServiceClient(conn),
+		filter:   &index.ShardFilter{Shards: int32(shardMap.Shards), Ids: shards},
+		hosted:   len(shardMap.ShardsOf(endpoint)),
+	})
+	sentinel.report(ctx, endpoint, err)
+
+	return merge, err
+}
+
+// GetDoc asks a replica of the shard of the document, see MultiGetDoc
+func (sentinel *Sentinel) GetDoc(docId string) (*search_proto.Document, error) {
+	docs, err := sentinel.MultiGetDoc([]string{docId})
+	if len(docs) > 0 {
+		return docs[0], nil
+	}
+	return nil, err
+}
+
+// MultiGetDoc groups the ids by a replica of their shard picked at random, the ids of a replica that failed are asked of
+// the other replicas of their shard. The ids of shards without an alive replica are asked of all workers.
+// A missing document is only missing if a replica of its shard answered, otherwise the error of its last failed replica,
+// or of its shard without an alive replica, is returned.
+func (sentinel *Sentinel) MultiGetDoc(docIds []string) ([]*search_proto.Document, error) {
+	endpoints := sentinel.hub.GetServiceEndpoints(sentinel.service)
 	if len(endpoints) == 0 {
-		return nil
+		return nil, fmt.Errorf("there is no alive index worker")
 	}
 
-	docs := make([]*search_proto.Document, 0, 1000)
-	resultCh := make(chan *search_proto.Document, 1000)
+	found := make(map[string]*search_proto.Document, len(docIds))
+	failed := make(map[string]bool)
+	errs := make(map[string]error)    // id -> why the document may be missing
+	unrouted := make(map[string]bool) // ids of shards without an alive replica
+	pending := docIds
+	for round := 0; len(pending) > 0; round++ {
+		requests := make(map[string][]string, len(endpoints)) // endpoint -> ids
+		for _, docId := range pending {
+			replicas, replicaErr := sentinel.replicas(docId, endpoints, false)
+			if replicaErr != nil {
+				errs[docId] = replicaErr // a worker that is not registered may hold the document
+				unrouted[docId] = true
+				if round == 0 {
+					for _, endpoint := range endpoints {
+						requests[endpoint] = append(requests[endpoint], docId)
+					}
+				}
+				continue
+			}
+			// the document keeps the error of its failed replicas once none is left
+			alive := aliveReplicas(replicas, endpoints, failed)
+			if len(alive) > 0 {
+				endpoint := sentinel.order(alive)[0]
+				requests[endpoint] = append(requests[endpoint], docId)
+			}
+		}
+
+		more, roundErrs := sentinel.multiGet(requests)
+		for docId, doc := range more {
+			found[docId] = doc
+		}
+		pending = make([]string, 0)
+		for endpoint, ids := range requests {
+			endpointErr, endpointFailed := roundErrs[endpoint]
+			if endpointFailed {
+				failed[endpoint] = true
+				pending = append(pending, ids...)
+			}
+			for _, docId := range ids {
+				if endpointFailed {
+					errs[docId] = endpointErr
+				} else if !unrouted[docId] {
+					delete(errs, docId) // a replica of its shard answered
+				}
+			}
+		}
+	}
+
+	var err error
+	docs := make([]*search_proto.Document, 0, len(found))
+	for _, docId := range docIds {
+		if doc, exists := found[docId]; exists {
+			docs = append(docs, doc)
+		} else if errs[docId] != nil {
+			err = errs[docId]
+		}
+	}
+
+	return docs, err
+}
+
+// multiGet sends each endpoint its ids in parallel and returns the documents found by id, and the errors of the endpoints
+// that failed
+func (sentinel *Sentinel) multiGet(requests map[string][]string) (map[string]*search_proto.Document, map[string]error) {
+	found := make(map[string]*search_proto.Document)
+	failed := make(map[string]error)
+	lock := sync.Mutex{}
 	wg := sync.WaitGroup{}
-	wg.Add(len(endpoints))
-	for _, endpoint := range endpoints {
-		go func(endpoint string) {
+	wg.Add(len(requests))
+	for endpoint, docIds := range requests {
+		go func(endpoint string, docIds []string) {
 			defer wg.Done()
+			var result *index.MultiGetDocResult
 			conn := sentinel.GetGrpcConn(endpoint)
+			err := fmt.Errorf("connect to worker %s failed", endpoint)
 			if conn != nil {
 				client := index.NewIndexServiceClient(conn)
-				result, err := client.Search(context.Background(), &index.SearchRequest{Query: query, OnFlag: onFlag, OffFlag: offFlag, OrFlags: orFlags})
+				result, err = client.MultiGetDoc(sentinel.context(), &index.DocIds{DocIds: docIds})
+				sentinel.report(sentinel.context(), endpoint, err)
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+			if err != nil {
+				logger.Log.Printf("get %d docs from worker %s failed: %s", len(docIds), endpoint, err)
+				failed[endpoint] = err
+				return
+			}
+			for _, doc := range result.Docs {
+				found[doc.Id] = doc
+			}
+		}(endpoint, docIds)
+	}
+	wg.Wait()
+
+	return found, failed
+}
+
+// Bulk sends every worker one stream of the operations on the shards it hosts, every operation goes to all alive replicas
+// of its shard. An operation fails if its shard has no alive replica, or if any replica failed to apply it.
+func (sentinel *Sentinel) Bulk(operations []*index.BulkOperation) []*index.BulkResult {
+	results := make([]*index.BulkResult, len(operations))
+	for i := range results {
+		results[i] = &index.BulkResult{}
+	}
+	endpoints := sentinel.hub.GetServiceEndpoints(sentinel.service)
+	if len(endpoints) == 0 {
+		for _, result := range results {
+			result.Error = "there is no alive index worker"
+		}
+		return results
+	}
+	requests := make(map[string][]int, len(endpoints)) // endpoint -> positions of its operations
+	for i, operation := range operations {
+		docId := operation.DeleteId
+		if operation.Doc != nil {
+			docId = operation.Doc.Id
+		}
+		replicas, err := sentinel.replicas(docId, endpoints, true)
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		for _, endpoint := range replicas {
+			requests[endpoint] = append(requests[endpoint], i)
+		}
+	}
+
+	lock := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	wg.Add(len(requests))
+	for endpoint, positions := range requests {
+		go func(endpoint string, positions []int) {
+			defer wg.Done()
+			workerOperations := make([]*index.BulkOperation, 0, len(positions))
+			for _, i := range positions {
+				workerOperations = append(workerOperations, operations[i])
+			}
+			workerResults, err := sentinel.bulk(sentinel.context(), endpoint, workerOperations)
+			if err != nil {
+				logger.Log.Printf("bulk to worker %s failed: %s", endpoint, err)
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+			for j, i := range positions {
 				if err != nil {
-					logger.Log.Printf("search from cluster failed: %s", err)
-				} else {
-					if len(result.Results) > 0 {
-						logger.Log.Printf("search %d doc from worker %s", len(result.Results), endpoint)
-						for _, doc := range result.Results {
-							resultCh <- doc
-						}
-					}
+					results[i].Error = err.Error()
+					continue
+				}
+				results[i].Count = max(results[i].Count, workerResults[j].Count)
+				results[i].Replaced = results[i].Replaced || workerResults[j].Replaced
+				if len(workerResults[j].Error) > 0 {
+					results[i].Error = workerResults[j].Error
 				}
 			}
-		}(endpoint)
+		}(endpoint, positions)
 	}
+	wg.Wait()
 
-	receiveFinish := make(chan struct{})
-	go func() {
+	return results
+}
+
+// bulk sends the operations to one worker, ctx names the collection
+func (sentinel *Sentinel) bulk(ctx context.Context, endpoint string, operations []*index.BulkOperation) ([]*index.BulkResult, error) {
+	conn := sentinel.GetGrpcConn(endpoint)
+	if conn == nil {
+		return nil, fmt.Errorf("connect to worker %s failed", endpoint)
+	}
+	stream, err := index.NewIndexServiceClient(conn).Bulk(ctx)
+	if err != nil {
+		sentinel.report(ctx, endpoint, err)
+		return nil, err
+	}
+
+	for _, operation := range operations {
+		if err := stream.Send(operation); err != nil {
+			sentinel.report(ctx, endpoint, err)
+			return nil, err
+		}
+	}
+	response, err := stream.CloseAndRecv()
+	sentinel.report(ctx, endpoint, err)
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Results) != len(operations) {
+		return nil, fmt.Errorf("worker %s returned %d results for %d operations", endpoint, len(response.Results), len(operations))
+	}
+
+	return response.Results, nil
+}
+
+// Search streams every shard from one of its replicas, see scatter
+func (sentinel *Sentinel) Search(query *search_proto.TermQuery, onFlag uint64, offFlag uint64, orFlags []uint64) []*search_proto.Document {
+	docs, _ := sentinel.SearchContext(context.Background(), query, onFlag, offFlag, orFlags)
+	return docs
+}
+
+// SearchContext searches like Search, the shards whose replicas did not answer before the deadline of ctx are left out
+func (sentinel *Sentinel) SearchContext(ctx context.Context, query *search_proto.TermQuery, onFlag uint64, offFlag uint64, orFlags []uint64) ([]*search_proto.Document, SearchStatus) {
+	docs := make([]*search_proto.Document, 0, 1000)
+	seen := make(map[string]struct{}, 1000)
+	lock := sync.Mutex{}
+	status := sentinel.scatter(ctx, "search", func(read shardRead) (func(), error) {
+		stream, err := read.client.SearchStream(read.ctx, &index.SearchRequest{Query: query, OnFlag: onFlag, OffFlag: offFlag, OrFlags: orFlags, Shards: read.filter})
+		if err != nil {
+			return nil, err
+		}
+		// the chunks are merged as they arrive, a document also read from another replica by a hedged read or after a failure
+		// is merged once
+		received := 0
 		for {
-			doc, ok := <-resultCh // synchronously receive documents from all workers
-			if !ok {
-				break // 2. if the channel is closed, exit the loop
+			chunk, err := stream.Recv()
+			if err == io.EOF {
+				break
 			}
-			docs = append(docs, doc)
+			if err != nil {
+				return nil, err
+			}
+			received += len(chunk.Results)
+			lock.Lock()
+			for _, doc := range chunk.Results {
+				if _, exists := seen[doc.Id]; !exists {
+					seen[doc.Id] = struct{}{}
+					docs = append(docs, doc)
+				}
+			}
+			lock.Unlock()
 		}
-		receiveFinish <- struct{}{} // 3. signal that all documents have been received
-	}()
-	wg.Wait()
-	close(resultCh) // 1. close the channel to signal that no more documents will be sent
-	<-receiveFinish // 4. wait for the receive goroutine to finish
+		return func() {
+			if received > 0 {
+				logger.Log.Printf("search %d doc from worker %s", received, read.endpoint)
+			}
+		}, nil
+	})
+	if status.Partial() {
+		logger.Log.Printf("search misses shards, %v timed out and %v failed", status.TimedOut, status.Failed)
+	}
 
-	return docs
+	return docs, status
+}
+
+// SearchVector streams the k nearest neighbors of every shard from one of its replicas and keeps the global top k. A stream
+// is cut once its next neighbors can not beat the top k of the shards already read.
+func (sentinel *Sentinel) SearchVector(vector []float32, k int) ([]*search_proto.Document, []float32) {
+	docs, scores, _ := sentinel.SearchVectorContext(context.Background(), vector, k)
+	return docs, scores
 }
 
+// SearchVectorContext searches like SearchVector, the shards whose replicas did not answer before the deadline of ctx are left out
+func (sentinel *Sentinel) SearchVectorContext(ctx context.Context, vector []float32, k int) ([]*search_proto.Document, []float32, SearchStatus) {
+	if k <= 0 {
+		return nil, nil, SearchStatus{}
+	}
+
+	top := &topCandidates{k: k}
+	status := sentinel.scatter(ctx, "vector", func(read shardRead) (func(), error) {
+		stream, err := read.client.SearchVectorStream(read.ctx, &index.VectorSearchRequest{Vector: vector, K: int32(read.limit(k)), Shards: read.filter})
+		if err != nil {
+			return nil, err
+		}
+		received := make([]scoredDoc, 0)
+		for {
+			chunk, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			if len(chunk.Results) != len(chunk.Scores) {
+				continue
+			}
+			for i, doc := range chunk.Results {
+				received = append(received, scoredDoc{doc, chunk.Scores[i]})
+			}
+			// the stream is sorted by descending score, scatter cancels the rest of it
+			if len(received) > 0 && !top.admits(received[len(received)-1].score) {
+				logger.Log.Printf("stop reading worker %s after %d neighbors, the rest can not make the top %d", read.endpoint, len(received), k)
+				break
+			}
+		}
+		return func() {
+			top.add(received)
+		}, nil
+	})
+	if status.Partial() {
+		logger.Log.Printf("vector search misses shards, %v timed out and %v failed", status.TimedOut, status.Failed)
+	}
+
+	docs, scores := topScored(top.candidates, k)
+	return docs, scores, status
+}
+
+// MoreLikeThis gets the source document from a replica of its shard, then sends it to a replica of every shard so that
+// every partition contributes similar documents.
+func (sentinel *Sentinel) MoreLikeThis(docId string, limit int) ([]*search_proto.Document, []float32) {
+	if limit <= 0 {
+		return nil, nil
+	}
+	like, _ := sentinel.GetDoc(docId)
+	if like == nil {
+		logger.Log.Printf("document %s not found on any worker", docId)
+		return nil, nil
+	}
+
+	return sentinel.MoreLikeThisDoc(like, limit)
+}
+
+// MoreLikeThisDoc sends like to a replica of every shard, see MoreLikeThis
+func (sentinel *Sentinel) MoreLikeThisDoc(like *search_proto.Document, limit int) ([]*search_proto.Document, []float32) {
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	candidates := make([]scoredDoc, 0, limit)
+	lock := sync.Mutex{}
+	status := sentinel.scatter(context.Background(), "more_like_this", func(read shardRead) (func(), error) {
+		result, err := read.client.MoreLikeThis(read.ctx, &index.MoreLikeThisRequest{DocId: like.Id, Like: like, Limit: int32(read.limit(limit)), Shards: read.filter})
+		if err != nil {
+			return nil, err
+		}
+		return func() {
+			if len(result.Results) == len(result.Scores) {
+				lock.Lock()
+				for i, doc := range result.Results {
+					candidates = append(candidates, scoredDoc{doc, result.Scores[i]})
+				}
+				lock.Unlock()
+			}
+		}, nil
+	})
+	if status.Partial() {
+		logger.Log.Printf("more like this misses shards %v", status.Failed)
+	}
+
+	return topScored(candidates, limit)
+}
+
+type scoredDoc struct {
+	doc   *search_proto.Document
+	score float32
+}
+
+// topCandidates keeps the k best documents of the reads merged so far
+type topCandidates struct {
+	k          int
+	lock       sync.Mutex
+	candidates []scoredDoc // by descending score, at most k
+}
+
+func (top *topCandidates) add(more []scoredDoc) {
+	top.lock.Lock()
+	defer top.lock.Unlock()
+	top.candidates = append(top.candidates, more...)
+	sort.SliceStable(top.candidates, func(i, j int) bool {
+		return top.candidates[i].score > top.candidates[j].score
+	})
+	if len(top.candidates) > top.k {
+		top.candidates = top.candidates[:top.k]
+	}
+}
+
+// admits tells whether a document scored score would make the top k
+func (top *topCandidates) admits(score float32) bool {
+	top.lock.Lock()
+	defer top.lock.Unlock()
+	return len(top.candidates) < top.k || score > top.candidates[top.k-1].score
+}
+
+// topScored returns the k candidates with the highest scores, in descending order
+func topScored(candidates []scoredDoc, k int) ([]*search_proto.Document, []float32) {
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+	if len(candidates) > k {
+		candidates = candidates[:k]
+	}
+
+	docs := make([]*search_proto.Document, 0, len(candidates))
+	scores := make([]float32, 0, len(candidates))
+	for _, candidate := range candidates {
+		docs = append(docs, candidate.doc)
+		scores = append(scores, candidate.score)
+	}
+
+	return docs, scores
+}
+
+// Count counts the documents of every shard on one of its replicas
 func (sentinel *Sentinel) Count() int {
 	var n int32
-	endpoints := sentinel.hub.GetServiceEndpoints(INDEX_SERVICE)
+	status := sentinel.scatter(context.Background(), "count", func(read shardRead) (func(), error) {
+		affected, err := read.client.Count(read.ctx, &index.CountRequest{Shards: read.filter})
+		if err != nil {
+			return nil, err
+		}
+		return func() {
+			if affected.Count > 0 {
+				atomic.AddInt32(&n, affected.Count)
+				logger.Log.Printf("worker %s have %d documents in %d shards", read.endpoint, affected.Count, len(read.filter.Ids))
+			}
+		}, nil
+	})
+	if status.Partial() {
+		logger.Log.Printf("count misses shards %v", status.Failed)
+	}
+
+	return int(atomic.LoadInt32(&n))
+}
+
+// WorkerCounts returns the number of documents of each alive worker, replicas included. The workers that could not be asked are left out.
+func (sentinel *Sentinel) WorkerCounts() map[string]int {
+	counts := make(map[string]int)
+	endpoints := sentinel.hub.GetServiceEndpoints(sentinel.service)
 	if len(endpoints) == 0 {
-		return 0
+		return counts
 	}
 
+	lock := sync.Mutex{}
 	wg := sync.WaitGroup{}
 	wg.Add(len(endpoints))
 	for _, endpoint := range endpoints {
@@ -181,30 +815,38 @@ func (sentinel *Sentinel) Count() int {
 			conn := sentinel.GetGrpcConn(endpoint)
 			if conn != nil {
 				client := index.NewIndexServiceClient(conn)
-				affected, err := client.Count(context.Background(), new(index.CountRequest))
+				affected, err := client.Count(sentinel.context(), new(index.CountRequest))
+				sentinel.report(sentinel.context(), endpoint, err)
 				if err != nil {
 					logger.Log.Printf("get doc count from worker %s failed: %s", endpoint, err)
 				} else {
-					if affected.Count > 0 {
-						atomic.AddInt32(&n, affected.Count)
-						logger.Log.Printf("worker %s have %d documents", endpoint, affected.Count)
-					}
+					lock.Lock()
+					counts[endpoint] = int(affected.Count)
+					lock.Unlock()
 				}
 			}
 		}(endpoint)
 	}
 	wg.Wait()
 
-	return int(n)
+	return counts
 }
 
 func (sentinel *Sentinel) Close() (err error) {
+	err = sentinel.closeConns()
+	sentinel.hub.Close()
+	
+	return
+}
+
+// closeConns closes the connections to workers but keeps the service hub, which is shared by the sentinels of all collections
+func (sentinel *Sentinel) closeConns() (err error) {
 	sentinel.connPool.Range(func(key, value any) bool {
 		conn := value.(*grpc.ClientConn)
 		err = conn.Close()
+		sentinel.connPool.Delete(key)
 		return true
 	})
-	sentinel.hub.Close()
-	
+
 	return
 }
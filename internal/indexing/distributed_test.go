@@ -0,0 +1,99 @@
+package indexing
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	index_proto "github.com/m1i3k0e7/distributed-search-engine/api/proto/index"
+	search_proto "github.com/m1i3k0e7/distributed-search-engine/api/proto/search"
+	service_hub "github.com/m1i3k0e7/distributed-search-engine/internal/service_hub"
+	"google.golang.org/grpc"
+)
+
+var errWorker = errors.New("worker failed")
+
+// fakeHub reports a fixed set of alive workers
+type fakeHub struct {
+	service_hub.IServiceHub
+	endpoints []string
+}
+
+func (hub *fakeHub) GetServiceEndpoints(service string) []string {
+	return hub.endpoints
+}
+
+// fakeWorker answers MultiGetDoc from docs, or fails every request if failing is set
+type fakeWorker struct {
+	index_proto.UnimplementedIndexServiceServer
+	docs    map[string]*search_proto.Document
+	failing bool
+}
+
+func (worker *fakeWorker) MultiGetDoc(ctx context.Context, request *index_proto.DocIds) (*index_proto.MultiGetDocResult, error) {
+	if worker.failing {
+		return nil, errWorker
+	}
+	result := &index_proto.MultiGetDocResult{}
+	for _, docId := range request.DocIds {
+		if doc, exists := worker.docs[docId]; exists {
+			result.Docs = append(result.Docs, doc)
+		}
+	}
+	return result, nil
+}
+
+// startWorker serves worker on a free local port and returns its endpoint
+func startWorker(t *testing.T, worker *fakeWorker) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer()
+	index_proto.RegisterIndexServiceServer(server, worker)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	return listener.Addr().String()
+}
+
+func TestMultiGetDocFailover(t *testing.T) {
+	docs := map[string]*search_proto.Document{"a": {Id: "a"}, "b": {Id: "b"}}
+	healthy := startWorker(t, &fakeWorker{docs: docs})
+	failing := startWorker(t, &fakeWorker{failing: true})
+	newSentinel := func(endpoints []string, replicas ...string) *Sentinel {
+		shardMap := NewShardMap(4, len(replicas))
+		for shard := range shardMap.Replicas {
+			shardMap.Replicas[shard] = replicas
+		}
+		sentinel := &Sentinel{hub: &fakeHub{endpoints: endpoints}, shards: &ShardRouter{}}
+		sentinel.shards.offer(shardMap)
+		return sentinel
+	}
+
+	// a failed replica fails over to the other one
+	sentinel := newSentinel([]string{failing, healthy}, failing, healthy)
+	for i := 0; i < 5; i++ { // the replica asked first is picked at random
+		if found, err := sentinel.MultiGetDoc([]string{"a", "b"}); len(found) != 2 || err != nil {
+			t.Fatalf("expect both documents from the healthy replica, got %d and %v", len(found), err)
+		}
+	}
+	if doc, err := sentinel.GetDoc("c"); doc != nil || err != nil {
+		t.Errorf("expect a missing document without error, got %v and %v", doc, err)
+	}
+
+	// once every replica failed, the documents are missing with the error of the replica, not just missing
+	sentinel = newSentinel([]string{failing}, failing)
+	if doc, err := sentinel.GetDoc("a"); doc != nil || err == nil {
+		t.Errorf("expect the error of the failed replica, got %v and %v", doc, err)
+	}
+
+	// a shard without an alive replica can not tell whether the document exists
+	sentinel = newSentinel([]string{healthy}, "127.0.0.1:1")
+	if found, err := sentinel.MultiGetDoc([]string{"a", "c"}); len(found) != 1 || err == nil {
+		t.Errorf("expect the document found by the other workers and an error for the missing one, got %d and %v", len(found), err)
+	}
+	if found, err := sentinel.MultiGetDoc([]string{"a"}); len(found) != 1 || err != nil {
+		t.Errorf("expect no error once every document is found, got %d and %v", len(found), err)
+	}
+}
@@ -0,0 +1,55 @@
+package indexing
+
+import (
+	"encoding/csv"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/m1i3k0e7/distributed-search-engine/pkg/logger"
+)
+
+// LoadEmbeddings reads precomputed embeddings from a csv file, each row is the product name followed by the vector components.
+// The result is keyed by the lower-cased product name.
+func LoadEmbeddings(embeddingFile string) (map[string][]float32, error) {
+	file, err := os.Open(embeddingFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	embeddings := make(map[string][]float32, 10000)
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1 // vectors are allowed to have different dimensions, the vector index will reject the odd ones
+	for {
+		record, err := reader.Read()
+		if err != nil {
+			if err != io.EOF {
+				return embeddings, err
+			}
+			break
+		}
+
+		if len(record) < 2 {
+			continue
+		}
+
+		vector := make([]float32, 0, len(record)-1)
+		for _, field := range record[1:] {
+			v, err := strconv.ParseFloat(strings.TrimSpace(field), 32)
+			if err != nil {
+				vector = nil
+				break
+			}
+			vector = append(vector, float32(v))
+		}
+		if len(vector) > 0 {
+			embeddings[strings.ToLower(strings.TrimSpace(record[0]))] = vector
+		}
+	}
+
+	logger.Log.Printf("load %d embeddings from %s", len(embeddings), embeddingFile)
+
+	return embeddings, nil
+}
@@ -0,0 +1,400 @@
+package indexing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/category"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/schema"
+	"github.com/m1i3k0e7/distributed-search-engine/pkg/logger"
+	etcdv3 "go.etcd.io/etcd/client/v3"
+)
+
+const (
+	COLLECTION_MISS_TTL = 5 * time.Second // how long a collection missing in etcd is not read again
+)
+
+// EtcdCollections keeps the schemas of the collections of a cluster in etcd, workers watch them to open or drop their part
+// of each collection. It is used by the distributed web server, every collection is served by its own Sentinel.
+// Aliases are cached and kept up to date by watching etcd, so repointing an alias on one web server switches all of them.
+// The schemas are watched as well, so dropping a collection on one web server closes it on all of them.
+type EtcdCollections struct {
+	etcdServers []string
+	client      *etcdv3.Client
+	collections map[string]*Collection // opened collections
+	aliases     map[string]string
+	misses      map[string]time.Time // collections missing in etcd -> when they are read again
+	dropped     map[string]int64     // collection -> etcd revision its schema was deleted at
+	lock        sync.Mutex
+	cancel      context.CancelFunc
+}
+
+func NewEtcdCollections(etcdServers []string, defaultCollection *Collection) (*EtcdCollections, error) {
+	client, err := etcdv3.New(
+		etcdv3.Config{
+			Endpoints:   etcdServers,
+			DialTimeout: 3 * time.Second,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	collections := &EtcdCollections{
+		etcdServers: etcdServers,
+		client:      client,
+		collections: map[string]*Collection{DEFAULT_COLLECTION: defaultCollection},
+		aliases:     make(map[string]string),
+		misses:      make(map[string]time.Time),
+		dropped:     make(map[string]int64),
+	}
+	resp, err := client.Get(context.Background(), ALIAS_ROOT_PATH+"/", etcdv3.WithPrefix())
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	for _, kv := range resp.Kvs {
+		collections.aliases[strings.TrimPrefix(string(kv.Key), ALIAS_ROOT_PATH+"/")] = string(kv.Value)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	collections.cancel = cancel
+	go collections.watchAliases(ctx, resp.Header.Revision+1)
+	go collections.watchSchemas(ctx, resp.Header.Revision+1)
+
+	return collections, nil
+}
+
+func (collections *EtcdCollections) watchAliases(ctx context.Context, revision int64) {
+	for watchResp := range collections.client.Watch(ctx, ALIAS_ROOT_PATH+"/", etcdv3.WithPrefix(), etcdv3.WithRev(revision)) {
+		collections.lock.Lock()
+		for _, event := range watchResp.Events {
+			alias := strings.TrimPrefix(string(event.Kv.Key), ALIAS_ROOT_PATH+"/")
+			if event.Type == etcdv3.EventTypePut {
+				collections.aliases[alias] = string(event.Kv.Value)
+			} else {
+				delete(collections.aliases, alias)
+			}
+		}
+		collections.lock.Unlock()
+	}
+}
+
+func (collections *EtcdCollections) watchSchemas(ctx context.Context, revision int64) {
+	for watchResp := range collections.client.Watch(ctx, COLLECTION_ROOT_PATH+"/", etcdv3.WithPrefix(), etcdv3.WithRev(revision)) {
+		collections.lock.Lock()
+		collections.schemaEvents(watchResp.Events)
+		collections.lock.Unlock()
+	}
+}
+
+// schemaEvents closes the collections whose schema was deleted and forgets the misses of created ones, the lock must be held
+func (collections *EtcdCollections) schemaEvents(events []*etcdv3.Event) {
+	for _, event := range events {
+		key := string(event.Kv.Key)
+		if !strings.HasSuffix(key, "/schema") {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(key, COLLECTION_ROOT_PATH+"/"), "/schema")
+		if event.Type == etcdv3.EventTypePut {
+			delete(collections.misses, name)
+		} else {
+			collections.evict(name, event.Kv.ModRevision)
+		}
+	}
+}
+
+// evict closes a collection whose schema was deleted at revision, a Get that read the schema before is not cached.
+// The lock must be held.
+func (collections *EtcdCollections) evict(name string, revision int64) {
+	if revision > collections.dropped[name] {
+		collections.dropped[name] = revision
+	}
+	if collection, exists := collections.collections[name]; exists {
+		delete(collections.collections, name)
+		closeCollection(collection)
+		logger.Log.Printf("close dropped collection %s", name)
+	}
+}
+
+// closeCollection closes a collection opened by Get, the service hub is shared with the default collection
+func closeCollection(collection *Collection) {
+	collection.Indexer.(*Sentinel).closeConns()
+	collection.Categories.Close()
+}
+
+func (collections *EtcdCollections) Create(name string, docSchema *schema.Schema) error {
+	if err := CheckCollectionName(name); err != nil {
+		return err
+	}
+	if name == DEFAULT_COLLECTION {
+		return fmt.Errorf("collection %s already exists", name)
+	}
+	bs, err := json.Marshal(docSchema)
+	if err != nil {
+		return err
+	}
+
+	// create only if no other node has created it and it is not an alias
+	txn, err := collections.client.Txn(context.Background()).
+		If(etcdv3.Compare(etcdv3.CreateRevision(collectionKey(name)), "=", 0),
+			etcdv3.Compare(etcdv3.CreateRevision(aliasKey(name)), "=", 0)).
+		Then(etcdv3.OpPut(collectionKey(name), string(bs))).
+		Commit()
+	if err != nil {
+		return err
+	}
+	if !txn.Succeeded {
+		return fmt.Errorf("collection or alias %s already exists", name)
+	}
+	// the watch forgets the miss as well, forget it now so the caller sees its own collection
+	collections.lock.Lock()
+	delete(collections.misses, name)
+	collections.lock.Unlock()
+	logger.Log.Printf("create collection %s", name)
+
+	return nil
+}
+
+// Drop deletes the schema and the categories of the collection, workers drop their data when they see the schema deleted
+func (collections *EtcdCollections) Drop(name string) error {
+	if name == DEFAULT_COLLECTION {
+		return fmt.Errorf("collection %s can not be dropped", DEFAULT_COLLECTION)
+	}
+	// delete only if no alias changed since they were checked, an alias pointed to the collection meanwhile would dangle
+	var revision int64
+	for {
+		resp, err := collections.client.Get(context.Background(), ALIAS_ROOT_PATH+"/", etcdv3.WithPrefix())
+		if err != nil {
+			return err
+		}
+		for _, kv := range resp.Kvs {
+			if string(kv.Value) == name {
+				return fmt.Errorf("%w: %s points to %s", ErrCollectionAliased, strings.TrimPrefix(string(kv.Key), ALIAS_ROOT_PATH+"/"), name)
+			}
+		}
+
+		txn, err := collections.client.Txn(context.Background()).
+			If(etcdv3.Compare(etcdv3.ModRevision(ALIAS_ROOT_PATH+"/"), "<", resp.Header.Revision+1).WithPrefix(),
+				etcdv3.Compare(etcdv3.CreateRevision(collectionKey(name)), ">", 0)).
+			Then(etcdv3.OpDelete(COLLECTION_ROOT_PATH+"/"+name+"/", etcdv3.WithPrefix())).
+			Else(etcdv3.OpGet(collectionKey(name), etcdv3.WithKeysOnly())).
+			Commit()
+		if err != nil {
+			return err
+		}
+		if txn.Succeeded {
+			revision = txn.Header.Revision
+			break
+		}
+		if len(txn.Responses[0].GetResponseRange().Kvs) == 0 {
+			return fmt.Errorf("%w: %s", ErrCollectionNotFound, name)
+		}
+	}
+
+	collections.lock.Lock()
+	defer collections.lock.Unlock()
+	collections.evict(name, revision) // the other web servers evict it when they see the schema deleted
+	logger.Log.Printf("drop collection %s", name)
+
+	return nil
+}
+
+// Get opens a sentinel for the collection on first use, the collection may have been created by another web server.
+// A collection missing in etcd is not read again for COLLECTION_MISS_TTL, unless the watch sees it created.
+func (collections *EtcdCollections) Get(name string) (*Collection, bool) {
+	collections.lock.Lock()
+	if target, exists := collections.aliases[name]; exists {
+		name = target
+	}
+	collection, exists := collections.collections[name]
+	retry := collections.misses[name]
+	collections.lock.Unlock()
+	if exists {
+		return collection, true
+	}
+	if time.Now().Before(retry) {
+		return nil, false
+	}
+
+	// etcd is read without the lock, a slow etcd must not block the requests of opened collections
+	resp, err := collections.client.Get(context.Background(), collectionKey(name))
+	if err != nil {
+		logger.Log.Printf("read schema of collection %s failed: %s", name, err)
+		return nil, false
+	}
+	if len(resp.Kvs) == 0 {
+		collections.lock.Lock()
+		collections.misses[name] = time.Now().Add(COLLECTION_MISS_TTL)
+		collections.lock.Unlock()
+		return nil, false
+	}
+	docSchema, err := schema.ParseSchema(resp.Kvs[0].Value)
+	if err != nil {
+		logger.Log.Printf("parse schema of collection %s failed: %s", name, err)
+		return nil, false
+	}
+	categories, err := category.NewEtcdRegistry(collections.etcdServers, collectionCategoryPath(name))
+	if err != nil {
+		logger.Log.Printf("open categories of collection %s failed: %s", name, err)
+		return nil, false
+	}
+
+	collection = &Collection{
+		Name:       name,
+		Schema:     docSchema,
+		Ranking:    docSchema.RankingConfig(),
+		Indexer:    NewSentinel(collections.etcdServers, name),
+		Categories: categories,
+	}
+
+	collections.lock.Lock()
+	defer collections.lock.Unlock()
+	if opened, exists := collections.collections[name]; exists { // opened by another request meanwhile
+		closeCollection(collection)
+		return opened, true
+	}
+	if collections.dropped[name] > resp.Header.Revision { // dropped after the schema was read
+		closeCollection(collection)
+		return nil, false
+	}
+	collections.collections[name] = collection
+
+	return collection, true
+}
+
+// SetAlias points alias to collection in one etcd transaction, which fails if the alias was repointed meanwhile
+func (collections *EtcdCollections) SetAlias(alias string, collection string, expected string) error {
+	if err := CheckCollectionName(alias); err != nil {
+		return err
+	}
+	if alias == DEFAULT_COLLECTION {
+		return fmt.Errorf("%s is a collection", alias)
+	}
+
+	conditions := []etcdv3.Cmp{
+		etcdv3.Compare(etcdv3.CreateRevision(collectionKey(alias)), "=", 0),
+		etcdv3.Compare(etcdv3.CreateRevision(collectionBuildingKey(collection)), "=", 0),
+	}
+	if collection != DEFAULT_COLLECTION {
+		conditions = append(conditions, etcdv3.Compare(etcdv3.CreateRevision(collectionKey(collection)), ">", 0))
+	}
+	if len(expected) > 0 {
+		conditions = append(conditions, etcdv3.Compare(etcdv3.Value(aliasKey(alias)), "=", expected))
+	}
+	txn, err := collections.client.Txn(context.Background()).
+		If(conditions...).
+		Then(etcdv3.OpPut(aliasKey(alias), collection)).
+		Commit()
+	if err != nil {
+		return err
+	}
+	if !txn.Succeeded {
+		return fmt.Errorf("alias %s can not point to %s, either %s is a collection, %s does not exist or is being built, or the alias does not point to %q", alias, collection, alias, collection, expected)
+	}
+
+	// the watch updates the cache as well, update it now so the caller sees its own change
+	collections.lock.Lock()
+	collections.aliases[alias] = collection
+	collections.lock.Unlock()
+	logger.Log.Printf("point alias %s to collection %s", alias, collection)
+
+	return nil
+}
+
+// SetBuilding marks the collection in etcd, so no web server points an alias to it before the build completes
+func (collections *EtcdCollections) SetBuilding(name string, building bool) error {
+	if !building {
+		_, err := collections.client.Delete(context.Background(), collectionBuildingKey(name))
+		return err
+	}
+	conditions := []etcdv3.Cmp{}
+	if name != DEFAULT_COLLECTION {
+		conditions = append(conditions, etcdv3.Compare(etcdv3.CreateRevision(collectionKey(name)), ">", 0))
+	}
+	txn, err := collections.client.Txn(context.Background()).
+		If(conditions...).
+		Then(etcdv3.OpPut(collectionBuildingKey(name), time.Now().Format(time.RFC3339))).
+		Commit()
+	if err != nil {
+		return err
+	}
+	if !txn.Succeeded {
+		return fmt.Errorf("%w: %s", ErrCollectionNotFound, name)
+	}
+	return nil
+}
+
+func (collections *EtcdCollections) RemoveAlias(alias string) error {
+	resp, err := collections.client.Delete(context.Background(), aliasKey(alias))
+	if err != nil {
+		return err
+	}
+	if resp.Deleted == 0 {
+		return fmt.Errorf("alias %s not found", alias)
+	}
+
+	collections.lock.Lock()
+	delete(collections.aliases, alias)
+	collections.lock.Unlock()
+
+	return nil
+}
+
+func (collections *EtcdCollections) Aliases() (map[string]string, error) {
+	resp, err := collections.client.Get(context.Background(), ALIAS_ROOT_PATH+"/", etcdv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+
+	aliases := make(map[string]string, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		aliases[strings.TrimPrefix(string(kv.Key), ALIAS_ROOT_PATH+"/")] = string(kv.Value)
+	}
+	return aliases, nil
+}
+
+func (collections *EtcdCollections) List() ([]string, error) {
+	return listCollections(collections.client)
+}
+
+// listCollections reads the names of all collections in etcd, DEFAULT_COLLECTION included
+func listCollections(client *etcdv3.Client) ([]string, error) {
+	resp, err := client.Get(context.Background(), COLLECTION_ROOT_PATH+"/", etcdv3.WithPrefix(), etcdv3.WithKeysOnly())
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{DEFAULT_COLLECTION}
+	for _, kv := range resp.Kvs {
+		key := string(kv.Key)
+		if strings.HasSuffix(key, "/schema") {
+			names = append(names, strings.TrimSuffix(strings.TrimPrefix(key, COLLECTION_ROOT_PATH+"/"), "/schema"))
+		}
+	}
+
+	return names, nil
+}
+
+func (collections *EtcdCollections) Close() error {
+	collections.cancel()
+	collections.lock.Lock()
+	defer collections.lock.Unlock()
+	for name, collection := range collections.collections {
+		if name == DEFAULT_COLLECTION {
+			continue // closed last, its sentinel also closes the shared service hub
+		}
+		closeCollection(collection)
+	}
+	if collection, exists := collections.collections[DEFAULT_COLLECTION]; exists {
+		collection.Indexer.Close()
+		if collection.Categories != nil {
+			collection.Categories.Close()
+		}
+	}
+
+	return collections.client.Close()
+}
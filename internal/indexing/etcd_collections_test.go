@@ -0,0 +1,63 @@
+package indexing
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/category"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/kvdb"
+	"go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	etcdv3 "go.etcd.io/etcd/client/v3"
+)
+
+// countingKV is an empty etcd that counts its reads
+type countingKV struct {
+	etcdv3.KV
+	gets int
+}
+
+func (kv *countingKV) Get(ctx context.Context, key string, opts ...etcdv3.OpOption) (*etcdv3.GetResponse, error) {
+	kv.gets++
+	return &etcdv3.GetResponse{Header: &etcdserverpb.ResponseHeader{Revision: 1}}, nil
+}
+
+func TestEtcdCollectionsCache(t *testing.T) {
+	kv := &countingKV{}
+	collections := &EtcdCollections{
+		client:      &etcdv3.Client{KV: kv},
+		collections: make(map[string]*Collection),
+		misses:      make(map[string]time.Time),
+		dropped:     make(map[string]int64),
+	}
+
+	// a missing collection is read from etcd once, until the watch sees it created
+	for i := 0; i < 3; i++ {
+		if _, exists := collections.Get("books"); exists {
+			t.Fatalf("expect books to be missing")
+		}
+	}
+	if kv.gets != 1 {
+		t.Errorf("expect one read of the missing collection, got %d", kv.gets)
+	}
+	collections.schemaEvents([]*etcdv3.Event{{Type: etcdv3.EventTypePut, Kv: &mvccpb.KeyValue{Key: []byte(collectionKey("books")), ModRevision: 3}}})
+	if _, missed := collections.misses["books"]; missed {
+		t.Errorf("expect the created collection to be read again")
+	}
+
+	// a collection dropped by another web server is closed
+	categories, err := category.NewLocalRegistry(kvdb.BOLT, filepath.Join(t.TempDir(), "category"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	collections.collections["books"] = &Collection{Name: "books", Indexer: &Sentinel{}, Categories: categories}
+	collections.schemaEvents([]*etcdv3.Event{
+		{Type: etcdv3.EventTypeDelete, Kv: &mvccpb.KeyValue{Key: []byte(collectionCategoryPath("books") + "/next_id"), ModRevision: 5}},
+		{Type: etcdv3.EventTypeDelete, Kv: &mvccpb.KeyValue{Key: []byte(collectionKey("books")), ModRevision: 5}},
+	})
+	if _, exists := collections.collections["books"]; exists || collections.dropped["books"] != 5 {
+		t.Errorf("expect books to be closed at revision 5, got revision %d", collections.dropped["books"])
+	}
+}
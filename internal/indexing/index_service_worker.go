@@ -3,7 +3,13 @@ package indexing
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	search_proto "github.com/m1i3k0e7/distributed-search-engine/api/proto/search"
@@ -11,51 +17,91 @@ import (
 	"github.com/m1i3k0e7/distributed-search-engine/pkg/net"
 	service_hub "github.com/m1i3k0e7/distributed-search-engine/internal/service_hub"
 	index "github.com/m1i3k0e7/distributed-search-engine/api/proto/index"
+	"github.com/m1i3k0e7/distributed-search-engine/pkg/logger"
+	etcdv3 "go.etcd.io/etcd/client/v3"
+	"google.golang.org/protobuf/proto"
 )
 
 const (
-	INDEX_SERVICE = "index_service"
+	INDEX_SERVICE      = "index_service"
+	SEARCH_CHUNK_BYTES = 1 << 20 // size of a chunk of streamed search results, well below the 4MB message limit of gRPC
+	SEARCH_STREAM_READ = 1000    // documents of a streamed keyword search read from the forward index at once
 )
 
 // IndexWorker, a gRPC service worker for indexing.
 type IndexServiceWorker struct {
 	index.UnimplementedIndexServiceServer
-	Indexer *Indexer // kvdb + inverted index
+	Indexer *Indexer // kvdb + inverted index of DEFAULT_COLLECTION
 	hub      *service_hub.ServiceHub
 	selfAddr string
+
+	dbtype      int
+	dataDir     string
+	etcdClient  *etcdv3.Client
+	collections map[string]*Indexer         // other collections, created through the web server and watched in etcd
+	leases      map[string]etcdv3.LeaseID   // service name -> lease of the registration
+	lock        sync.RWMutex
 }
 
 func (service *IndexServiceWorker) Init(DocNumEstimate int, dbtype int, DataDir string) error {
+	service.dbtype = dbtype
+	service.dataDir = DataDir
+	service.collections = make(map[string]*Indexer)
+	service.leases = make(map[string]etcdv3.LeaseID)
 	service.Indexer = new(Indexer)
 	return service.Indexer.Init(DocNumEstimate, dbtype, DataDir)
 }
 
+// WorkerAddress returns the endpoint a worker listening on servicePort registers under, it names the worker in the shard map
+func WorkerAddress(servicePort int) (string, error) {
+	if servicePort <= 1024 {
+		return "", fmt.Errorf("invalid listen port %d, should more than 1024", servicePort)
+	}
+
+	selfLocalIp, err := net.GetLocalIP()
+	if err != nil {
+		return "", err
+	}
+	// selfLocalIp = "127.0.0.1" // when testing locally, use loopback address
+	return selfLocalIp + ":" + strconv.Itoa(servicePort), nil
+}
+
 func (service *IndexServiceWorker) Regist(etcdServers []string, servicePort int) error {
 	// Register the service in etcd if etcdServers is not empty
 	if len(etcdServers) > 0 {
-		if servicePort <= 1024 {
-			return fmt.Errorf("invalid listen port %d, should more than 1024", servicePort)
-		}
-
-		selfLocalIp, err := net.GetLocalIP()
+		selfAddr, err := WorkerAddress(servicePort)
 		if err != nil {
-			panic(err)
+			return err
 		}
-		
-		// selfLocalIp = "127.0.0.1" // when testing locally, use loopback address
-		service.selfAddr = selfLocalIp + ":" + strconv.Itoa(servicePort)
+		service.selfAddr = selfAddr
 		var heartBeat int64 = 3                      // heartbeat interval in seconds
 		hub := service_hub.GetServiceHub(etcdServers, heartBeat)
-		leaseId, err := hub.Regist(INDEX_SERVICE, service.selfAddr, 0)
-		if err != nil {
+		service.hub = hub
+		if err := service.registService(CollectionService(DEFAULT_COLLECTION)); err != nil {
 			panic(err)
 		}
+		if err := service.watchCollections(etcdServers); err != nil {
+			return err
+		}
 
-		service.hub = hub
-		// heartbeat goroutine
+		// heartbeat goroutine, keeps the registration of every hosted collection alive
 		go func() {
 			for {
-				hub.Regist(INDEX_SERVICE, service.selfAddr, leaseId)
+				service.lock.RLock()
+				leases := make(map[string]etcdv3.LeaseID, len(service.leases))
+				for name, leaseId := range service.leases {
+					leases[name] = leaseId
+				}
+				service.lock.RUnlock()
+				for name, leaseId := range leases {
+					if newLeaseId, err := hub.Regist(name, service.selfAddr, leaseId); err == nil && newLeaseId != leaseId {
+						service.lock.Lock()
+						if _, exists := service.leases[name]; exists { // the collection may have been dropped meanwhile
+							service.leases[name] = newLeaseId
+						}
+						service.lock.Unlock()
+					}
+				}
 				time.Sleep(time.Duration(heartBeat)*time.Second - 100*time.Millisecond)
 			}
 		}()
@@ -64,27 +110,423 @@ func (service *IndexServiceWorker) Regist(etcdServers []string, servicePort int)
 	return nil
 }
 
+func (service *IndexServiceWorker) registService(name string) error {
+	leaseId, err := service.hub.Regist(name, service.selfAddr, 0)
+	if err != nil {
+		return err
+	}
+	service.lock.Lock()
+	service.leases[name] = leaseId
+	service.lock.Unlock()
+
+	return nil
+}
+
+func (service *IndexServiceWorker) unregistService(name string) {
+	service.lock.Lock()
+	delete(service.leases, name)
+	service.lock.Unlock()
+	service.hub.UnRegist(name, service.selfAddr)
+}
+
+// collectionPath is the forward index of this worker's part of a collection
+func (service *IndexServiceWorker) collectionPath(name string) string {
+	return service.dataDir + "_" + name
+}
+
+// watchCollections opens the collections that exist in etcd, then follows their creation and deletion
+func (service *IndexServiceWorker) watchCollections(etcdServers []string) error {
+	client, err := etcdv3.New(etcdv3.Config{Endpoints: etcdServers, DialTimeout: 3 * time.Second})
+	if err != nil {
+		return err
+	}
+	service.etcdClient = client
+
+	names, err := listCollections(client)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if name != DEFAULT_COLLECTION {
+			service.openCollection(name)
+		}
+	}
+
+	ch := client.Watch(context.Background(), COLLECTION_ROOT_PATH+"/", etcdv3.WithPrefix())
+	go func() {
+		for response := range ch {
+			for _, event := range response.Events {
+				key := string(event.Kv.Key)
+				if !strings.HasSuffix(key, "/schema") {
+					continue
+				}
+				name := strings.TrimSuffix(strings.TrimPrefix(key, COLLECTION_ROOT_PATH+"/"), "/schema")
+				switch event.Type {
+				case etcdv3.EventTypePut:
+					service.openCollection(name)
+				case etcdv3.EventTypeDelete:
+					service.dropCollection(name)
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (service *IndexServiceWorker) openCollection(name string) {
+	service.lock.Lock()
+	if _, exists := service.collections[name]; exists {
+		service.lock.Unlock()
+		return
+	}
+	indexer := new(Indexer)
+	if err := indexer.Init(50000, service.dbtype, service.collectionPath(name)); err != nil {
+		service.lock.Unlock()
+		logger.Log.Printf("open collection %s failed: %s", name, err)
+		return
+	}
+	indexer.LoadFromIndexFile()
+	service.collections[name] = indexer
+	service.lock.Unlock()
+
+	if err := service.registService(CollectionService(name)); err != nil {
+		logger.Log.Printf("regist collection %s failed: %s", name, err)
+	}
+	logger.Log.Printf("open collection %s", name)
+}
+
+func (service *IndexServiceWorker) dropCollection(name string) {
+	service.unregistService(CollectionService(name))
+	service.lock.Lock()
+	indexer, exists := service.collections[name]
+	delete(service.collections, name)
+	service.lock.Unlock()
+	if exists {
+		indexer.Close()
+		os.RemoveAll(service.collectionPath(name))
+		logger.Log.Printf("drop collection %s", name)
+	}
+}
+
+// indexer returns the indexer of the collection named in the request metadata
+func (service *IndexServiceWorker) indexer(ctx context.Context) (*Indexer, error) {
+	name := CollectionFromContext(ctx)
+	if name == DEFAULT_COLLECTION {
+		return service.Indexer, nil
+	}
+
+	service.lock.RLock()
+	defer service.lock.RUnlock()
+	if indexer, exists := service.collections[name]; exists {
+		return indexer, nil
+	}
+	return nil, fmt.Errorf("collection %s is not hosted by worker %s", name, service.selfAddr)
+}
+
 func (service *IndexServiceWorker) Close() error {
 	if service.hub != nil {
-		service.hub.UnRegist(INDEX_SERVICE, service.selfAddr)
+		service.lock.RLock()
+		names := make([]string, 0, len(service.leases))
+		for name := range service.leases {
+			names = append(names, name)
+		}
+		service.lock.RUnlock()
+		for _, name := range names {
+			service.hub.UnRegist(name, service.selfAddr)
+		}
+	}
+	if service.etcdClient != nil {
+		service.etcdClient.Close()
+	}
+	service.lock.Lock()
+	for _, indexer := range service.collections {
+		indexer.Close()
 	}
+	service.lock.Unlock()
 	return service.Indexer.Close()
 }
 
 func (service *IndexServiceWorker) DeleteDoc(ctx context.Context, docId *index_proto.DocId) (*index_proto.AffectedCount, error) {
-	return &index_proto.AffectedCount{Count: int32(service.Indexer.DeleteDoc(docId.DocId))}, nil
+	indexer, err := service.indexer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &index_proto.AffectedCount{Count: int32(indexer.DeleteDoc(docId.DocId))}, nil
 }
 
 func (service *IndexServiceWorker) AddDoc(ctx context.Context, doc *search_proto.Document) (*index_proto.AffectedCount, error) {
-	n, err := service.Indexer.AddDoc(*doc)
+	indexer, err := service.indexer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	n, err := indexer.AddDoc(*doc)
 	return &index_proto.AffectedCount{Count: int32(n)}, err
 }
 
 func (service *IndexServiceWorker) Search(ctx context.Context, request *index_proto.SearchRequest) (*index_proto.SearchResult, error) {
-	result := service.Indexer.Search(request.Query, request.OnFlag, request.OffFlag, request.OrFlags)
+	indexer, err := service.indexer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := indexer.Search(request.Query, request.OnFlag, request.OffFlag, request.OrFlags)
+	result, _ = filterShards(request.Shards, result, nil)
 	return &index_proto.SearchResult{Results: result}, nil
 }
 
+func (service *IndexServiceWorker) SearchVector(ctx context.Context, request *index_proto.VectorSearchRequest) (*index_proto.SearchResult, error) {
+	indexer, err := service.indexer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result, scores := indexer.SearchVector(request.Vector, int(request.K))
+	result, scores = filterShards(request.Shards, result, scores)
+	return &index_proto.SearchResult{Results: result, Scores: scores}, nil
+}
+
+// SearchStream sends the results of Search in chunks, so a broad query is not bounded by the message size limit. Only the ids
+// of the matches are held at once, the documents are read from the forward index SEARCH_STREAM_READ at a time as they are sent.
+func (service *IndexServiceWorker) SearchStream(request *index_proto.SearchRequest, stream index_proto.IndexService_SearchStreamServer) error {
+	indexer, err := service.indexer(stream.Context())
+	if err != nil {
+		return err
+	}
+	docIds := indexer.reverseIndex.Search(request.Query, request.OnFlag, request.OffFlag, request.OrFlags)
+	if inShards := shardFilter(request.Shards); inShards != nil {
+		docIds = slices.DeleteFunc(docIds, func(docId string) bool { return !inShards(docId) })
+	}
+
+	chunks := &resultChunks{send: stream.Send}
+	for start := 0; start < len(docIds); start += SEARCH_STREAM_READ {
+		docs, err := indexer.MultiGetDoc(docIds[start:min(start+SEARCH_STREAM_READ, len(docIds))])
+		if err != nil {
+			return err
+		}
+		for _, doc := range docs {
+			if err := chunks.add(doc); err != nil {
+				return err // the reader stopped reading
+			}
+		}
+	}
+	return chunks.flush()
+}
+
+// SearchVectorStream sends the results of SearchVector in chunks by descending score, so the reader can stop once the
+// rest of the stream can not make its top k
+func (service *IndexServiceWorker) SearchVectorStream(request *index_proto.VectorSearchRequest, stream index_proto.IndexService_SearchVectorStreamServer) error {
+	indexer, err := service.indexer(stream.Context())
+	if err != nil {
+		return err
+	}
+	result, scores := indexer.SearchVector(request.Vector, int(request.K))
+	result, scores = filterShards(request.Shards, result, scores)
+	order := make([]int, len(result))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return scores[order[i]] > scores[order[j]]
+	})
+	docs := make([]*search_proto.Document, 0, len(order))
+	sorted := make([]float32, 0, len(order))
+	for _, i := range order {
+		docs = append(docs, result[i])
+		sorted = append(sorted, scores[i])
+	}
+	return sendChunks(stream.Send, docs, sorted)
+}
+
+// sendChunks sends the documents and their scores, if there are any, in chunks of about SEARCH_CHUNK_BYTES
+func sendChunks(send func(*index_proto.SearchResult) error, docs []*search_proto.Document, scores []float32) error {
+	chunks := &resultChunks{send: send}
+	for i, doc := range docs {
+		if err := chunks.add(doc); err != nil {
+			return err
+		}
+		if len(scores) == len(docs) {
+			chunks.chunk.Scores = append(chunks.chunk.Scores, scores[i])
+		}
+	}
+	return chunks.flush()
+}
+
+// resultChunks sends the documents added to it in chunks of about SEARCH_CHUNK_BYTES, a larger document is sent alone
+type resultChunks struct {
+	send  func(*index_proto.SearchResult) error
+	chunk *index_proto.SearchResult // the chunk being filled, the score of a document goes to the chunk it was added to
+	size  int
+}
+
+func (chunks *resultChunks) add(doc *search_proto.Document) error {
+	docSize := proto.Size(doc)
+	if chunks.chunk != nil && len(chunks.chunk.Results) > 0 && chunks.size+docSize > SEARCH_CHUNK_BYTES {
+		if err := chunks.flush(); err != nil {
+			return err
+		}
+	}
+	if chunks.chunk == nil {
+		chunks.chunk = &index_proto.SearchResult{}
+	}
+	chunks.chunk.Results = append(chunks.chunk.Results, doc)
+	chunks.size += docSize
+	return nil
+}
+
+// flush sends the chunk being filled, if it has documents
+func (chunks *resultChunks) flush() error {
+	chunk := chunks.chunk
+	chunks.chunk, chunks.size = nil, 0
+	if chunk == nil || len(chunk.Results) == 0 {
+		return nil
+	}
+	return chunks.send(chunk)
+}
+
+func (service *IndexServiceWorker) MoreLikeThis(ctx context.Context, request *index_proto.MoreLikeThisRequest) (*index_proto.MoreLikeThisResult, error) {
+	indexer, err := service.indexer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if request.Like != nil {
+		result, scores := indexer.MoreLikeThisDoc(request.Like, int(request.Limit))
+		result, scores = filterShards(request.Shards, result, scores)
+		return &index_proto.MoreLikeThisResult{Results: result, Scores: scores}, nil
+	}
+
+	like, err := indexer.GetDoc(request.DocId)
+	if err != nil || like == nil {
+		return &index_proto.MoreLikeThisResult{}, err // the source document lives on another worker
+	}
+	result, scores := indexer.MoreLikeThisDoc(like, int(request.Limit))
+	result, scores = filterShards(request.Shards, result, scores)
+	return &index_proto.MoreLikeThisResult{Results: result, Scores: scores, Like: like}, nil
+}
+
+// filterShards keeps the documents of the shards of filter, and their scores if there are any
+func filterShards(filter *index_proto.ShardFilter, docs []*search_proto.Document, scores []float32) ([]*search_proto.Document, []float32) {
+	inShards := shardFilter(filter)
+	if inShards == nil {
+		return docs, scores
+	}
+	n := 0
+	for i, doc := range docs {
+		if inShards(doc.Id) {
+			docs[n] = doc
+			if len(scores) == len(docs) {
+				scores[n] = scores[i]
+			}
+			n++
+		}
+	}
+	if len(scores) == len(docs) {
+		scores = scores[:n]
+	}
+	return docs[:n], scores
+}
+
+// shardFilter returns whether a document id belongs to the shards of filter, nil if the read is not limited to some shards
+func shardFilter(filter *index_proto.ShardFilter) func(docId string) bool {
+	if filter == nil || filter.Shards <= 0 {
+		return nil
+	}
+	shards := make(map[int32]struct{}, len(filter.Ids))
+	for _, shard := range filter.Ids {
+		shards[shard] = struct{}{}
+	}
+	shardMap := ShardMap{Shards: int(filter.Shards)}
+	return func(docId string) bool {
+		_, exists := shards[int32(shardMap.Shard(docId))]
+		return exists
+	}
+}
+
+func (service *IndexServiceWorker) GetDoc(ctx context.Context, docId *index_proto.DocId) (*index_proto.GetDocResult, error) {
+	indexer, err := service.indexer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := indexer.GetDoc(docId.DocId)
+	return &index_proto.GetDocResult{Doc: doc}, err
+}
+
+func (service *IndexServiceWorker) MultiGetDoc(ctx context.Context, docIds *index_proto.DocIds) (*index_proto.MultiGetDocResult, error) {
+	indexer, err := service.indexer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	docs, err := indexer.MultiGetDoc(docIds.DocIds)
+	return &index_proto.MultiGetDocResult{Docs: docs}, err
+}
+
+// Bulk reads the operations of the stream and applies them in batches of BULK_BATCH_SIZE
+func (service *IndexServiceWorker) Bulk(stream index_proto.IndexService_BulkServer) error {
+	indexer, err := service.indexer(stream.Context())
+	if err != nil {
+		return err
+	}
+
+	results := make([]*index_proto.BulkResult, 0, BULK_BATCH_SIZE)
+	batch := make([]*index_proto.BulkOperation, 0, BULK_BATCH_SIZE)
+	for {
+		operation, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		batch = append(batch, operation)
+		if len(batch) == BULK_BATCH_SIZE {
+			results = append(results, indexer.Bulk(batch)...)
+			batch = batch[:0]
+		}
+	}
+	results = append(results, indexer.Bulk(batch)...)
+
+	return stream.SendAndClose(&index_proto.BulkResponse{Results: results})
+}
+
+// Transfer streams the documents of the shards of the request in chunks of BULK_BATCH_SIZE, then their count and digest.
+// The ids are listed first so that no read transaction stays open while the chunks are sent.
+func (service *IndexServiceWorker) Transfer(request *index_proto.TransferRequest, stream index_proto.IndexService_TransferServer) error {
+	indexer, err := service.indexer(stream.Context())
+	if err != nil {
+		return err
+	}
+	inShards := shardFilter(request.Shards)
+	if inShards == nil {
+		return fmt.Errorf("transfer without shards")
+	}
+
+	docIds := indexer.DocIds(inShards)
+	var digest uint64
+	count := 0
+	for start := 0; start < len(docIds); start += BULK_BATCH_SIZE {
+		docs, err := indexer.MultiGetDoc(docIds[start:min(start+BULK_BATCH_SIZE, len(docIds))])
+		if err != nil {
+			return err
+		}
+		for _, doc := range docs {
+			digest ^= DocDigest(doc)
+		}
+		count += len(docs) // documents deleted meanwhile are left out
+		if !request.DigestOnly && len(docs) > 0 {
+			if err := stream.Send(&index_proto.TransferChunk{Docs: docs}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return stream.Send(&index_proto.TransferChunk{Count: int32(count), Digest: digest})
+}
+
 func (service *IndexServiceWorker) Count(ctx context.Context, request *index_proto.CountRequest) (*index_proto.AffectedCount, error) {
-	return &index_proto.AffectedCount{Count: int32(service.Indexer.Count())}, nil
+	indexer, err := service.indexer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if inShards := shardFilter(request.Shards); inShards != nil {
+		return &index_proto.AffectedCount{Count: int32(indexer.CountIf(inShards))}, nil
+	}
+	return &index_proto.AffectedCount{Count: int32(indexer.Count())}, nil
 }
@@ -0,0 +1,79 @@
+package indexing
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	index_proto "github.com/m1i3k0e7/distributed-search-engine/api/proto/index"
+	search_proto "github.com/m1i3k0e7/distributed-search-engine/api/proto/search"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/kvdb"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/schema"
+	"google.golang.org/protobuf/proto"
+)
+
+// fakeSearchStream collects the chunks a worker sends
+type fakeSearchStream struct {
+	index_proto.IndexService_SearchStreamServer
+	chunks []*index_proto.SearchResult
+}
+
+func (stream *fakeSearchStream) Context() context.Context {
+	return context.Background()
+}
+
+func (stream *fakeSearchStream) Send(chunk *index_proto.SearchResult) error {
+	stream.chunks = append(stream.chunks, chunk)
+	return nil
+}
+
+func TestSearchStream(t *testing.T) {
+	service := new(IndexServiceWorker)
+	if err := service.Init(100, kvdb.BOLT, filepath.Join(t.TempDir(), "index")); err != nil {
+		t.Fatal(err)
+	}
+	defer service.Indexer.Close()
+
+	// 5MB of matching documents, more than SEARCH_STREAM_READ in the shards asked for
+	const n = 2500
+	operations := make([]*index_proto.BulkOperation, 0, n)
+	for i := 0; i < n; i++ {
+		doc := testDocument(strconv.Itoa(i), "shoe")
+		doc.Bytes = make([]byte, 2048)
+		operations = append(operations, &index_proto.BulkOperation{Doc: &doc})
+	}
+	service.Indexer.Bulk(operations)
+
+	filter := &index_proto.ShardFilter{Shards: 4, Ids: []int32{0, 1}}
+	inShards := shardFilter(filter)
+	expect := 0
+	for i := 0; i < n; i++ {
+		if inShards(strconv.Itoa(i)) {
+			expect++
+		}
+	}
+
+	stream := &fakeSearchStream{}
+	request := &index_proto.SearchRequest{Query: search_proto.NewTermQuery(schema.CONTENT_FIELD, "shoe"), Shards: filter}
+	if err := service.SearchStream(request, stream); err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool, expect)
+	for _, chunk := range stream.chunks {
+		size := 0
+		for _, doc := range chunk.Results {
+			if seen[doc.Id] || !inShards(doc.Id) {
+				t.Fatalf("unexpected document %s", doc.Id)
+			}
+			seen[doc.Id] = true
+			size += proto.Size(doc)
+		}
+		if size > SEARCH_CHUNK_BYTES {
+			t.Errorf("expect chunks of %d bytes at most, got %d", SEARCH_CHUNK_BYTES, size)
+		}
+	}
+	if len(seen) != expect || len(stream.chunks) < 2 {
+		t.Errorf("expect %d documents in several chunks, got %d in %d chunks", expect, len(seen), len(stream.chunks))
+	}
+}
@@ -2,13 +2,16 @@ package indexing
 
 import (
 	"bytes"
+	"context"
 	"encoding/gob"
 	"strings"
 	"sync/atomic"
 
+	farmhash "github.com/leemcloughlin/gofarmhash"
 	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/kvdb"
 	inverted_index "github.com/m1i3k0e7/distributed-search-engine/internal/indexing/inverted_index"
 	search_proto "github.com/m1i3k0e7/distributed-search-engine/api/proto/search"
+	"github.com/m1i3k0e7/distributed-search-engine/pkg/hnsw"
 	"github.com/m1i3k0e7/distributed-search-engine/pkg/logger"
 )
 
@@ -16,7 +19,9 @@ import (
 type Indexer struct {
 	forwardIndex kvdb.IKeyValueDB
 	reverseIndex inverted_index.IReverseIndexer
+	vectorIndex  *hnsw.Graph // ANN index of Document.Vector, kept in memory and rebuilt from the forward index
 	maxIntId     uint64
+	docNum       int64 // number of documents, used as N when computing idf
 }
 
 func (indexer *Indexer) Init(DocNumEstimate int, dbtype int, DataDir string) error {
@@ -27,12 +32,14 @@ func (indexer *Indexer) Init(DocNumEstimate int, dbtype int, DataDir string) err
 
 	indexer.forwardIndex = db
 	indexer.reverseIndex = inverted_index.NewSkipListReverseIndex(DocNumEstimate)
+	indexer.vectorIndex = hnsw.NewDefaultGraph()
 
 	return nil
 }
 
 func (indexer *Indexer) LoadFromIndexFile() int {
 	reader := bytes.NewReader([]byte{})
+	var maxIntId uint64
 	n := indexer.forwardIndex.IterDB(func(k, v []byte) error {
 		reader.Reset(v)
 		decoder := gob.NewDecoder(reader)
@@ -44,10 +51,14 @@ func (indexer *Indexer) LoadFromIndexFile() int {
 		}
 
 		indexer.reverseIndex.Add(doc)
+		indexer.addVector(&doc)
+		maxIntId = max(maxIntId, doc.IntId)
 
 		return err
 	})
 	
+	atomic.StoreInt64(&indexer.docNum, n)
+	atomic.StoreUint64(&indexer.maxIntId, maxIntId) // documents added later must not reuse the IntId of a loaded one
 	logger.Log.Printf("load %d data from forward index %s", n, indexer.forwardIndex.GetDbPath())
 
 	return int(n)
@@ -75,9 +86,20 @@ func (indexer *Indexer) AddDoc(doc search_proto.Document) (int, error) {
 
 	// add the document to the inverted index
 	indexer.reverseIndex.Add(doc)
+	indexer.addVector(&doc)
+	atomic.AddInt64(&indexer.docNum, 1)
 	return 1, nil
 }
 
+func (indexer *Indexer) addVector(doc *search_proto.Document) {
+	if len(doc.Vector) == 0 {
+		return
+	}
+	if !indexer.vectorIndex.Add(doc.Id, doc.Vector) {
+		logger.Log.Printf("vector of document %s has dimension %d, expect %d", doc.Id, len(doc.Vector), indexer.vectorIndex.Dim())
+	}
+}
+
 func (indexer *Indexer) UpdateDoc(doc search_proto.Document) (int, error) {
 	docId := strings.TrimSpace(doc.Id)
 	if len(docId) == 0 {
@@ -108,14 +130,57 @@ func (indexer *Indexer) DeleteDoc(docId string) int {
 		return 0
 	}
 
+	indexer.vectorIndex.Delete(docId)
+
 	// Delete the document from the forward index
 	if err := indexer.forwardIndex.Delete(forwardKey); err != nil {
 		return 0
 	}
 
+	atomic.AddInt64(&indexer.docNum, -1)
 	return 1
 }
 
+// GetDoc reads a document from the forward index, it returns nil if the document does not exist
+func (indexer *Indexer) GetDoc(docId string) (*search_proto.Document, error) {
+	docBs, err := indexer.forwardIndex.Get([]byte(docId))
+	if err != nil || len(docBs) == 0 {
+		return nil, nil
+	}
+
+	var doc search_proto.Document
+	if err := gob.NewDecoder(bytes.NewReader(docBs)).Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	return &doc, nil
+}
+
+func (indexer *Indexer) MultiGetDoc(docIds []string) ([]*search_proto.Document, error) {
+	keys := make([][]byte, 0, len(docIds))
+	for _, docId := range docIds {
+		keys = append(keys, []byte(docId))
+	}
+	docsBs, err := indexer.forwardIndex.BatchGet(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	// BatchGet returns the values in the order of the keys, with an empty value for a missing key
+	docs := make([]*search_proto.Document, 0, len(docsBs))
+	reader := bytes.NewReader([]byte{})
+	for _, docBs := range docsBs {
+		if len(docBs) > 0 {
+			reader.Reset(docBs)
+			var doc search_proto.Document
+			if err := gob.NewDecoder(reader).Decode(&doc); err == nil {
+				docs = append(docs, &doc)
+			}
+		}
+	}
+	return docs, nil
+}
+
 func (indexer *Indexer) Search(query *search_proto.TermQuery, onFlag uint64, offFlag uint64, orFlags []uint64) []*search_proto.Document {
 	docIds := indexer.reverseIndex.Search(query, onFlag, offFlag, orFlags)
 	if len(docIds) == 0 {
@@ -151,6 +216,52 @@ func (indexer *Indexer) Search(query *search_proto.TermQuery, onFlag uint64, off
 	return result
 }
 
+// SearchContext searches the local index completely, it does not wait for anything that the deadline could cut short
+func (indexer *Indexer) SearchContext(ctx context.Context, query *search_proto.TermQuery, onFlag uint64, offFlag uint64, orFlags []uint64) ([]*search_proto.Document, SearchStatus) {
+	return indexer.Search(query, onFlag, offFlag, orFlags), SearchStatus{}
+}
+
+func (indexer *Indexer) SearchVectorContext(ctx context.Context, vector []float32, k int) ([]*search_proto.Document, []float32, SearchStatus) {
+	docs, scores := indexer.SearchVector(vector, k)
+	return docs, scores, SearchStatus{}
+}
+
+func (indexer *Indexer) SearchVector(vector []float32, k int) ([]*search_proto.Document, []float32) {
+	neighbors := indexer.vectorIndex.Search(vector, k)
+	if len(neighbors) == 0 {
+		return nil, nil
+	}
+
+	keys := make([][]byte, 0, len(neighbors))
+	for _, neighbor := range neighbors {
+		keys = append(keys, []byte(neighbor.Key))
+	}
+
+	docs, err := indexer.forwardIndex.BatchGet(keys)
+	if err != nil {
+		logger.Log.Printf("read kvdb failed: %s", err)
+		return nil, nil
+	}
+
+	result := make([]*search_proto.Document, 0, len(docs))
+	scores := make([]float32, 0, len(docs))
+	reader := bytes.NewReader([]byte{})
+	for i, docBs := range docs {
+		if len(docBs) > 0 {
+			reader.Reset(docBs)
+			decoder := gob.NewDecoder(reader)
+			var doc search_proto.Document
+			err := decoder.Decode(&doc)
+			if err == nil {
+				result = append(result, &doc)
+				scores = append(scores, 1-neighbors[i].Distance) // cosine similarity
+			}
+		}
+	}
+
+	return result, scores
+}
+
 func (indexer *Indexer) Count() int {
 	n := 0
 	indexer.forwardIndex.IterKey(func(k []byte) error {
@@ -160,3 +271,34 @@ func (indexer *Indexer) Count() int {
 
 	return n
 }
+
+// DocIds returns the ids of the documents keep returns true for, in ascending order
+func (indexer *Indexer) DocIds(keep func(docId string) bool) []string {
+	docIds := make([]string, 0)
+	indexer.forwardIndex.IterKey(func(k []byte) error {
+		if keep(string(k)) {
+			docIds = append(docIds, string(k))
+		}
+		return nil
+	})
+
+	return docIds
+}
+
+// DocDigest hashes the id and the content of a document, the xor of the digests of some documents does not depend on their order
+func DocDigest(doc *search_proto.Document) uint64 {
+	return farmhash.Hash64(append([]byte(doc.Id+"\x00"), doc.Bytes...))
+}
+
+// CountIf counts the documents whose id keep returns true for
+func (indexer *Indexer) CountIf(keep func(docId string) bool) int {
+	n := 0
+	indexer.forwardIndex.IterKey(func(k []byte) error {
+		if keep(string(k)) {
+			n++
+		}
+		return nil
+	})
+
+	return n
+}
@@ -0,0 +1,64 @@
+package indexing
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	search_proto "github.com/m1i3k0e7/distributed-search-engine/api/proto/search"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/kvdb"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/schema"
+)
+
+func testDocument(id string, words ...string) search_proto.Document {
+	keywords := make([]*search_proto.Keyword, 0, len(words))
+	for _, word := range words {
+		keywords = append(keywords, &search_proto.Keyword{Field: schema.CONTENT_FIELD, Word: word})
+	}
+	return search_proto.Document{Id: id, Keywords: keywords}
+}
+
+func TestAddAfterLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index")
+	indexer := new(Indexer)
+	if err := indexer.Init(100, kvdb.BOLT, path); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"a", "b"} {
+		if _, err := indexer.AddDoc(testDocument(id, "shoe")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	indexer.Close()
+
+	// the restarted indexer must not give new documents the IntIds of the loaded ones
+	indexer = new(Indexer)
+	if err := indexer.Init(100, kvdb.BOLT, path); err != nil {
+		t.Fatal(err)
+	}
+	defer indexer.Close()
+	if n := indexer.LoadFromIndexFile(); n != 2 {
+		t.Fatalf("expect 2 loaded documents, got %d", n)
+	}
+	if _, err := indexer.AddDoc(testDocument("c", "shoe")); err != nil {
+		t.Fatal(err)
+	}
+
+	docs := indexer.Search(search_proto.NewTermQuery(schema.CONTENT_FIELD, "shoe"), 0, 0, nil)
+	ids := make([]string, 0, len(docs))
+	intIds := make(map[uint64]bool, len(docs))
+	for _, doc := range docs {
+		ids = append(ids, doc.Id)
+		intIds[doc.IntId] = true
+	}
+	slices.Sort(ids)
+	if !slices.Equal(ids, []string{"a", "b", "c"}) {
+		t.Errorf("expect a, b and c, got %v", ids)
+	}
+	if len(intIds) != len(docs) {
+		t.Errorf("expect distinct IntIds, got %v", intIds)
+	}
+	if n := indexer.Count(); n != 3 {
+		t.Errorf("expect 3 documents, got %d", n)
+	}
+}
@@ -0,0 +1,165 @@
+package indexing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/m1i3k0e7/distributed-search-engine/pkg/logger"
+	etcdv3 "go.etcd.io/etcd/client/v3"
+)
+
+const (
+	INGEST_PROGRESS_KEY = "/radic/ingest/progress" // json IngestProgress of the running or the last coordinated build
+
+	INGEST_RUNNING  = "running"
+	INGEST_COMPLETE = "complete"
+	INGEST_FAILED   = "failed" // the build stopped before the end, running the coordinator again resumes it
+
+	INGEST_COUNT_INTERVAL = 10 * time.Second // the workers scan their index to count documents, the counts are refreshed at most this often
+)
+
+// IngestProgress is the state of a coordinated build, published in etcd after every batch
+type IngestProgress struct {
+	Coordinator   string
+	Collection    string
+	State         string
+	File          string // file being built
+	Rows          int
+	Documents     int
+	Replaced      int
+	Skipped       int
+	ErrorCount    int
+	RowsPerSecond float64
+	Workers       map[string]int // documents hosted by each worker, as of the last count
+	Started       time.Time
+	Updated       time.Time
+}
+
+// IngestCoordinator builds the index of a cluster. It reads and analyzes the source files once, then streams every
+// batch to the workers with Bulk, each document to the worker hosting its shard, so the workers do not read the files.
+type IngestCoordinator struct {
+	sentinel *Sentinel
+	client   *etcdv3.Client
+	progress IngestProgress
+	counted  time.Time // when the documents of the workers were counted last
+}
+
+func NewIngestCoordinator(etcdServers []string, sentinel *Sentinel) (*IngestCoordinator, error) {
+	client, err := etcdv3.New(etcdv3.Config{Endpoints: etcdServers, DialTimeout: 3 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	return &IngestCoordinator{sentinel: sentinel, client: client}, nil
+}
+
+// Run builds the index from the files of dir. options.Shards is ignored, the sentinel routes the documents.
+func (coordinator *IngestCoordinator) Run(dir string, options BuildOptions) (*BuildReport, error) {
+	workers := coordinator.sentinel.hub.GetServiceEndpoints(coordinator.sentinel.service)
+	if len(workers) == 0 {
+		return nil, fmt.Errorf("there is no alive index worker")
+	}
+	logger.Log.Printf("build the index of %d workers from %s", len(workers), dir)
+
+	hostname, _ := os.Hostname()
+	coordinator.progress = IngestProgress{
+		Coordinator: hostname,
+		Collection:  coordinator.sentinel.collection,
+		State:       INGEST_RUNNING,
+		Workers:     make(map[string]int),
+		Started:     time.Now(),
+	}
+	coordinator.publish()
+
+	options.Shards = nil
+	options.Progress = func(report *BuildReport, file string) {
+		coordinator.update(report, file)
+		if time.Since(coordinator.counted) >= INGEST_COUNT_INTERVAL {
+			coordinator.count()
+		}
+		coordinator.publish()
+	}
+	report := BuildIndexFromDir(dir, coordinator.sentinel, options)
+
+	coordinator.update(report, "")
+	coordinator.count()
+	coordinator.progress.State = INGEST_COMPLETE
+	if !report.Complete {
+		coordinator.progress.State = INGEST_FAILED
+	}
+	coordinator.publish()
+
+	return report, nil
+}
+
+func (coordinator *IngestCoordinator) update(report *BuildReport, file string) {
+	progress := &coordinator.progress
+	progress.File = file
+	progress.Rows = report.Rows
+	progress.Documents = report.Documents
+	progress.Replaced = report.Replaced
+	progress.Skipped = report.Skipped
+	progress.ErrorCount = report.ErrorCount
+	progress.RowsPerSecond = report.RowsPerSecond()
+}
+
+func (coordinator *IngestCoordinator) count() {
+	coordinator.progress.Workers = coordinator.sentinel.WorkerCounts()
+	coordinator.counted = time.Now()
+}
+
+// publish writes the progress to etcd, a failure only costs the visibility of the build
+func (coordinator *IngestCoordinator) publish() {
+	coordinator.progress.Updated = time.Now()
+	bs, err := json.Marshal(coordinator.progress)
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if _, err := coordinator.client.Put(ctx, INGEST_PROGRESS_KEY, string(bs)); err != nil {
+		logger.Log.Printf("publish ingest progress failed: %s", err)
+	}
+}
+
+func (coordinator *IngestCoordinator) Close() error {
+	return coordinator.client.Close()
+}
+
+// IngestMonitor reads the progress of coordinated builds for the web servers
+type IngestMonitor struct {
+	client *etcdv3.Client
+}
+
+func NewIngestMonitor(etcdServers []string) (*IngestMonitor, error) {
+	client, err := etcdv3.New(etcdv3.Config{Endpoints: etcdServers, DialTimeout: 3 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	return &IngestMonitor{client: client}, nil
+}
+
+// Progress returns the progress of the running or the last coordinated build, nil if there was none
+func (monitor *IngestMonitor) Progress() (*IngestProgress, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	response, err := monitor.client.Get(ctx, INGEST_PROGRESS_KEY)
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Kvs) == 0 {
+		return nil, nil
+	}
+
+	var progress IngestProgress
+	if err := json.Unmarshal(response.Kvs[0].Value, &progress); err != nil {
+		return nil, err
+	}
+	return &progress, nil
+}
+
+func (monitor *IngestMonitor) Close() error {
+	return monitor.client.Close()
+}
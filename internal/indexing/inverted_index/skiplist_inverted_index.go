@@ -56,6 +56,32 @@ func (indexer *SkipListReverseIndex) Add(doc search_proto.Document) {
 	}
 }
 
+func (indexer *SkipListReverseIndex) BatchAdd(docs []*search_proto.Document) {
+	postings := make(map[string][]*search_proto.Document) // group the docs by keyword
+	for _, doc := range docs {
+		for _, keyword := range doc.Keywords {
+			key := keyword.ToString()
+			postings[key] = append(postings[key], doc)
+		}
+	}
+
+	for key, docs := range postings {
+		lock := indexer.getLock(key)
+		lock.Lock()
+		var list *skiplist.SkipList
+		if value, exists := indexer.table.Get(key); exists {
+			list = value.(*skiplist.SkipList)
+		} else {
+			list = skiplist.New(skiplist.Uint64)
+			indexer.table.Set(key, list)
+		}
+		for _, doc := range docs {
+			list.Set(doc.IntId, SkipListValue{doc.Id, doc.BitsFeature})
+		}
+		lock.Unlock()
+	}
+}
+
 func (indexer *SkipListReverseIndex) Delete(IntId uint64, keyword *search_proto.Keyword) {
 	key := keyword.ToString()
 	lock := indexer.getLock(key)
@@ -67,6 +93,18 @@ func (indexer *SkipListReverseIndex) Delete(IntId uint64, keyword *search_proto.
 	lock.Unlock()
 }
 
+func (indexer *SkipListReverseIndex) DocFreq(keyword *search_proto.Keyword) int {
+	key := keyword.ToString()
+	lock := indexer.getLock(key)
+	lock.RLock()
+	defer lock.RUnlock()
+	if value, exists := indexer.table.Get(key); exists {
+		return value.(*skiplist.SkipList).Len()
+	}
+
+	return 0
+}
+
 func IntersectionOfSkipList(lists ...*skiplist.SkipList) *skiplist.SkipList {
 	if len(lists) == 0 {
 		return nil
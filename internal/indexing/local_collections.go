@@ -0,0 +1,256 @@
+package indexing
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/category"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/kvdb"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/schema"
+	"github.com/m1i3k0e7/distributed-search-engine/pkg/logger"
+)
+
+// LocalCollections hosts collections in one process for standalone mode.
+// Schemas are kept in <dbPath>_collections and aliases in <dbPath>_aliases, each collection has its own forward index <dbPath>_<name>.
+type LocalCollections struct {
+	dbtype      int
+	dbPath      string
+	meta        kvdb.IKeyValueDB // collection name -> json schema
+	aliasDB     kvdb.IKeyValueDB // alias -> collection name
+	collections map[string]*Collection
+	aliases     map[string]string
+	lock        sync.RWMutex
+}
+
+// NewLocalCollections opens the collections created before, defaultCollection is served under DEFAULT_COLLECTION
+func NewLocalCollections(dbtype int, dbPath string, defaultCollection *Collection) (*LocalCollections, error) {
+	meta, err := kvdb.GetKvDb(dbtype, dbPath+"_collections")
+	if err != nil {
+		return nil, err
+	}
+	aliasDB, err := kvdb.GetKvDb(dbtype, dbPath+"_aliases")
+	if err != nil {
+		meta.Close()
+		return nil, err
+	}
+
+	collections := &LocalCollections{
+		dbtype:      dbtype,
+		dbPath:      dbPath,
+		meta:        meta,
+		aliasDB:     aliasDB,
+		collections: map[string]*Collection{DEFAULT_COLLECTION: defaultCollection},
+		aliases:     make(map[string]string),
+	}
+	aliasDB.IterDB(func(k, v []byte) error {
+		collections.aliases[string(k)] = string(v)
+		return nil
+	})
+	meta.IterDB(func(k, v []byte) error {
+		docSchema, err := schema.ParseSchema(v)
+		if err != nil {
+			logger.Log.Printf("parse schema of collection %s failed: %s", k, err)
+			return nil
+		}
+		collection, err := collections.open(string(k), docSchema)
+		if err != nil {
+			logger.Log.Printf("open collection %s failed: %s", k, err)
+			return nil
+		}
+		collection.Indexer.(*Indexer).LoadFromIndexFile()
+		collections.collections[collection.Name] = collection
+		return nil
+	})
+
+	return collections, nil
+}
+
+func (collections *LocalCollections) path(name string) string {
+	return collections.dbPath + "_" + name
+}
+
+func (collections *LocalCollections) open(name string, docSchema *schema.Schema) (*Collection, error) {
+	indexer := new(Indexer)
+	if err := indexer.Init(50000, collections.dbtype, collections.path(name)); err != nil {
+		return nil, err
+	}
+	categories, err := category.NewLocalRegistry(collections.dbtype, collections.path(name)+"_category")
+	if err != nil {
+		indexer.Close()
+		return nil, err
+	}
+
+	return &Collection{Name: name, Schema: docSchema, Ranking: docSchema.RankingConfig(), Indexer: indexer, Categories: categories}, nil
+}
+
+func (collections *LocalCollections) Create(name string, docSchema *schema.Schema) error {
+	if err := CheckCollectionName(name); err != nil {
+		return err
+	}
+	bs, err := json.Marshal(docSchema)
+	if err != nil {
+		return err
+	}
+
+	collections.lock.Lock()
+	defer collections.lock.Unlock()
+	if _, exists := collections.collections[name]; exists {
+		return fmt.Errorf("collection %s already exists", name)
+	}
+	if _, exists := collections.aliases[name]; exists {
+		return fmt.Errorf("%s is an alias", name)
+	}
+	collection, err := collections.open(name, docSchema)
+	if err != nil {
+		return err
+	}
+	if err := collections.meta.Set([]byte(name), bs); err != nil {
+		collection.Indexer.Close()
+		collection.Categories.Close()
+		return err
+	}
+	collections.collections[name] = collection
+	logger.Log.Printf("create collection %s", name)
+
+	return nil
+}
+
+// Drop closes the collection and deletes all its data
+func (collections *LocalCollections) Drop(name string) error {
+	if name == DEFAULT_COLLECTION {
+		return fmt.Errorf("collection %s can not be dropped", DEFAULT_COLLECTION)
+	}
+
+	collections.lock.Lock()
+	defer collections.lock.Unlock()
+	collection, exists := collections.collections[name]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrCollectionNotFound, name)
+	}
+	for alias, target := range collections.aliases {
+		if target == name {
+			return fmt.Errorf("%w: %s points to %s", ErrCollectionAliased, alias, name)
+		}
+	}
+	if err := collections.meta.Delete([]byte(name)); err != nil {
+		return err
+	}
+	delete(collections.collections, name)
+	collection.Indexer.Close()
+	collection.Categories.Close()
+	os.RemoveAll(collections.path(name))
+	os.RemoveAll(collections.path(name) + "_category")
+	os.Remove(collections.buildingPath(name))
+	logger.Log.Printf("drop collection %s", name)
+
+	return nil
+}
+
+func (collections *LocalCollections) Get(name string) (*Collection, bool) {
+	collections.lock.RLock()
+	defer collections.lock.RUnlock()
+	if target, exists := collections.aliases[name]; exists {
+		name = target
+	}
+	collection, exists := collections.collections[name]
+	return collection, exists
+}
+
+// SetAlias points alias to collection, requests resolving the alias switch to the new collection at once
+func (collections *LocalCollections) SetAlias(alias string, collection string, expected string) error {
+	if err := CheckCollectionName(alias); err != nil {
+		return err
+	}
+
+	collections.lock.Lock()
+	defer collections.lock.Unlock()
+	if _, exists := collections.collections[alias]; exists {
+		return fmt.Errorf("%s is a collection", alias)
+	}
+	if _, exists := collections.collections[collection]; !exists {
+		return fmt.Errorf("%w: %s", ErrCollectionNotFound, collection)
+	}
+	if _, err := os.Stat(collections.buildingPath(collection)); err == nil {
+		return fmt.Errorf("%w: %s", ErrCollectionBuilding, collection)
+	}
+	if current := collections.aliases[alias]; len(expected) > 0 && current != expected {
+		return fmt.Errorf("alias %s points to %q instead of %s", alias, current, expected)
+	}
+	if err := collections.aliasDB.Set([]byte(alias), []byte(collection)); err != nil {
+		return err
+	}
+	collections.aliases[alias] = collection
+	logger.Log.Printf("point alias %s to collection %s", alias, collection)
+
+	return nil
+}
+
+// buildingPath is a file that exists while a build of the collection runs
+func (collections *LocalCollections) buildingPath(name string) string {
+	return collections.path(name) + "_building"
+}
+
+func (collections *LocalCollections) SetBuilding(name string, building bool) error {
+	collections.lock.Lock()
+	defer collections.lock.Unlock()
+	if _, exists := collections.collections[name]; !exists {
+		return fmt.Errorf("%w: %s", ErrCollectionNotFound, name)
+	}
+	if !building {
+		if err := os.Remove(collections.buildingPath(name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return os.WriteFile(collections.buildingPath(name), []byte(time.Now().Format(time.RFC3339)), 0644)
+}
+
+func (collections *LocalCollections) RemoveAlias(alias string) error {
+	collections.lock.Lock()
+	defer collections.lock.Unlock()
+	if _, exists := collections.aliases[alias]; !exists {
+		return fmt.Errorf("alias %s not found", alias)
+	}
+	if err := collections.aliasDB.Delete([]byte(alias)); err != nil {
+		return err
+	}
+	delete(collections.aliases, alias)
+
+	return nil
+}
+
+func (collections *LocalCollections) Aliases() (map[string]string, error) {
+	collections.lock.RLock()
+	defer collections.lock.RUnlock()
+	aliases := make(map[string]string, len(collections.aliases))
+	for alias, collection := range collections.aliases {
+		aliases[alias] = collection
+	}
+	return aliases, nil
+}
+
+func (collections *LocalCollections) List() ([]string, error) {
+	collections.lock.RLock()
+	defer collections.lock.RUnlock()
+	names := make([]string, 0, len(collections.collections))
+	for name := range collections.collections {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func (collections *LocalCollections) Close() error {
+	collections.lock.Lock()
+	defer collections.lock.Unlock()
+	for _, collection := range collections.collections {
+		collection.Indexer.Close()
+		if collection.Categories != nil {
+			collection.Categories.Close()
+		}
+	}
+	collections.aliasDB.Close()
+	return collections.meta.Close()
+}
@@ -0,0 +1,154 @@
+package indexing
+
+import (
+	"bytes"
+	"encoding/gob"
+	"math"
+	"sort"
+	"sync/atomic"
+
+	search_proto "github.com/m1i3k0e7/distributed-search-engine/api/proto/search"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/category"
+	"github.com/m1i3k0e7/distributed-search-engine/pkg/logger"
+)
+
+const (
+	MLT_MAX_TERMS = 25 // number of most discriminative terms taken from the source document
+)
+
+// MoreLikeThis returns documents similar to the document docId, together with their similarity scores
+func (indexer *Indexer) MoreLikeThis(docId string, limit int) ([]*search_proto.Document, []float32) {
+	like, err := indexer.GetDoc(docId)
+	if err != nil || like == nil {
+		return nil, nil
+	}
+
+	return indexer.MoreLikeThisDoc(like, limit)
+}
+
+// MoreLikeThisDoc builds a weighted Should query from the most discriminative keywords of like, restricted to its categories.
+// Keywords are weighted by their idf in this index, so every shard scores with its own statistics.
+func (indexer *Indexer) MoreLikeThisDoc(like *search_proto.Document, limit int) ([]*search_proto.Document, []float32) {
+	query := indexer.likeQuery(like)
+	if query == nil || limit <= 0 {
+		return nil, nil
+	}
+
+	// share at least one category with the source document
+	categories := make([]*search_proto.TermQuery, 0, 2)
+	for _, keyword := range like.Keywords {
+		if keyword.Field == category.FIELD {
+			categories = append(categories, search_proto.NewTermQuery(keyword.Field, keyword.Word))
+		}
+	}
+	if len(categories) > 0 {
+		query = query.And(&search_proto.TermQuery{Should: categories})
+	}
+	docIds := indexer.reverseIndex.Search(query, 0, 0, nil)
+
+	keys := make([][]byte, 0, len(docIds))
+	for _, docId := range docIds {
+		if docId != like.Id {
+			keys = append(keys, []byte(docId))
+		}
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	docBss, err := indexer.forwardIndex.BatchGet(keys)
+	if err != nil {
+		logger.Log.Printf("read kvdb failed: %s", err)
+		return nil, nil
+	}
+
+	docs := make([]*search_proto.Document, 0, len(docBss))
+	scores := make([]float32, 0, len(docBss))
+	reader := bytes.NewReader([]byte{})
+	for _, docBs := range docBss {
+		if len(docBs) == 0 {
+			continue
+		}
+		reader.Reset(docBs)
+		var doc search_proto.Document
+		if err := gob.NewDecoder(reader).Decode(&doc); err != nil {
+			continue
+		}
+		keywords := make(map[string]struct{}, len(doc.Keywords))
+		for _, keyword := range doc.Keywords {
+			keywords[keyword.ToString()] = struct{}{}
+		}
+		docs = append(docs, &doc)
+		scores = append(scores, query.Score(keywords))
+	}
+
+	order := make([]int, len(docs))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return scores[order[i]] > scores[order[j]]
+	})
+	if len(order) > limit {
+		order = order[:limit]
+	}
+
+	topDocs := make([]*search_proto.Document, 0, len(order))
+	topScores := make([]float32, 0, len(order))
+	for _, i := range order {
+		topDocs = append(topDocs, docs[i])
+		topScores = append(topScores, scores[i])
+	}
+
+	return topDocs, topScores
+}
+
+// likeQuery weights each keyword of like by tf * idf and keeps the top MLT_MAX_TERMS ones
+func (indexer *Indexer) likeQuery(like *search_proto.Document) *search_proto.TermQuery {
+	tf := make(map[string]int, len(like.Keywords))
+	keywords := make(map[string]*search_proto.Keyword, len(like.Keywords))
+	for _, keyword := range like.Keywords {
+		if keyword.Field == category.FIELD {
+			continue // categories filter the results, they are not similarity terms
+		}
+		key := keyword.ToString()
+		tf[key]++
+		keywords[key] = keyword
+	}
+
+	type weightedTerm struct {
+		keyword *search_proto.Keyword
+		weight  float64
+	}
+	N := float64(atomic.LoadInt64(&indexer.docNum))
+	self := 0.0 // the source document counts in the df of its keywords only where it is indexed, a remote shard lacks it
+	if source, _ := indexer.GetDoc(like.Id); source != nil {
+		self = 1
+	}
+	terms := make([]weightedTerm, 0, len(keywords))
+	for key, keyword := range keywords {
+		df := float64(indexer.reverseIndex.DocFreq(keyword))
+		if df-self <= 0 {
+			continue // no other document has it, it can not recall anything
+		}
+		idf := math.Log((N-df+0.5)/(df+0.5) + 1)
+		terms = append(terms, weightedTerm{keyword, float64(tf[key]) * idf})
+	}
+	if len(terms) == 0 {
+		return nil
+	}
+
+	sort.Slice(terms, func(i, j int) bool {
+		return terms[i].weight > terms[j].weight
+	})
+	if len(terms) > MLT_MAX_TERMS {
+		terms = terms[:MLT_MAX_TERMS]
+	}
+
+	should := make([]*search_proto.TermQuery, 0, len(terms))
+	for _, term := range terms {
+		should = append(should, search_proto.NewWeightedTermQuery(term.keyword.Field, term.keyword.Word, float32(term.weight)))
+	}
+
+	return &search_proto.TermQuery{Should: should}
+}
@@ -0,0 +1,355 @@
+package indexing
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	index "github.com/m1i3k0e7/distributed-search-engine/api/proto/index"
+	search_proto "github.com/m1i3k0e7/distributed-search-engine/api/proto/search"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/schema"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/source"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/trie"
+	"github.com/m1i3k0e7/distributed-search-engine/pkg/logger"
+	"github.com/m1i3k0e7/distributed-search-engine/pkg/trie"
+)
+
+// BuildProgress is told the state of a build after every batch written, file is the file being built
+type BuildProgress func(report *BuildReport, file string)
+
+// RowFilter tells whether a row changed since it was indexed last, it is called concurrently by the analyzers
+type RowFilter func(docId string, values []string) bool
+
+// buildItem is a row on its way through the build pipeline, or the end of a file when row is nil
+type buildItem struct {
+	file     int // position of the file in the build
+	row      *source.Row
+	rows     int           // rows of the file read so far, this one included
+	complete bool          // set on the end of a file, the file was read to the end
+	analyzed chan struct{} // closed once the row is analyzed
+
+	docId     string
+	doc       *search_proto.Document
+	title     string
+	skipped   bool // the row is invalid or could not be encoded, it counts as skipped
+	foreign   bool // the document belongs to a shard of another worker
+	unchanged bool // the row did not change since it was indexed last
+	resumed   bool // the row was indexed before the checkpoint, only its title goes to the query suggestions
+}
+
+// pipeline builds the index in three stages: one reader, options.Parallelism analyzers tokenizing and encoding the rows,
+// and one writer applying them in batches with Bulk. The writer takes the rows in the order they were read, so a later
+// row with the same id wins as in a serial build. The queues are bounded, a slow writer holds back the reader.
+type pipeline struct {
+	files       []string
+	indexer     IIndexer
+	options     BuildOptions
+	docSchema   *schema.Schema
+	titleField  string
+	parallelism int
+	batchSize   int
+	report      *BuildReport
+
+	analyze  chan *buildItem // rows waiting for an analyzer
+	ordered  chan *buildItem // rows in the order they were read, waiting for the writer
+	complete bool            // every file was read to the end, set by the reader
+	failed   bool            // a batch or the suggestions of a file could not be written, set by the writer which checkpoints no further
+	readWait time.Duration   // time the reader was blocked by full queues
+	analysis int64           // nanoseconds spent analyzing, summed over the analyzers
+}
+
+// build runs the pipeline over files and returns true if every file was read to the end and written
+func build(files []string, indexer IIndexer, options BuildOptions, report *BuildReport) bool {
+	docSchema := options.Schema
+	if docSchema == nil {
+		docSchema = schema.ProductSchema()
+	}
+	for _, name := range append([]string{options.IdField}, options.IdFields...) {
+		if _, exists := docSchema.Field(name); len(name) > 0 && !exists {
+			log.Printf("id field %s is not a field of schema %s", name, docSchema.Name)
+			return false
+		}
+	}
+
+	p := &pipeline{
+		files:       files,
+		indexer:     indexer,
+		options:     options,
+		docSchema:   docSchema,
+		titleField:  firstTextField(docSchema),
+		parallelism: options.Parallelism,
+		batchSize:   options.BatchSize,
+		report:      report,
+		complete:    true,
+	}
+	if p.parallelism <= 0 {
+		p.parallelism = runtime.NumCPU()
+	}
+	if p.batchSize <= 0 {
+		p.batchSize = BULK_BATCH_SIZE
+	}
+	// the analyzers run at most one batch ahead of the writer
+	p.analyze = make(chan *buildItem, p.parallelism)
+	p.ordered = make(chan *buildItem, p.batchSize)
+
+	start := time.Now()
+	wg := sync.WaitGroup{}
+	wg.Add(p.parallelism)
+	for i := 0; i < p.parallelism; i++ {
+		go func() {
+			defer wg.Done()
+			p.analyzeRows()
+		}()
+	}
+	go p.read()
+	p.write()
+	wg.Wait()
+
+	report.Elapsed = time.Since(start)
+	report.ReadWait = p.readWait
+	report.AnalyzeTime = time.Duration(atomic.LoadInt64(&p.analysis))
+	report.Parallelism = p.parallelism
+	report.Complete = p.complete && !p.failed
+
+	return report.Complete
+}
+
+// send queues item for the writer, and for an analyzer unless it is the end of a file
+func (p *pipeline) send(item *buildItem) {
+	start := time.Now()
+	p.ordered <- item
+	if item.row != nil {
+		p.analyze <- item
+	}
+	p.readWait += time.Since(start)
+}
+
+func (p *pipeline) read() {
+	defer close(p.ordered)
+	defer close(p.analyze)
+
+	for i, sourceFile := range p.files {
+		log.Printf("start to build index from file: %s", sourceFile)
+		complete := p.readFile(i, sourceFile)
+		p.complete = p.complete && complete
+	}
+}
+
+// readFile queues the rows of a file after its last checkpoint, and then the end of the file
+func (p *pipeline) readFile(file int, sourceFile string) bool {
+	// files are checkpointed by name, the data directory may move between runs
+	skip := 0
+	if p.options.Checkpoints != nil {
+		rows, done := p.options.Checkpoints.Progress(filepath.Base(sourceFile))
+		if done {
+			log.Printf("file %s was built before", sourceFile)
+			return true
+		}
+		if rows > 0 {
+			log.Printf("resume building %s after row %d", sourceFile, rows)
+		}
+		skip = rows
+	}
+
+	end := &buildItem{file: file, analyzed: make(chan struct{})}
+	close(end.analyzed)
+	defer p.send(end)
+
+	reader, err := source.Open(sourceFile, p.docSchema, p.options.Mapping)
+	if err != nil {
+		log.Printf("open file %s failed: %s", sourceFile, err)
+		return false
+	}
+	defer reader.Close()
+
+	for {
+		row, err := reader.Read()
+		if err != nil {
+			if err != io.EOF {
+				log.Printf("read %s failed: %s", sourceFile, err)
+				return false
+			}
+			end.complete = true
+			return true
+		}
+		end.rows++
+		// rows indexed before the last checkpoint are only analyzed for the query suggestions of the file
+		p.send(&buildItem{file: file, row: row, rows: end.rows, resumed: end.rows <= skip, analyzed: make(chan struct{})})
+	}
+}
+
+func (p *pipeline) analyzeRows() {
+	for item := range p.analyze {
+		start := time.Now()
+		p.analyzeRow(item)
+		atomic.AddInt64(&p.analysis, int64(time.Since(start)))
+		close(item.analyzed)
+	}
+}
+
+// analyzeRow derives the id of the row and encodes it, the expensive tokenization happens here
+func (p *pipeline) analyzeRow(item *buildItem) {
+	options := p.options
+	row := item.row
+	// values that could not be converted are left out, or the row is skipped in strict mode
+	if row.Doc == nil || len(row.Errors) > 0 && options.Mapping != nil && options.Mapping.Strict {
+		item.skipped = true
+		return
+	}
+	doc := row.Doc
+	item.docId = DocumentId(doc, row.Values, options)
+	if len(item.docId) == 0 {
+		log.Printf("skip row %s:%d without id", p.files[item.file], row.Line)
+		item.skipped = true
+		return
+	}
+
+	// the shard map is read for every row, a rebalance may move shards while a watched directory is ingested
+	if shardMap := options.ShardMap(); shardMap != nil && !shardMap.Receives(shardMap.Shard(item.docId), options.Worker) {
+		log.Printf("skip document %s of shard %d", item.docId, shardMap.Shard(item.docId))
+		item.foreign = true
+		return
+	}
+	doc[schema.ID_FIELD] = item.docId
+	item.title = doc.String(p.titleField)
+	if item.resumed {
+		return
+	}
+	if options.Changed != nil && !options.Changed(item.docId, row.Values) {
+		item.unchanged = true
+		return
+	}
+	if vectorField, exists := p.docSchema.VectorField(); exists && doc[vectorField.Name] == nil {
+		if vector, exists := options.Embeddings[strings.ToLower(strings.TrimSpace(item.title))]; exists {
+			doc[vectorField.Name] = vector
+		}
+	}
+
+	indexDoc, err := IndexDocument(doc, p.docSchema, options.Categories)
+	if err != nil {
+		log.Printf("encode document %s failed: %s", item.docId, err)
+		item.skipped = true
+		return
+	}
+	item.doc = indexDoc
+}
+
+// write applies the rows in the order they were read, a file is written and checkpointed before the next one starts
+func (p *pipeline) write() {
+	report := p.report
+	batch := make([]*buildItem, 0, p.batchSize)
+	queryTrie := trie.NewTrie()
+	for item := range p.ordered {
+		<-item.analyzed
+		sourceFile := p.files[item.file]
+		if item.row == nil {
+			p.flush(batch, sourceFile, item.rows)
+			batch = batch[:0]
+			if item.complete && !p.failed {
+				if err := p.finishFile(sourceFile, queryTrie); err != nil {
+					// the file is not marked done, the build resumes it
+					log.Printf("finish file %s failed: %s", sourceFile, err)
+					p.failed = true
+				}
+			}
+			queryTrie = trie.NewTrie()
+			continue
+		}
+		if item.resumed {
+			if len(item.title) > 0 {
+				queryTrie.Insert(item.title)
+			}
+			continue
+		}
+
+		report.Rows++
+		for _, rowErr := range item.row.Errors {
+			report.error(sourceFile, item.row.Line, rowErr)
+		}
+		if item.skipped {
+			report.Skipped++
+			continue
+		}
+		if item.foreign {
+			continue
+		}
+		// the suggestions of a file are stored as a whole, unchanged rows keep theirs
+		if len(item.title) > 0 {
+			queryTrie.Insert(item.title)
+		}
+		if item.unchanged {
+			report.Unchanged++
+			continue
+		}
+		report.see(item.docId, sourceFile, item.row.Line)
+
+		batch = append(batch, item)
+		if len(batch) == p.batchSize {
+			p.flush(batch, sourceFile, item.rows)
+			batch = batch[:0]
+		}
+	}
+}
+
+// flush writes a batch with one Bulk call, then checkpoints the first rows of the file as indexed
+func (p *pipeline) flush(batch []*buildItem, sourceFile string, rows int) {
+	report := p.report
+	if len(batch) > 0 {
+		operations := make([]*index.BulkOperation, 0, len(batch))
+		for _, item := range batch {
+			operations = append(operations, &index.BulkOperation{Doc: item.doc})
+		}
+
+		start := time.Now()
+		results := p.indexer.Bulk(operations)
+		report.WriteTime += time.Since(start)
+		for i, result := range results {
+			if len(result.Error) > 0 {
+				log.Printf("add document %s failed: %s", batch[i].docId, result.Error)
+				report.Skipped++
+				p.failed = true
+				continue
+			}
+			if result.Replaced {
+				report.Replaced++
+			}
+			report.Documents++
+		}
+		report.Batches++
+		logger.Log.Printf("processed %d documents, %.0f rows/s", report.Documents, report.RowsPerSecond())
+		if p.options.Progress != nil {
+			p.options.Progress(report, sourceFile)
+		}
+	}
+
+	// the rows of a failed batch are built again when the build resumes
+	if p.options.Checkpoints != nil && !p.failed {
+		if err := p.options.Checkpoints.Save(filepath.Base(sourceFile), rows); err != nil {
+			log.Printf("save checkpoint of %s failed: %s", sourceFile, err)
+		}
+	}
+}
+
+// finishFile stores the query suggestions of a file read to the end and marks it done
+func (p *pipeline) finishFile(sourceFile string, queryTrie *trie.Trie) error {
+	err := withTrieDB(p.options, func(trieDB *storage.TrieDB) error {
+		return trieDB.StoreTrie(filepath.Base(sourceFile), queryTrie)
+	})
+	if err != nil {
+		return fmt.Errorf("store query suggestions failed: %w", err)
+	}
+
+	logger.Log.Printf("finish building index from file %s", sourceFile)
+	if p.options.Checkpoints != nil {
+		if err := p.options.Checkpoints.FinishFile(filepath.Base(sourceFile)); err != nil {
+			log.Printf("save checkpoint of %s failed: %s", sourceFile, err)
+		}
+	}
+	return nil
+}
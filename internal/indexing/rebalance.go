@@ -0,0 +1,399 @@
+package indexing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"slices"
+	"sort"
+	"time"
+
+	index "github.com/m1i3k0e7/distributed-search-engine/api/proto/index"
+	"github.com/m1i3k0e7/distributed-search-engine/pkg/logger"
+	etcdv3 "go.etcd.io/etcd/client/v3"
+)
+
+const (
+	REBALANCE_SETTLE   = time.Minute     // the alive workers must stay the same this long before shards move, a restarting worker keeps its shards
+	REBALANCE_ATTEMPTS = 3               // copies of a shard before the rebalance gives up moving it
+	REBALANCE_DRAIN    = 5 * time.Second // time for the web servers to see the new map before the removed replicas are cleared
+)
+
+// shardMove is the copy of a shard from an alive replica to a new replica
+type shardMove struct {
+	shard  int
+	source string
+	target string
+}
+
+// Rebalancer moves shards between the workers when workers join or leave. The new replicas of a shard first get its writes
+// as pending replicas, then its documents are streamed from an old replica with Transfer and written with Bulk. The map only
+// cuts over to the new replicas once the count and the digest of every copied shard match its source, in every collection.
+type Rebalancer struct {
+	sentinel *Sentinel // connections to the workers
+	client   *etcdv3.Client
+}
+
+func NewRebalancer(etcdServers []string) (*Rebalancer, error) {
+	client, err := etcdv3.New(etcdv3.Config{Endpoints: etcdServers, DialTimeout: 3 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	return &Rebalancer{sentinel: NewSentinel(etcdServers, DEFAULT_COLLECTION), client: client}, nil
+}
+
+// workers returns the alive workers in a stable order, every worker hosts the default collection
+func (rebalancer *Rebalancer) workers() []string {
+	workers := append([]string(nil), rebalancer.sentinel.hub.GetServiceEndpoints(rebalancer.sentinel.service)...)
+	sort.Strings(workers)
+	return workers
+}
+
+// Watch rebalances the shards once the alive workers changed and stayed the same for REBALANCE_SETTLE, until stop is closed.
+// A rebalance that failed or left shards on their old replicas is tried again every interval.
+func (rebalancer *Rebalancer) Watch(interval time.Duration, stop chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var last []string
+	changed := time.Now()
+	balanced := false
+	for {
+		if workers := rebalancer.workers(); !slices.Equal(workers, last) {
+			last, changed, balanced = workers, time.Now(), false
+		}
+		if !balanced && len(last) > 0 && time.Since(changed) >= REBALANCE_SETTLE {
+			if _, err := rebalancer.Rebalance(); err != nil {
+				logger.Log.Printf("rebalance failed: %s", err)
+			} else {
+				balanced = true
+			}
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Rebalance spreads the replicas of the shards evenly over the alive workers and returns the number of shard copies made
+func (rebalancer *Rebalancer) Rebalance() (int, error) {
+	workers := rebalancer.workers()
+	if len(workers) == 0 {
+		return 0, fmt.Errorf("there is no alive index worker")
+	}
+	ctx := context.Background()
+	current, err := LoadShardMap(ctx, rebalancer.client)
+	if err != nil {
+		return 0, err
+	}
+	if current == nil {
+		return 0, fmt.Errorf("there is no shard map, no worker claimed shards yet")
+	}
+
+	next := planShards(current, workers)
+	moves := make([]shardMove, 0)
+	changed := make([]int, 0) // shards whose replicas change
+	orphans := 0              // shards to move without an alive replica to copy from, they keep their replicas
+	for shard, replicas := range next.Replicas {
+		if slices.Equal(replicas, current.Replicas[shard]) {
+			continue
+		}
+		sources := aliveReplicas(current.Replicas[shard], workers, nil)
+		if len(sources) == 0 {
+			logger.Log.Printf("shard %d has no alive replica to copy from, it keeps its replicas", shard)
+			orphans++
+			continue
+		}
+		changed = append(changed, shard)
+		for _, target := range replicas {
+			if !current.Hosts(shard, target) {
+				moves = append(moves, shardMove{shard: shard, source: sources[rand.Intn(len(sources))], target: target})
+			}
+		}
+	}
+	if len(changed) == 0 {
+		if orphans > 0 {
+			return 0, fmt.Errorf("%d shards have no alive replica", orphans)
+		}
+		logger.Log.Printf("the shards are balanced over %d workers", len(workers))
+		return 0, nil
+	}
+	logger.Log.Printf("rebalance %d shards over %d workers, %d copies", len(changed), len(workers), len(moves))
+
+	// the new replicas get the writes of their shards from now on, so the copies miss none
+	pending := NewShardMap(current.Shards, current.Replication)
+	for _, move := range moves {
+		pending.Pending[move.shard] = append(pending.Pending[move.shard], move.target)
+	}
+	current.Pending = pending.Pending
+	saved, err := SaveShardMap(ctx, rebalancer.client, current)
+	if err != nil {
+		return 0, err
+	}
+	if !saved {
+		return 0, fmt.Errorf("the shard map changed meanwhile, rebalance again")
+	}
+
+	failed := make(map[int]bool) // shards that keep their replicas
+	collections, err := listCollections(rebalancer.client)
+	if err != nil {
+		// no shard moves, the cutover only drops the pending replicas
+		for _, shard := range changed {
+			failed[shard] = true
+		}
+		if cutoverErr := rebalancer.cutover(next, changed, failed); cutoverErr != nil {
+			logger.Log.Printf("drop pending replicas failed: %s", cutoverErr)
+		}
+		return 0, err
+	}
+	for _, move := range moves {
+		for _, collection := range collections {
+			if err := rebalancer.copyShard(collection, move, current.Shards); err != nil {
+				logger.Log.Printf("copy shard %d of collection %s to worker %s failed: %s", move.shard, collection, move.target, err)
+				failed[move.shard] = true
+				break
+			}
+		}
+	}
+
+	if err := rebalancer.cutover(next, changed, failed); err != nil {
+		return 0, err
+	}
+
+	// the replicas that lost a shard drop its documents once the web servers read from the new replicas
+	time.Sleep(REBALANCE_DRAIN)
+	for _, shard := range changed {
+		if failed[shard] {
+			continue
+		}
+		for _, worker := range aliveReplicas(current.Replicas[shard], workers, nil) {
+			if next.Hosts(shard, worker) {
+				continue
+			}
+			for _, collection := range collections {
+				ctx := WithCollection(context.Background(), collection)
+				if err := rebalancer.clear(ctx, worker, shardFilterOf(current.Shards, shard)); err != nil {
+					logger.Log.Printf("clear shard %d of collection %s on worker %s failed: %s", shard, collection, worker, err)
+				}
+			}
+		}
+	}
+
+	logger.Log.Printf("rebalance done, %d shards moved, %d kept their replicas", len(changed)-len(failed), len(failed)+orphans)
+	if len(failed) > 0 || orphans > 0 {
+		return len(moves), fmt.Errorf("%d shards kept their replicas", len(failed)+orphans)
+	}
+	return len(moves), nil
+}
+
+// cutover gives the changed shards their new replicas in one update of the map, the failed shards keep their replicas
+func (rebalancer *Rebalancer) cutover(next *ShardMap, changed []int, failed map[int]bool) error {
+	ctx := context.Background()
+	for {
+		shardMap, err := LoadShardMap(ctx, rebalancer.client)
+		if err != nil {
+			return err
+		}
+		for _, shard := range changed {
+			if !failed[shard] {
+				shardMap.Replicas[shard] = next.Replicas[shard]
+			}
+			shardMap.Pending[shard] = nil
+		}
+		saved, err := SaveShardMap(ctx, rebalancer.client, shardMap)
+		if err != nil {
+			return err
+		}
+		if saved {
+			return nil
+		}
+	}
+}
+
+// copyShard copies the documents of a shard of collection from the source to the target of move and verifies the copy.
+// A copy is made again from scratch if it does not match the source, a write may have raced with it.
+func (rebalancer *Rebalancer) copyShard(collection string, move shardMove, shards int) error {
+	if len(move.source) == 0 {
+		return fmt.Errorf("shard %d has no alive replica to copy from", move.shard)
+	}
+	ctx := WithCollection(context.Background(), collection)
+	filter := shardFilterOf(shards, move.shard)
+
+	for attempt := 1; ; attempt++ {
+		err := rebalancer.copyOnce(ctx, move, filter)
+		if err == nil {
+			logger.Log.Printf("copied shard %d of collection %s from worker %s to %s", move.shard, collection, move.source, move.target)
+			return nil
+		}
+		if attempt == REBALANCE_ATTEMPTS {
+			return err
+		}
+		logger.Log.Printf("copy shard %d of collection %s again: %s", move.shard, collection, err)
+	}
+}
+
+func (rebalancer *Rebalancer) copyOnce(ctx context.Context, move shardMove, filter *index.ShardFilter) error {
+	// the target may keep documents of the shard from an earlier time it hosted it
+	if err := rebalancer.clear(ctx, move.target, filter); err != nil {
+		return err
+	}
+	err := rebalancer.transfer(ctx, move.source, filter, false, func(chunk *index.TransferChunk) error {
+		operations := make([]*index.BulkOperation, 0, len(chunk.Docs))
+		for _, doc := range chunk.Docs {
+			operations = append(operations, &index.BulkOperation{Doc: doc})
+		}
+		return rebalancer.write(ctx, move.target, operations)
+	})
+	if err != nil {
+		return err
+	}
+
+	sourceCount, sourceDigest, err := rebalancer.digest(ctx, move.source, filter)
+	if err != nil {
+		return err
+	}
+	targetCount, targetDigest, err := rebalancer.digest(ctx, move.target, filter)
+	if err != nil {
+		return err
+	}
+	if sourceCount != targetCount || sourceDigest != targetDigest {
+		return fmt.Errorf("the copy has %d documents of digest %x, the source %d of digest %x", targetCount, targetDigest, sourceCount, sourceDigest)
+	}
+	return nil
+}
+
+// clear deletes the documents of the shards of filter on worker
+func (rebalancer *Rebalancer) clear(ctx context.Context, worker string, filter *index.ShardFilter) error {
+	return rebalancer.transfer(ctx, worker, filter, false, func(chunk *index.TransferChunk) error {
+		operations := make([]*index.BulkOperation, 0, len(chunk.Docs))
+		for _, doc := range chunk.Docs {
+			operations = append(operations, &index.BulkOperation{DeleteId: doc.Id})
+		}
+		return rebalancer.write(ctx, worker, operations)
+	})
+}
+
+func (rebalancer *Rebalancer) digest(ctx context.Context, worker string, filter *index.ShardFilter) (int32, uint64, error) {
+	var count int32
+	var digest uint64
+	err := rebalancer.transfer(ctx, worker, filter, true, func(chunk *index.TransferChunk) error {
+		count, digest = chunk.Count, chunk.Digest
+		return nil
+	})
+	return count, digest, err
+}
+
+// transfer streams the documents of the shards of filter from worker to receive, chunk by chunk, the last chunk included
+func (rebalancer *Rebalancer) transfer(ctx context.Context, worker string, filter *index.ShardFilter, digestOnly bool, receive func(chunk *index.TransferChunk) error) error {
+	conn := rebalancer.sentinel.GetGrpcConn(worker)
+	if conn == nil {
+		return fmt.Errorf("connect to worker %s failed", worker)
+	}
+	stream, err := index.NewIndexServiceClient(conn).Transfer(ctx, &index.TransferRequest{Shards: filter, DigestOnly: digestOnly})
+	if err != nil {
+		return err
+	}
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := receive(chunk); err != nil {
+			return err
+		}
+	}
+}
+
+func (rebalancer *Rebalancer) write(ctx context.Context, worker string, operations []*index.BulkOperation) error {
+	if len(operations) == 0 {
+		return nil
+	}
+	results, err := rebalancer.sentinel.bulk(ctx, worker, operations)
+	if err != nil {
+		return err
+	}
+	for _, result := range results {
+		if len(result.Error) > 0 {
+			return fmt.Errorf("worker %s: %s", worker, result.Error)
+		}
+	}
+	return nil
+}
+
+func (rebalancer *Rebalancer) Close() error {
+	rebalancer.sentinel.Close()
+	return rebalancer.client.Close()
+}
+
+func shardFilterOf(shards int, shard int) *index.ShardFilter {
+	return &index.ShardFilter{Shards: int32(shards), Ids: []int32{int32(shard)}}
+}
+
+// planShards keeps the replicas on alive workers, gives the shards missing replicas to the least loaded workers, then moves
+// replicas from the most to the least loaded workers until their loads differ by one at most. Few shards move.
+func planShards(current *ShardMap, workers []string) *ShardMap {
+	next := NewShardMap(current.Shards, current.Replication)
+	load := make(map[string]int, len(workers))
+	for _, worker := range workers {
+		load[worker] = 0
+	}
+	for shard, replicas := range current.Replicas {
+		for _, replica := range replicas {
+			if _, alive := load[replica]; alive {
+				next.Replicas[shard] = append(next.Replicas[shard], replica)
+				load[replica]++
+			}
+		}
+	}
+
+	replication := min(next.Replication, len(workers))
+	for shard := range next.Replicas {
+		for len(next.Replicas[shard]) < replication {
+			var least string
+			for _, worker := range workers {
+				if !next.Hosts(shard, worker) && (len(least) == 0 || load[worker] < load[least]) {
+					least = worker
+				}
+			}
+			next.Replicas[shard] = append(next.Replicas[shard], least)
+			load[least]++
+		}
+	}
+
+	for {
+		most, least := workers[0], workers[0]
+		for _, worker := range workers {
+			if load[worker] > load[most] {
+				most = worker
+			}
+			if load[worker] < load[least] {
+				least = worker
+			}
+		}
+		if load[most]-load[least] <= 1 {
+			return next
+		}
+		moved := false
+		for shard, replicas := range next.Replicas {
+			if !next.Hosts(shard, most) || next.Hosts(shard, least) {
+				continue
+			}
+			replicas = append([]string(nil), replicas...)
+			replicas[slices.Index(replicas, most)] = least
+			next.Replicas[shard] = replicas
+			load[most]--
+			load[least]++
+			moved = true
+			break
+		}
+		if !moved {
+			return next
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package indexing
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	etcdv3 "go.etcd.io/etcd/client/v3"
+)
+
+// checkPlan checks that every shard of plan has distinct replicas on workers and that the loads differ by one at most
+func checkPlan(t *testing.T, plan *ShardMap, workers []string, replication int) map[string]int {
+	load := make(map[string]int, len(workers))
+	for shard, replicas := range plan.Replicas {
+		if len(replicas) != replication {
+			t.Errorf("expect %d replicas of shard %d, got %v", replication, shard, replicas)
+		}
+		for i, replica := range replicas {
+			if !slices.Contains(workers, replica) || slices.Index(replicas, replica) != i {
+				t.Errorf("invalid replicas %v of shard %d", replicas, shard)
+			}
+			load[replica]++
+		}
+	}
+	most, least := 0, len(plan.Replicas)
+	for _, worker := range workers {
+		most, least = max(most, load[worker]), min(least, load[worker])
+	}
+	if most-least > 1 {
+		t.Errorf("expect the loads to differ by one at most, got %v", load)
+	}
+	return load
+}
+
+// moved counts the replicas of plan that current does not have
+func moved(current *ShardMap, plan *ShardMap) int {
+	n := 0
+	for shard, replicas := range plan.Replicas {
+		for _, replica := range replicas {
+			if !current.Hosts(shard, replica) {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func TestPlanShards(t *testing.T) {
+	current := NewShardMap(12, 2)
+	workers := []string{"a:1", "b:1", "c:1"}
+	for i, worker := range workers {
+		current.claim(worker, i, len(workers))
+	}
+	if plan := planShards(current, workers); moved(current, plan) != 0 {
+		t.Errorf("expect a balanced map to stay the same, got %v", plan.Replicas)
+	}
+
+	// a joining worker takes a quarter of the replicas, no other replica moves
+	joined := append(slices.Clone(workers), "d:1")
+	plan := planShards(current, joined)
+	if load := checkPlan(t, plan, joined, 2); load["d:1"] != 6 {
+		t.Errorf("expect the new worker to host 6 replicas, got %v", load)
+	}
+	if n := moved(current, plan); n != 6 {
+		t.Errorf("expect 6 replicas to move, got %d", n)
+	}
+	for shard, replicas := range plan.Replicas {
+		if !slices.ContainsFunc(replicas, func(replica string) bool { return current.Hosts(shard, replica) }) {
+			t.Errorf("expect shard %d to keep a replica, got %v", shard, replicas)
+		}
+	}
+
+	// the replicas of a worker that left go to the others, the replicas on alive workers stay
+	left := []string{"a:1", "c:1"}
+	plan = planShards(current, left)
+	checkPlan(t, plan, left, 2)
+	if n := moved(current, plan); n != 8 {
+		t.Errorf("expect the 8 replicas of the worker that left to move, got %d", n)
+	}
+
+	// a single worker hosts one replica of every shard
+	plan = planShards(current, []string{"b:1"})
+	checkPlan(t, plan, []string{"b:1"}, 1)
+}
+
+func TestCutover(t *testing.T) {
+	rebalancer := &Rebalancer{client: &etcdv3.Client{KV: &memKV{values: make(map[string]*mvccpb.KeyValue)}}}
+	ctx := context.Background()
+	current := NewShardMap(4, 1)
+	current.Replicas = [][]string{{"a:1"}, {"b:1"}, {"a:1"}, {"b:1"}}
+	current.Pending[1] = []string{"c:1"}
+	current.Pending[3] = []string{"c:1"}
+	if saved, err := SaveShardMap(ctx, rebalancer.client, current); !saved || err != nil {
+		t.Fatalf("save shard map failed: %v %v", saved, err)
+	}
+
+	next := NewShardMap(4, 1)
+	next.Replicas = [][]string{{"a:1"}, {"c:1"}, {"a:1"}, {"c:1"}}
+	if err := rebalancer.cutover(next, []int{1, 3}, map[int]bool{3: true}); err != nil {
+		t.Fatal(err)
+	}
+	shardMap, _ := LoadShardMap(ctx, rebalancer.client)
+	expect := [][]string{{"a:1"}, {"c:1"}, {"a:1"}, {"b:1"}}
+	for shard := range expect {
+		if !slices.Equal(shardMap.Replicas[shard], expect[shard]) || len(shardMap.Pending[shard]) > 0 {
+			t.Errorf("expect shard %d on %v without pending replicas, got %v and %v", shard, expect[shard], shardMap.Replicas[shard], shardMap.Pending[shard])
+		}
+	}
+}
+
+func TestRebalanceWithoutSource(t *testing.T) {
+	rebalancer := &Rebalancer{
+		sentinel: &Sentinel{hub: &fakeHub{endpoints: []string{"a:1", "b:1"}}},
+		client:   &etcdv3.Client{KV: &memKV{values: make(map[string]*mvccpb.KeyValue)}},
+	}
+	ctx := context.Background()
+	current := NewShardMap(2, 1)
+	current.Replicas = [][]string{{"a:1"}, {"c:1"}} // c:1 left with the only replica of shard 1
+	SaveShardMap(ctx, rebalancer.client, current)
+	before, _ := LoadShardMap(ctx, rebalancer.client)
+
+	if _, err := rebalancer.Rebalance(); err == nil {
+		t.Errorf("expect a shard without an alive replica to fail the rebalance")
+	}
+	after, _ := LoadShardMap(ctx, rebalancer.client)
+	if after.revision != before.revision || !slices.Equal(after.Replicas[1], []string{"c:1"}) {
+		t.Errorf("expect the shard map to stay the same, got %v at revision %d", after.Replicas, after.revision)
+	}
+	if err := rebalancer.copyShard(DEFAULT_COLLECTION, shardMove{shard: 1, target: "b:1"}, 2); err == nil {
+		t.Errorf("expect a copy without source to fail")
+	}
+}
@@ -0,0 +1,83 @@
+package schema
+
+import (
+	"strings"
+
+	search_proto "github.com/m1i3k0e7/distributed-search-engine/api/proto/search"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/category"
+	"github.com/m1i3k0e7/distributed-search-engine/pkg/preprocessing"
+)
+
+const (
+	ANALYZER_STANDARD   = "standard"   // tokenize, remove stop words and stem, the same as search queries
+	ANALYZER_WHITESPACE = "whitespace" // split on white space
+	ANALYZER_KEYWORD    = "keyword"    // every value is one term
+)
+
+var analyzers = map[string]func(string) []string{
+	ANALYZER_STANDARD:   preprocessing.PreprocessForLargeDataset,
+	ANALYZER_WHITESPACE: strings.Fields,
+	ANALYZER_KEYWORD:    func(value string) []string { return []string{value} },
+}
+
+// Tokens returns the lower-cased terms of a field of doc, by the analyzer of the field
+func (schema *Schema) Tokens(doc Document, name string) []string {
+	field, exists := schema.Field(name)
+	if !exists {
+		return nil
+	}
+
+	analyze := analyzers[field.Analyzer]
+	tokens := make([]string, 0, 8)
+	for _, value := range doc.Strings(name) {
+		for _, token := range analyze(value) {
+			token = strings.ToLower(strings.TrimSpace(token))
+			if len(token) > 0 {
+				tokens = append(tokens, token)
+			}
+		}
+	}
+
+	return tokens
+}
+
+// Keywords returns the inverted index keywords of all indexed fields of doc, without duplicates
+func (schema *Schema) Keywords(doc Document) []*search_proto.Keyword {
+	keywords := make([]*search_proto.Keyword, 0, 16)
+	seen := make(map[string]struct{}, 16)
+	for _, field := range schema.Fields {
+		if !field.Indexed || field.Type == TYPE_VECTOR {
+			continue
+		}
+		tokens := schema.Tokens(doc, field.Name)
+		if field.Type != TYPE_TEXT && field.Type != TYPE_KEYWORD {
+			tokens = []string{strings.ToLower(doc.String(field.Name))}
+		}
+		for _, token := range tokens {
+			if _, exists := seen[token]; !exists && len(token) > 0 {
+				seen[token] = struct{}{}
+				keywords = append(keywords, &search_proto.Keyword{Field: CONTENT_FIELD, Word: token})
+			}
+		}
+	}
+
+	return keywords
+}
+
+// CategoryPath returns the category path of doc, e.g. "appliances/Air Conditioners", empty if the schema has no categories
+func (schema *Schema) CategoryPath(doc Document) string {
+	levels := make([]string, 0, len(schema.Categories))
+	for _, name := range schema.Categories {
+		levels = append(levels, doc.String(name))
+	}
+
+	return category.Path(levels...)
+}
+
+// Vector returns the embedding of doc, nil if the schema has no vector field
+func (schema *Schema) Vector(doc Document) []float32 {
+	if field, exists := schema.VectorField(); exists {
+		return doc.Vector(field.Name)
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+package schema
+
+import (
+	"fmt"
+
+	_ "github.com/m1i3k0e7/distributed-search-engine/api/proto/search" // registers the message types a schema can be stored as
+	proto "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func messageType(name string) (protoreflect.MessageType, error) {
+	messageType, err := protoregistry.GlobalTypes.FindMessageByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, fmt.Errorf("unknown message %s: %w", name, err)
+	}
+	return messageType, nil
+}
+
+// Marshal serializes the id and the stored fields of doc into Document.Bytes,
+// as the proto message of the schema if there is one, as a google.protobuf.Struct otherwise
+func (schema *Schema) Marshal(doc Document) ([]byte, error) {
+	if len(schema.Message) > 0 {
+		messageType, err := messageType(schema.Message)
+		if err != nil {
+			return nil, err
+		}
+		message := messageType.New()
+		for name, value := range schema.stored(doc) {
+			fd := message.Descriptor().Fields().ByName(protoreflect.Name(name))
+			if fd == nil {
+				continue // the message has no such field
+			}
+			if err := setField(message, fd, value); err != nil {
+				return nil, err
+			}
+		}
+		return proto.Marshal(message.Interface())
+	}
+
+	fields := make(map[string]any, len(doc))
+	for name, value := range schema.stored(doc) {
+		switch v := value.(type) {
+		case []string:
+			values := make([]any, 0, len(v))
+			for _, item := range v {
+				values = append(values, item)
+			}
+			fields[name] = values
+		case []float32:
+			values := make([]any, 0, len(v))
+			for _, item := range v {
+				values = append(values, float64(item))
+			}
+			fields[name] = values
+		default:
+			fields[name] = value
+		}
+	}
+	message, err := structpb.NewStruct(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	return proto.Marshal(message)
+}
+
+// Unmarshal is the reverse of Marshal, it returns the id and the stored fields of the schema
+func (schema *Schema) Unmarshal(bs []byte) (Document, error) {
+	fields := make(map[string]any, len(schema.Fields)+1)
+	if len(schema.Message) > 0 {
+		messageType, err := messageType(schema.Message)
+		if err != nil {
+			return nil, err
+		}
+		message := messageType.New()
+		if err := proto.Unmarshal(bs, message.Interface()); err != nil {
+			return nil, err
+		}
+		fds := message.Descriptor().Fields()
+		for i := 0; i < fds.Len(); i++ {
+			fields[string(fds.Get(i).Name())] = getField(message, fds.Get(i))
+		}
+	} else {
+		message := new(structpb.Struct)
+		if err := proto.Unmarshal(bs, message); err != nil {
+			return nil, err
+		}
+		fields = message.AsMap()
+	}
+
+	doc := make(Document, len(schema.Fields)+1)
+	if id, ok := fields[ID_FIELD].(string); ok {
+		doc[ID_FIELD] = id
+	}
+	for _, field := range schema.Fields {
+		value, exists := fields[field.Name]
+		if !exists || !field.Stored {
+			continue
+		}
+		if converted, err := field.Convert(value); err == nil {
+			doc[field.Name] = converted
+		}
+	}
+
+	return doc, nil
+}
+
+// stored returns the id and the stored fields of doc
+func (schema *Schema) stored(doc Document) Document {
+	stored := make(Document, len(doc))
+	for name, value := range doc {
+		if name == ID_FIELD {
+			stored[name] = value
+			continue
+		}
+		if field, exists := schema.Field(name); exists && field.Stored {
+			stored[name] = value
+		}
+	}
+	return stored
+}
+
+func setField(message protoreflect.Message, fd protoreflect.FieldDescriptor, value any) error {
+	if fd.IsList() {
+		list := message.Mutable(fd).List()
+		switch v := value.(type) {
+		case []string:
+			for _, item := range v {
+				list.Append(protoreflect.ValueOfString(item))
+			}
+		case []float32:
+			for _, item := range v {
+				list.Append(protoreflect.ValueOfFloat32(item))
+			}
+		case string:
+			list.Append(protoreflect.ValueOfString(v))
+		default:
+			return fmt.Errorf("field %s can not hold %T", fd.Name(), value)
+		}
+		return nil
+	}
+
+	var v protoreflect.Value
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		v = protoreflect.ValueOfString(fmt.Sprint(value))
+	case protoreflect.BoolKind:
+		b, _ := value.(bool)
+		v = protoreflect.ValueOfBool(b)
+	case protoreflect.DoubleKind, protoreflect.FloatKind, protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Int64Kind, protoreflect.Sint64Kind:
+		var f float64
+		switch n := value.(type) {
+		case float64:
+			f = n
+		case int64:
+			f = float64(n)
+		default:
+			return fmt.Errorf("field %s can not hold %T", fd.Name(), value)
+		}
+		switch fd.Kind() {
+		case protoreflect.DoubleKind:
+			v = protoreflect.ValueOfFloat64(f)
+		case protoreflect.FloatKind:
+			v = protoreflect.ValueOfFloat32(float32(f))
+		case protoreflect.Int32Kind, protoreflect.Sint32Kind:
+			v = protoreflect.ValueOfInt32(int32(f))
+		default:
+			v = protoreflect.ValueOfInt64(int64(f))
+		}
+	default:
+		return fmt.Errorf("field %s has unsupported kind %s", fd.Name(), fd.Kind())
+	}
+	message.Set(fd, v)
+
+	return nil
+}
+
+func getField(message protoreflect.Message, fd protoreflect.FieldDescriptor) any {
+	value := message.Get(fd)
+	if fd.IsList() {
+		list := value.List()
+		switch fd.Kind() {
+		case protoreflect.StringKind:
+			values := make([]string, 0, list.Len())
+			for i := 0; i < list.Len(); i++ {
+				values = append(values, list.Get(i).String())
+			}
+			return values
+		case protoreflect.FloatKind, protoreflect.DoubleKind:
+			values := make([]float32, 0, list.Len())
+			for i := 0; i < list.Len(); i++ {
+				values = append(values, float32(list.Get(i).Float()))
+			}
+			return values
+		default:
+			return nil
+		}
+	}
+
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return value.String()
+	case protoreflect.BoolKind:
+		return value.Bool()
+	case protoreflect.DoubleKind, protoreflect.FloatKind:
+		return value.Float()
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Int64Kind, protoreflect.Sint64Kind:
+		return value.Int()
+	default:
+		return nil
+	}
+}
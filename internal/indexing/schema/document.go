@@ -0,0 +1,199 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Document is a schema-driven document. Values have the canonical type of their field:
+// string for text, string or []string for keyword, int64, float64, bool and []float32 for vector.
+type Document map[string]any
+
+func (doc Document) Id() string {
+	id, _ := doc[ID_FIELD].(string)
+	return id
+}
+
+func (doc Document) String(field string) string {
+	switch value := doc[field].(type) {
+	case string:
+		return value
+	case []string:
+		return strings.Join(value, " ")
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// Strings returns the values of a keyword field, a single value is returned as a list of one
+func (doc Document) Strings(field string) []string {
+	switch value := doc[field].(type) {
+	case []string:
+		return value
+	case string:
+		return []string{value}
+	default:
+		return nil
+	}
+}
+
+// Float returns a numeric field as float64, false if the field is missing or not numeric
+func (doc Document) Float(field string) (float64, bool) {
+	switch value := doc[field].(type) {
+	case float64:
+		return value, true
+	case int64:
+		return float64(value), true
+	default:
+		return 0, false
+	}
+}
+
+func (doc Document) Vector(field string) []float32 {
+	vector, _ := doc[field].([]float32)
+	return vector
+}
+
+// NewDocument converts the values of fields to the canonical types of the schema, e.g. after json decoding.
+// Unknown fields are rejected, null values are missing values.
+func (schema *Schema) NewDocument(fields map[string]any) (Document, error) {
+	doc := make(Document, len(fields))
+	for name, value := range fields {
+		if value == nil {
+			if _, exists := schema.Field(name); !exists && name != ID_FIELD {
+				return nil, fmt.Errorf("unknown field %s", name)
+			}
+			continue
+		}
+		if name == ID_FIELD {
+			id, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s must be a string", ID_FIELD)
+			}
+			doc[ID_FIELD] = id
+			continue
+		}
+
+		field, exists := schema.Field(name)
+		if !exists {
+			return nil, fmt.Errorf("unknown field %s", name)
+		}
+		converted, err := field.Convert(value)
+		if err != nil {
+			return nil, err
+		}
+		doc[name] = converted
+	}
+
+	return doc, nil
+}
+
+// FromRecord maps a csv record to the fields of the schema by position, vector fields are skipped.
+// Values that cannot be parsed are left out.
+func (schema *Schema) FromRecord(record []string) Document {
+	doc := make(Document, len(schema.Fields))
+	column := 0
+	for i := range schema.Fields {
+		field := &schema.Fields[i]
+		if field.Type == TYPE_VECTOR {
+			continue
+		}
+		if column >= len(record) {
+			break
+		}
+		if value, err := field.Convert(record[column]); err == nil {
+			doc[field.Name] = value
+		}
+		column++
+	}
+
+	return doc
+}
+
+// Convert returns value in the canonical type of the field. Strings are parsed for numeric and bool fields.
+// A nil value, or a nil item of a list, is an error.
+func (field *Field) Convert(value any) (any, error) {
+	if value == nil {
+		return nil, fmt.Errorf("field %s can not be null", field.Name)
+	}
+	if number, ok := value.(json.Number); ok {
+		value = number.String()
+	}
+
+	switch field.Type {
+	case TYPE_TEXT:
+		return fmt.Sprint(value), nil
+	case TYPE_KEYWORD:
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case []string:
+			return v, nil
+		case []any:
+			values := make([]string, 0, len(v))
+			for _, item := range v {
+				if item == nil {
+					return nil, fmt.Errorf("field %s can not hold null", field.Name)
+				}
+				values = append(values, fmt.Sprint(item))
+			}
+			return values, nil
+		default:
+			return fmt.Sprint(v), nil
+		}
+	case TYPE_INT:
+		switch v := value.(type) {
+		case int64:
+			return v, nil
+		case int:
+			return int64(v), nil
+		case int32:
+			return int64(v), nil
+		case float64:
+			return int64(v), nil
+		case string:
+			return strconv.ParseInt(strings.ReplaceAll(strings.TrimSpace(v), ",", ""), 10, 64)
+		}
+	case TYPE_FLOAT:
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case float32:
+			return float64(v), nil
+		case int64:
+			return float64(v), nil
+		case int:
+			return float64(v), nil
+		case string:
+			return strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(v), ",", ""), 64)
+		}
+	case TYPE_BOOL:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			return strconv.ParseBool(strings.TrimSpace(v))
+		}
+	case TYPE_VECTOR:
+		switch v := value.(type) {
+		case []float32:
+			return v, nil
+		case []any:
+			vector := make([]float32, 0, len(v))
+			for _, item := range v {
+				f, ok := item.(float64)
+				if !ok {
+					return nil, fmt.Errorf("field %s must be a list of numbers", field.Name)
+				}
+				vector = append(vector, float32(f))
+			}
+			return vector, nil
+		}
+	}
+
+	return nil, fmt.Errorf("field %s of type %s can not hold %T", field.Name, field.Type, value)
+}
@@ -0,0 +1,207 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/m1i3k0e7/distributed-search-engine/pkg/ranking"
+)
+
+type FieldType string
+
+const (
+	TYPE_TEXT    FieldType = "text"    // free text, split into terms by the analyzer
+	TYPE_KEYWORD FieldType = "keyword" // exact value or list of values, e.g. tags
+	TYPE_INT     FieldType = "int"
+	TYPE_FLOAT   FieldType = "float"
+	TYPE_BOOL    FieldType = "bool"
+	TYPE_VECTOR  FieldType = "vector" // embedding, goes to the vector index instead of the inverted index
+)
+
+const (
+	ID_FIELD      = "Id"      // every document has an id, it is not declared in Fields
+	CONTENT_FIELD = "content" // Keyword.Field of the terms of all indexed fields
+)
+
+// Field describes how one field of a document is indexed and stored
+type Field struct {
+	Name     string
+	Type     FieldType
+	Analyzer string // how the field is split into terms, default ANALYZER_STANDARD for text and ANALYZER_KEYWORD otherwise
+	Indexed  bool   // terms of the field go to the inverted index
+	Stored   bool   // the field is kept with the document and returned in search results
+}
+
+// Schema defines the fields of the documents in an index
+type Schema struct {
+	Name       string
+	Message    string               // optional full name of the proto message documents are stored as, e.g. "search.Product"
+	Fields     []Field
+	Categories []string             // text fields holding the levels of the category path, top down
+	Ranking    *ranking.BM25FConfig // BM25F field weights, every indexed text or keyword field weighs 1 if nil
+
+	fields map[string]*Field
+}
+
+// LoadSchema reads a schema definition from a json file
+func LoadSchema(schemaFile string) (*Schema, error) {
+	bs, err := os.ReadFile(schemaFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseSchema(bs)
+}
+
+// ParseSchema decodes and checks a json schema definition
+func ParseSchema(bs []byte) (*Schema, error) {
+	schema := new(Schema)
+	if err := json.Unmarshal(bs, schema); err != nil {
+		return nil, err
+	}
+	if err := schema.Init(); err != nil {
+		return nil, err
+	}
+
+	return schema, nil
+}
+
+// Init fills default analyzers and checks the schema, it must be called before a schema built by hand is used
+func (schema *Schema) Init() error {
+	schema.fields = make(map[string]*Field, len(schema.Fields))
+	for i := range schema.Fields {
+		field := &schema.Fields[i]
+		if field.Name == ID_FIELD {
+			return fmt.Errorf("field %s is reserved", ID_FIELD)
+		}
+		if _, exists := schema.fields[field.Name]; exists {
+			return fmt.Errorf("duplicate field %s", field.Name)
+		}
+		switch field.Type {
+		case TYPE_TEXT, TYPE_KEYWORD, TYPE_INT, TYPE_FLOAT, TYPE_BOOL, TYPE_VECTOR:
+		default:
+			return fmt.Errorf("field %s has unknown type %q", field.Name, field.Type)
+		}
+		if len(field.Analyzer) == 0 {
+			field.Analyzer = ANALYZER_KEYWORD
+			if field.Type == TYPE_TEXT {
+				field.Analyzer = ANALYZER_STANDARD
+			}
+		}
+		if _, exists := analyzers[field.Analyzer]; !exists {
+			return fmt.Errorf("field %s has unknown analyzer %q", field.Name, field.Analyzer)
+		}
+		schema.fields[field.Name] = field
+	}
+
+	for _, name := range schema.Categories {
+		if field, exists := schema.fields[name]; !exists || field.Type != TYPE_TEXT && field.Type != TYPE_KEYWORD {
+			return fmt.Errorf("category field %s is not a text field of the schema", name)
+		}
+	}
+	if len(schema.Message) > 0 {
+		if _, err := messageType(schema.Message); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Field returns the definition of a field, false if the schema does not have it
+func (schema *Schema) Field(name string) (*Field, bool) {
+	field, exists := schema.fields[name]
+	return field, exists
+}
+
+// VectorField returns the first vector field, false if documents have no embedding
+func (schema *Schema) VectorField() (*Field, bool) {
+	for i := range schema.Fields {
+		if schema.Fields[i].Type == TYPE_VECTOR {
+			return &schema.Fields[i], true
+		}
+	}
+	return nil, false
+}
+
+// RankingConfig returns the BM25F config of the schema
+func (schema *Schema) RankingConfig() ranking.BM25FConfig {
+	if schema.Ranking != nil {
+		return schema.Ranking.WithWeights(nil) // copy, callers may modify it
+	}
+
+	config := ranking.BM25FConfig{K1: ranking.DefaultBM25FConfig().K1, Fields: make(map[string]ranking.FieldConfig)}
+	for _, field := range schema.Fields {
+		if field.Indexed && (field.Type == TYPE_TEXT || field.Type == TYPE_KEYWORD) {
+			config.Fields[field.Name] = ranking.FieldConfig{Weight: 1, B: 0.75}
+		}
+	}
+
+	return config
+}
+
+// ProductSchema is the schema of the Amazon products dataset, documents are stored as search.Product
+func ProductSchema() *Schema {
+	config := ranking.DefaultBM25FConfig()
+	schema := &Schema{
+		Name:    "product",
+		Message: "search.Product",
+		Fields: []Field{
+			{Name: "Name", Type: TYPE_TEXT, Indexed: true, Stored: true},
+			{Name: "Category", Type: TYPE_TEXT, Stored: true},
+			{Name: "SubCategory", Type: TYPE_TEXT, Stored: true},
+			{Name: "Image", Type: TYPE_KEYWORD, Stored: true},
+			{Name: "Link", Type: TYPE_KEYWORD}, // column of the dataset, not kept
+			{Name: "Ratings", Type: TYPE_FLOAT, Stored: true},
+			{Name: "NoRatings", Type: TYPE_INT, Stored: true},
+			{Name: "DiscountPrice", Type: TYPE_FLOAT, Stored: true},
+			{Name: "ActualPrice", Type: TYPE_FLOAT, Stored: true},
+			{Name: "Keywords", Type: TYPE_KEYWORD, Stored: true}, // terms of Name, ranked but not indexed twice
+			{Name: "Embedding", Type: TYPE_VECTOR},
+		},
+		Categories: []string{"Category", "SubCategory"},
+		Ranking:    &config,
+	}
+	if err := schema.Init(); err != nil {
+		panic(err)
+	}
+
+	return schema
+}
+
+// VideoSchema is the schema of bilibili videos, documents are stored as demo.BiliVideo
+func VideoSchema() *Schema {
+	schema := &Schema{
+		Name:    "video",
+		Message: "demo.BiliVideo",
+		Fields: []Field{
+			{Name: "Title", Type: TYPE_TEXT, Indexed: true, Stored: true},
+			{Name: "PostTime", Type: TYPE_INT, Stored: true},
+			{Name: "Author", Type: TYPE_KEYWORD, Indexed: true, Stored: true},
+			{Name: "View", Type: TYPE_INT, Stored: true},
+			{Name: "Like", Type: TYPE_INT, Stored: true},
+			{Name: "Coin", Type: TYPE_INT, Stored: true},
+			{Name: "Favorite", Type: TYPE_INT, Stored: true},
+			{Name: "Share", Type: TYPE_INT, Stored: true},
+			{Name: "Keywords", Type: TYPE_KEYWORD, Indexed: true, Stored: true},
+		},
+	}
+	if err := schema.Init(); err != nil {
+		panic(err)
+	}
+
+	return schema
+}
+
+// GetSchema returns a built-in schema by name ("product" or "video"), or loads the schema file otherwise
+func GetSchema(nameOrFile string) (*Schema, error) {
+	switch nameOrFile {
+	case "", "product":
+		return ProductSchema(), nil
+	case "video":
+		return VideoSchema(), nil
+	default:
+		return LoadSchema(nameOrFile)
+	}
+}
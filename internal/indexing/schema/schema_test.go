@@ -0,0 +1,100 @@
+package schema
+
+import (
+	"testing"
+
+	search_proto "github.com/m1i3k0e7/distributed-search-engine/api/proto/search"
+	proto "google.golang.org/protobuf/proto"
+)
+
+func TestProductRoundTrip(t *testing.T) {
+	schema := ProductSchema()
+	doc := schema.FromRecord([]string{"Lloyd 1.5 Ton Inverter AC", "appliances", "Air Conditioners", "img.jpg", "link", "4.2", "2,255", "32999", "58990"})
+	doc[ID_FIELD] = "p1"
+	doc["Embedding"] = []float32{1, 2}
+
+	bs, err := schema.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var product search_proto.Product
+	if err := proto.Unmarshal(bs, &product); err != nil {
+		t.Fatal(err)
+	}
+	if product.Id != "p1" || product.SubCategory != "Air Conditioners" || product.NoRatings != 2255 || product.DiscountPrice != 32999 || len(product.Embedding) > 0 {
+		t.Errorf("unexpected product %v", &product)
+	}
+
+	decoded, err := schema.Unmarshal(bs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Id() != "p1" || decoded["NoRatings"] != int64(2255) || decoded.String("Name") != "Lloyd 1.5 Ton Inverter AC" {
+		t.Errorf("unexpected document %v", decoded)
+	}
+	if _, exists := decoded["Embedding"]; exists {
+		t.Errorf("vector field is not stored")
+	}
+	if path := schema.CategoryPath(decoded); path != "appliances/Air Conditioners" {
+		t.Errorf("unexpected category path %s", path)
+	}
+}
+
+func TestGenericRoundTrip(t *testing.T) {
+	schema := &Schema{
+		Name: "book",
+		Fields: []Field{
+			{Name: "Title", Type: TYPE_TEXT, Indexed: true, Stored: true},
+			{Name: "Tags", Type: TYPE_KEYWORD, Indexed: true, Stored: true},
+			{Name: "Pages", Type: TYPE_INT, Stored: true},
+			{Name: "Note", Type: TYPE_TEXT},
+		},
+	}
+	if err := schema.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := schema.NewDocument(map[string]any{"Id": "b1", "Title": "Go", "Tags": []any{"Programming", "golang"}, "Pages": 300.0, "Note": "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keywords := schema.Keywords(doc); len(keywords) != 3 || keywords[1].Word != "programming" {
+		t.Errorf("unexpected keywords %v", keywords)
+	}
+
+	bs, err := schema.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := schema.Unmarshal(bs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Id() != "b1" || decoded["Pages"] != int64(300) || len(decoded.Strings("Tags")) != 2 {
+		t.Errorf("unexpected document %v", decoded)
+	}
+	if _, exists := decoded["Note"]; exists {
+		t.Errorf("field Note is not stored")
+	}
+
+	if _, err := schema.NewDocument(map[string]any{"Author": "x"}); err == nil {
+		t.Errorf("unknown field should be rejected")
+	}
+}
+
+func TestNewDocumentNull(t *testing.T) {
+	schema := ProductSchema()
+	doc, err := schema.NewDocument(map[string]any{ID_FIELD: "p1", "Name": nil, "Ratings": nil, "Category": "appliances"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := doc["Name"]; exists || doc["Category"] != "appliances" {
+		t.Errorf("expect null values to be missing, got %v", doc)
+	}
+	if _, err := schema.NewDocument(map[string]any{"Unknown": nil}); err == nil {
+		t.Errorf("expect an unknown field to be rejected even if null")
+	}
+	if _, err := schema.NewDocument(map[string]any{"Keywords": []any{"a", nil}}); err == nil {
+		t.Errorf("expect a null item of a list to be rejected")
+	}
+}
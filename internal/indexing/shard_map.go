@@ -0,0 +1,243 @@
+package indexing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	farmhash "github.com/leemcloughlin/gofarmhash"
+	"github.com/m1i3k0e7/distributed-search-engine/pkg/logger"
+	etcdv3 "go.etcd.io/etcd/client/v3"
+)
+
+const (
+	SHARD_MAP_KEY = "/radic/shardmap" // json ShardMap of the cluster, shared by all collections since every worker hosts every collection
+	SHARD_COUNT   = 64                // virtual shards of a new cluster, more than workers so that shards can move one by one
+)
+
+// ShardMap assigns every virtual shard to the workers hosting its replicas. A document belongs to shard farmhash(id) % Shards
+// for the lifetime of the cluster, so the build of a worker, the ingestion coordinator and the sentinels agree on its workers.
+type ShardMap struct {
+	Shards      int
+	Replication int        // replicas of every shard, fewer while not enough workers claimed it
+	Replicas    [][]string // shard -> endpoints of the workers hosting it, in the order they claimed it
+	Pending     [][]string // shard -> workers a rebalance copies it to, they get the writes of the shard but no reads yet
+
+	revision int64 // mod revision of the map in etcd, 0 if it is not stored
+}
+
+func NewShardMap(shards int, replication int) *ShardMap {
+	return &ShardMap{Shards: shards, Replication: max(replication, 1), Replicas: make([][]string, shards), Pending: make([][]string, shards)}
+}
+
+// Shard returns the virtual shard of a document
+func (shardMap *ShardMap) Shard(docId string) int {
+	return int(farmhash.Hash32WithSeed([]byte(docId), 0) % uint32(shardMap.Shards))
+}
+
+// Hosts tells whether worker hosts a replica of shard
+func (shardMap *ShardMap) Hosts(shard int, worker string) bool {
+	for _, replica := range shardMap.Replicas[shard] {
+		if replica == worker {
+			return true
+		}
+	}
+	return false
+}
+
+// Receives tells whether worker hosts a replica of shard or gets a copy of it
+func (shardMap *ShardMap) Receives(shard int, worker string) bool {
+	for _, replica := range shardMap.Pending[shard] {
+		if replica == worker {
+			return true
+		}
+	}
+	return shardMap.Hosts(shard, worker)
+}
+
+// ShardsOf returns the shards hosted by worker
+func (shardMap *ShardMap) ShardsOf(worker string) []int {
+	shards := make([]int, 0)
+	for shard := range shardMap.Replicas {
+		if shardMap.Hosts(shard, worker) {
+			shards = append(shards, shard)
+		}
+	}
+	return shards
+}
+
+// claim adds worker to the replicas of the shards it should host and returns the number of shards it claimed
+func (shardMap *ShardMap) claim(worker string, workerIndex int, totalWorkers int) int {
+	claimed := 0
+	for shard, replicas := range shardMap.Replicas {
+		ring := (workerIndex - shard%totalWorkers + totalWorkers) % totalWorkers // position of the worker after the first replica
+		if ring < shardMap.Replication && len(replicas) < shardMap.Replication && !shardMap.Hosts(shard, worker) {
+			shardMap.Replicas[shard] = append(replicas, worker)
+			claimed++
+		}
+	}
+	return claimed
+}
+
+// LoadShardMap reads the shard map of the cluster, nil if no worker claimed shards yet
+func LoadShardMap(ctx context.Context, client *etcdv3.Client) (*ShardMap, error) {
+	response, err := client.Get(ctx, SHARD_MAP_KEY)
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Kvs) == 0 {
+		return nil, nil
+	}
+	return parseShardMap(response.Kvs[0].Value, response.Kvs[0].ModRevision)
+}
+
+func parseShardMap(bs []byte, revision int64) (*ShardMap, error) {
+	var shardMap ShardMap
+	if err := json.Unmarshal(bs, &shardMap); err != nil {
+		return nil, err
+	}
+	if shardMap.Shards <= 0 || len(shardMap.Replicas) != shardMap.Shards {
+		return nil, fmt.Errorf("invalid shard map of %d shards and %d replica sets", shardMap.Shards, len(shardMap.Replicas))
+	}
+	if len(shardMap.Pending) != shardMap.Shards {
+		shardMap.Pending = make([][]string, shardMap.Shards)
+	}
+	shardMap.revision = revision
+	return &shardMap, nil
+}
+
+// SaveShardMap stores shardMap if nobody changed the map in etcd since it was loaded, it returns false otherwise
+func SaveShardMap(ctx context.Context, client *etcdv3.Client, shardMap *ShardMap) (bool, error) {
+	bs, err := json.Marshal(shardMap)
+	if err != nil {
+		return false, err
+	}
+	response, err := client.Txn(ctx).
+		If(etcdv3.Compare(etcdv3.ModRevision(SHARD_MAP_KEY), "=", shardMap.revision)).
+		Then(etcdv3.OpPut(SHARD_MAP_KEY, string(bs))).
+		Commit()
+	if err != nil {
+		return false, err
+	}
+	if response.Succeeded {
+		shardMap.revision = response.Header.Revision
+	}
+	return response.Succeeded, nil
+}
+
+// ShardRouter caches the shard map of the cluster and follows its changes in etcd, it is shared by all sentinels
+type ShardRouter struct {
+	client   *etcdv3.Client
+	shardMap *ShardMap
+	lock     sync.RWMutex
+}
+
+var (
+	shardRouter *ShardRouter
+	routerOnce  sync.Once // single instance only
+)
+
+// GetShardRouter returns the shard router of the process, connected to etcd on the first call
+func GetShardRouter(etcdServers []string) *ShardRouter {
+	routerOnce.Do(func() {
+		client, err := etcdv3.New(etcdv3.Config{Endpoints: etcdServers, DialTimeout: 3 * time.Second})
+		if err != nil {
+			logger.Log.Fatalf("Failed to connect to etcd server: %v", err)
+		}
+		shardRouter = &ShardRouter{client: client}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		response, err := client.Get(ctx, SHARD_MAP_KEY)
+		if err != nil {
+			logger.Log.Printf("load shard map failed: %s", err)
+			go shardRouter.watch(0)
+			return
+		}
+		if len(response.Kvs) > 0 {
+			shardRouter.update(response.Kvs[0].Value, response.Kvs[0].ModRevision)
+		}
+		go shardRouter.watch(response.Header.Revision + 1)
+	})
+
+	return shardRouter
+}
+
+func (router *ShardRouter) update(bs []byte, revision int64) {
+	shardMap, err := parseShardMap(bs, revision)
+	if err != nil {
+		logger.Log.Printf("parse shard map failed: %s", err)
+		return
+	}
+	router.offer(shardMap)
+}
+
+func (router *ShardRouter) watch(revision int64) {
+	options := []etcdv3.OpOption{}
+	if revision > 0 {
+		options = append(options, etcdv3.WithRev(revision))
+	}
+	for response := range router.client.Watch(context.Background(), SHARD_MAP_KEY, options...) {
+		for _, event := range response.Events {
+			if event.Type == etcdv3.EventTypePut {
+				router.update(event.Kv.Value, event.Kv.ModRevision)
+			}
+		}
+	}
+}
+
+// ShardMap returns the current shard map, nil if no worker claimed shards yet. It must not be modified.
+func (router *ShardRouter) ShardMap() *ShardMap {
+	router.lock.RLock()
+	defer router.lock.RUnlock()
+	return router.shardMap
+}
+
+// offer replaces the cached map with shardMap if it is newer, so the map a worker just saved is used before the watch reports it
+func (router *ShardRouter) offer(shardMap *ShardMap) {
+	router.lock.Lock()
+	defer router.lock.Unlock()
+	if router.shardMap == nil || router.shardMap.revision < shardMap.revision {
+		router.shardMap = shardMap
+	}
+}
+
+// Claim makes worker a replica of the shards s whose replicas are the workers s % totalWorkers, s % totalWorkers + 1 and so on,
+// as long as the shard has fewer replicas than the replication of the map. The first claim of the cluster creates the map of
+// SHARD_COUNT shards with replication, later claims keep the replication of the map. A shard keeps its replicas once assigned.
+func (router *ShardRouter) Claim(worker string, workerIndex int, totalWorkers int, replication int) (*ShardMap, error) {
+	if totalWorkers <= 0 || workerIndex < 0 || workerIndex >= totalWorkers {
+		return nil, fmt.Errorf("invalid worker index %d of %d workers", workerIndex, totalWorkers)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for {
+		shardMap, err := LoadShardMap(ctx, router.client)
+		if err != nil {
+			return nil, err
+		}
+		if shardMap == nil {
+			shardMap = NewShardMap(SHARD_COUNT, replication)
+		} else if shardMap.Replication != replication {
+			logger.Log.Printf("the shard map has %d replicas of every shard, replication %d is ignored", shardMap.Replication, replication)
+		}
+
+		claimed := shardMap.claim(worker, workerIndex, totalWorkers)
+		if claimed == 0 {
+			router.offer(shardMap)
+			return shardMap, nil
+		}
+		saved, err := SaveShardMap(ctx, router.client, shardMap)
+		if err != nil {
+			return nil, err
+		}
+		if saved {
+			logger.Log.Printf("worker %s claimed %d shards", worker, claimed)
+			router.offer(shardMap)
+			return shardMap, nil
+		}
+	}
+}
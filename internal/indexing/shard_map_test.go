@@ -0,0 +1,208 @@
+package indexing
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+	"testing"
+
+	"go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	etcdv3 "go.etcd.io/etcd/client/v3"
+)
+
+func TestClaimShards(t *testing.T) {
+	shardMap := NewShardMap(12, 2)
+	workers := []string{"a:1", "b:1", "c:1"}
+	for i, worker := range workers {
+		if claimed := shardMap.claim(worker, i, len(workers)); claimed != 8 {
+			t.Errorf("expect %s to claim 8 shards, claimed %d", worker, claimed)
+		}
+	}
+	// worker i hosts the shards s with s % 3 == i or i - 1, wrapping around
+	for shard, replicas := range shardMap.Replicas {
+		expect := []string{workers[shard%3], workers[(shard+1)%3]}
+		slices.Sort(replicas)
+		slices.Sort(expect)
+		if !slices.Equal(replicas, expect) {
+			t.Errorf("expect shard %d on %v, got %v", shard, expect, replicas)
+		}
+	}
+
+	// a restarted worker keeps its shards, a joining worker finds every shard fully replicated
+	if claimed := shardMap.claim("b:1", 1, 3); claimed != 0 {
+		t.Errorf("expect a restarted worker to claim nothing, claimed %d", claimed)
+	}
+	if claimed := shardMap.claim("d:1", 3, 4); claimed != 0 {
+		t.Errorf("expect a joining worker to claim nothing, claimed %d", claimed)
+	}
+
+	// once the replicas of a worker that left are dropped, its replacement claims its shards
+	for shard, replicas := range shardMap.Replicas {
+		shardMap.Replicas[shard] = slices.DeleteFunc(replicas, func(replica string) bool { return replica == "b:1" })
+	}
+	if claimed := shardMap.claim("d:1", 1, 3); claimed != 8 {
+		t.Errorf("expect the replacement to claim 8 shards, claimed %d", claimed)
+	}
+	if !slices.Equal(shardMap.ShardsOf("d:1"), []int{0, 1, 3, 4, 6, 7, 9, 10}) {
+		t.Errorf("expect the replacement to host the shards of the worker that left, got %v", shardMap.ShardsOf("d:1"))
+	}
+}
+
+func TestShardMapRouting(t *testing.T) {
+	shardMap := NewShardMap(SHARD_COUNT, 1)
+	shardMap.claim("a:1", 0, 2)
+	shardMap.claim("b:1", 1, 2)
+	shardMap.Pending[3] = []string{"c:1"}
+
+	for _, docId := range []string{"1", "42", "product-7"} {
+		shard := shardMap.Shard(docId)
+		if shard != shardMap.Shard(docId) || shard < 0 || shard >= SHARD_COUNT {
+			t.Fatalf("invalid shard %d of %s", shard, docId)
+		}
+		owner := []string{"a:1", "b:1"}[shard%2]
+		if !shardMap.Hosts(shard, owner) || !shardMap.Receives(shard, owner) {
+			t.Errorf("expect %s to host shard %d of %s", owner, shard, docId)
+		}
+	}
+	if shardMap.Hosts(3, "c:1") || !shardMap.Receives(3, "c:1") || shardMap.Receives(4, "c:1") {
+		t.Errorf("expect a pending replica to receive the writes of its shard only, and host nothing")
+	}
+
+	// the json stored in etcd round trips, maps stored before Pending existed get empty pending replicas
+	bs, _ := json.Marshal(shardMap)
+	parsed, err := parseShardMap(bs, 7)
+	if err != nil || parsed.revision != 7 || !slices.Equal(parsed.ShardsOf("b:1"), shardMap.ShardsOf("b:1")) {
+		t.Errorf("expect the shard map to round trip, got %v %v", parsed, err)
+	}
+	old, err := parseShardMap([]byte(`{"Shards":2,"Replication":1,"Replicas":[["a:1"],["b:1"]]}`), 1)
+	if err != nil || len(old.Pending) != 2 {
+		t.Errorf("expect pending replicas for every shard, got %v %v", old, err)
+	}
+	if _, err := parseShardMap([]byte(`{"Shards":3,"Replicas":[["a:1"]]}`), 1); err == nil {
+		t.Errorf("expect a map missing replica sets to be rejected")
+	}
+}
+
+func TestRouterKeepsNewestMap(t *testing.T) {
+	router := &ShardRouter{}
+	newer := NewShardMap(4, 1)
+	newer.revision = 5
+	older := NewShardMap(4, 1)
+	older.revision = 3
+	router.offer(newer)
+	router.offer(older) // e.g. the watch reports a put the router already got from a save
+	if router.ShardMap() != newer {
+		t.Errorf("expect the router to keep the map of revision 5, got revision %d", router.ShardMap().revision)
+	}
+}
+
+// memKV keeps the keys of a fake etcd in memory, it implements the Get and the compare-and-put transactions of the shard map
+type memKV struct {
+	etcdv3.KV
+	lock     sync.Mutex
+	revision int64
+	values   map[string]*mvccpb.KeyValue
+}
+
+func (kv *memKV) Get(ctx context.Context, key string, opts ...etcdv3.OpOption) (*etcdv3.GetResponse, error) {
+	kv.lock.Lock()
+	defer kv.lock.Unlock()
+	response := &etcdv3.GetResponse{Header: &etcdserverpb.ResponseHeader{Revision: kv.revision}}
+	if value, exists := kv.values[key]; exists {
+		response.Kvs = append(response.Kvs, value)
+	}
+	return response, nil
+}
+
+func (kv *memKV) Txn(ctx context.Context) etcdv3.Txn {
+	return &memTxn{kv: kv}
+}
+
+type memTxn struct {
+	kv   *memKV
+	cmps []etcdv3.Cmp
+	ops  []etcdv3.Op
+}
+
+func (txn *memTxn) If(cmps ...etcdv3.Cmp) etcdv3.Txn { txn.cmps = cmps; return txn }
+func (txn *memTxn) Then(ops ...etcdv3.Op) etcdv3.Txn { txn.ops = ops; return txn }
+func (txn *memTxn) Else(ops ...etcdv3.Op) etcdv3.Txn { return txn }
+
+func (txn *memTxn) Commit() (*etcdv3.TxnResponse, error) {
+	kv := txn.kv
+	kv.lock.Lock()
+	defer kv.lock.Unlock()
+	for _, cmp := range txn.cmps {
+		var revision int64
+		if value, exists := kv.values[string(cmp.KeyBytes())]; exists {
+			revision = value.ModRevision
+		}
+		if revision != cmp.TargetUnion.(*etcdserverpb.Compare_ModRevision).ModRevision {
+			return &etcdv3.TxnResponse{Header: &etcdserverpb.ResponseHeader{Revision: kv.revision}}, nil
+		}
+	}
+	kv.revision++
+	for _, op := range txn.ops {
+		kv.values[string(op.KeyBytes())] = &mvccpb.KeyValue{Key: op.KeyBytes(), Value: op.ValueBytes(), ModRevision: kv.revision}
+	}
+	return &etcdv3.TxnResponse{Header: &etcdserverpb.ResponseHeader{Revision: kv.revision}, Succeeded: true}, nil
+}
+
+func TestSaveShardMapComparesRevision(t *testing.T) {
+	client := &etcdv3.Client{KV: &memKV{values: make(map[string]*mvccpb.KeyValue)}}
+	ctx := context.Background()
+	if saved, err := SaveShardMap(ctx, client, NewShardMap(4, 1)); !saved || err != nil {
+		t.Fatalf("expect the first map to be saved, got %v %v", saved, err)
+	}
+
+	first, _ := LoadShardMap(ctx, client)
+	second, _ := LoadShardMap(ctx, client)
+	first.claim("a:1", 0, 2)
+	second.claim("b:1", 1, 2)
+	if saved, err := SaveShardMap(ctx, client, first); !saved || err != nil {
+		t.Fatalf("expect a map saved at its revision to be stored, got %v %v", saved, err)
+	}
+	if saved, err := SaveShardMap(ctx, client, second); saved || err != nil {
+		t.Fatalf("expect a map loaded before the last save to be refused, got %v %v", saved, err)
+	}
+
+	second, _ = LoadShardMap(ctx, client)
+	second.claim("b:1", 1, 2)
+	if saved, _ := SaveShardMap(ctx, client, second); !saved {
+		t.Fatalf("expect the reloaded map to be saved")
+	}
+	stored, _ := LoadShardMap(ctx, client)
+	if !slices.Equal(stored.ShardsOf("a:1"), []int{0, 2}) || !slices.Equal(stored.ShardsOf("b:1"), []int{1, 3}) {
+		t.Errorf("expect both claims in the stored map, got %v", stored.Replicas)
+	}
+}
+
+func TestConcurrentClaims(t *testing.T) {
+	router := &ShardRouter{client: &etcdv3.Client{KV: &memKV{values: make(map[string]*mvccpb.KeyValue)}}}
+	workers := []string{"a:1", "b:1", "c:1", "d:1"}
+	wg := sync.WaitGroup{}
+	for i, worker := range workers {
+		wg.Add(1)
+		go func(i int, worker string) {
+			defer wg.Done()
+			if _, err := router.Claim(worker, i, len(workers), 2); err != nil {
+				t.Errorf("claim of %s failed: %s", worker, err)
+			}
+		}(i, worker)
+	}
+	wg.Wait()
+
+	shardMap := router.ShardMap()
+	for shard, replicas := range shardMap.Replicas {
+		if len(replicas) != 2 {
+			t.Errorf("expect 2 replicas of shard %d, got %v", shard, replicas)
+		}
+	}
+	for _, worker := range workers {
+		if hosted := len(shardMap.ShardsOf(worker)); hosted != SHARD_COUNT*2/len(workers) {
+			t.Errorf("expect %s to host %d shards, got %d", worker, SHARD_COUNT*2/len(workers), hosted)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package source
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	FORMAT_CSV   = "csv"
+	FORMAT_TSV   = "tsv"
+	FORMAT_JSONL = "jsonl" // one json object per line
+)
+
+// Mapping describes how the rows of a source file become documents
+type Mapping struct {
+	Format  string            // csv, tsv or jsonl, taken from the file extension if empty
+	Header  bool              // the first csv/tsv row names the columns, columns are mapped to schema fields by position otherwise
+	Columns map[string]string // column or json key -> field, columns named like a field (ignoring case) are mapped to it without an entry
+	Strict  bool              // rows with values that can not be converted are skipped, they are indexed without those values otherwise
+}
+
+// LoadMapping reads a mapping from a json file
+func LoadMapping(mappingFile string) (*Mapping, error) {
+	bs, err := os.ReadFile(mappingFile)
+	if err != nil {
+		return nil, err
+	}
+
+	mapping := new(Mapping)
+	if err := json.Unmarshal(bs, mapping); err != nil {
+		return nil, err
+	}
+	if len(mapping.Format) > 0 && !supportedFormat(mapping.Format) {
+		return nil, fmt.Errorf("unknown format %q", mapping.Format)
+	}
+
+	return mapping, nil
+}
+
+// ProductMapping maps the header of the Amazon products dataset to schema.ProductSchema
+func ProductMapping() *Mapping {
+	return &Mapping{
+		Format: FORMAT_CSV,
+		Header: true,
+		Columns: map[string]string{
+			"name":           "Name",
+			"main_category":  "Category",
+			"sub_category":   "SubCategory",
+			"image":          "Image",
+			"link":           "Link",
+			"ratings":        "Ratings",
+			"no_of_ratings":  "NoRatings",
+			"discount_price": "DiscountPrice",
+			"actual_price":   "ActualPrice",
+		},
+	}
+}
+
+// format returns the format of file, the extension decides unless the mapping names one
+func (mapping *Mapping) format(file string) string {
+	if mapping != nil && len(mapping.Format) > 0 {
+		return mapping.Format
+	}
+	return formatOf(file)
+}
+
+func formatOf(file string) string {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".csv":
+		return FORMAT_CSV
+	case ".tsv", ".tab":
+		return FORMAT_TSV
+	case ".jsonl", ".ndjson":
+		return FORMAT_JSONL
+	default:
+		return ""
+	}
+}
+
+func supportedFormat(format string) bool {
+	return format == FORMAT_CSV || format == FORMAT_TSV || format == FORMAT_JSONL
+}
+
+// Supported tells whether the extension of file is one of the source formats
+func Supported(file string) bool {
+	return len(formatOf(file)) > 0
+}
+
+// field returns the field a column is mapped to, empty if the column is not indexed
+func (mapping *Mapping) field(column string, fields []string) string {
+	if mapping != nil {
+		if field, exists := mapping.Columns[column]; exists {
+			return field
+		}
+	}
+	for _, field := range fields {
+		if strings.EqualFold(field, strings.TrimSpace(column)) {
+			return field
+		}
+	}
+	return ""
+}
@@ -0,0 +1,178 @@
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/schema"
+)
+
+const (
+	MAX_LINE = 16 << 20 // longest line of a jsonl file
+)
+
+// Row is a document read from a source file. Doc is nil if the row could not be parsed at all.
+type Row struct {
+	Line   int
+	Doc    schema.Document
+	Values []string // raw values of the row, the document id may be a hash of them
+	Errors []error  // values that could not be converted, or why the row could not be parsed
+}
+
+// IReader reads the rows of a source file one by one, Read returns io.EOF after the last row
+type IReader interface {
+	Read() (*Row, error)
+	Close() error
+}
+
+// Open returns a reader of file in the format of the mapping or the file extension.
+// mapping may be nil, csv/tsv columns are then mapped to the fields of the schema by position.
+func Open(file string, docSchema *schema.Schema, mapping *Mapping) (IReader, error) {
+	format := mapping.format(file)
+	if !supportedFormat(format) {
+		return nil, fmt.Errorf("unknown format of file %s", file)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(docSchema.Fields))
+	for _, field := range docSchema.Fields {
+		names = append(names, field.Name)
+	}
+
+	if format == FORMAT_JSONL {
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 0, 64<<10), MAX_LINE)
+		return &jsonlReader{file: f, scanner: scanner, schema: docSchema, mapping: mapping, fields: names}, nil
+	}
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = -1 // short rows are kept, their missing columns are missing fields
+	reader.LazyQuotes = true
+	if format == FORMAT_TSV {
+		reader.Comma = '\t'
+	}
+	csvReader := &csvReader{file: f, reader: reader, schema: docSchema}
+	if mapping != nil && mapping.Header {
+		header, err := reader.Read()
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("read header of %s failed: %w", file, err)
+		}
+		for _, column := range header {
+			csvReader.columns = append(csvReader.columns, mapping.field(column, names))
+		}
+	} else {
+		// by position, vector fields are not in the file
+		for _, field := range docSchema.Fields {
+			if field.Type != schema.TYPE_VECTOR {
+				csvReader.columns = append(csvReader.columns, field.Name)
+			}
+		}
+	}
+
+	return csvReader, nil
+}
+
+// set converts value to the type of the field, empty values are missing values
+func set(doc schema.Document, docSchema *schema.Schema, name string, value any, row *Row) {
+	field, exists := docSchema.Field(name)
+	if !exists || value == nil || value == "" {
+		return
+	}
+	converted, err := field.Convert(value)
+	if err != nil {
+		row.Errors = append(row.Errors, fmt.Errorf("field %s: %w", name, err))
+		return
+	}
+	doc[name] = converted
+}
+
+type csvReader struct {
+	file    *os.File
+	reader  *csv.Reader
+	schema  *schema.Schema
+	columns []string // field of each column, empty if the column is not mapped
+}
+
+func (reader *csvReader) Read() (*Row, error) {
+	record, err := reader.reader.Read()
+	var parseError *csv.ParseError
+	if errors.As(err, &parseError) {
+		return &Row{Line: parseError.StartLine, Errors: []error{err}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	line, _ := reader.reader.FieldPos(0) // FieldPos panics unless the last Read returned a record
+	row := &Row{Line: line, Values: record}
+	row.Doc = make(schema.Document, len(reader.columns))
+	for i, value := range record {
+		if i < len(reader.columns) && len(reader.columns[i]) > 0 {
+			set(row.Doc, reader.schema, reader.columns[i], value, row)
+		}
+	}
+
+	return row, nil
+}
+
+func (reader *csvReader) Close() error {
+	return reader.file.Close()
+}
+
+type jsonlReader struct {
+	file    *os.File
+	scanner *bufio.Scanner
+	schema  *schema.Schema
+	mapping *Mapping
+	fields  []string
+	line    int
+}
+
+func (reader *jsonlReader) Read() (*Row, error) {
+	for reader.scanner.Scan() {
+		reader.line++
+		line := bytes.TrimSpace(reader.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		row := &Row{Line: reader.line, Values: []string{string(line)}}
+		var object map[string]any
+		if err := json.Unmarshal(line, &object); err != nil {
+			row.Errors = append(row.Errors, err)
+			return row, nil
+		}
+		row.Doc = make(schema.Document, len(object))
+		for key, value := range object {
+			if key == schema.ID_FIELD {
+				if id, ok := value.(string); ok {
+					row.Doc[schema.ID_FIELD] = id
+				}
+				continue
+			}
+			if name := reader.mapping.field(key, reader.fields); len(name) > 0 {
+				set(row.Doc, reader.schema, name, value, row)
+			}
+		}
+		return row, nil
+	}
+	if err := reader.scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, io.EOF
+}
+
+func (reader *jsonlReader) Close() error {
+	return reader.file.Close()
+}
@@ -0,0 +1,79 @@
+package source
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/schema"
+)
+
+func readAll(t *testing.T, file string, content string, mapping *Mapping) []*Row {
+	path := filepath.Join(t.TempDir(), file)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	reader, err := Open(path, schema.ProductSchema(), mapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	rows := make([]*Row, 0)
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return rows
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows = append(rows, row)
+	}
+}
+
+func TestCsvHeader(t *testing.T) {
+	rows := readAll(t, "products.csv", "ratings,name,main_category,no_of_ratings\n4.2,Lloyd AC,appliances,\"2,255\"\nGet,Short row\n", ProductMapping())
+	if len(rows) != 2 {
+		t.Fatalf("expect 2 rows, got %d", len(rows))
+	}
+	if doc := rows[0].Doc; doc["Name"] != "Lloyd AC" || doc["Ratings"] != 4.2 || doc["NoRatings"] != int64(2255) || len(rows[0].Errors) > 0 {
+		t.Errorf("unexpected row %v %v", doc, rows[0].Errors)
+	}
+	// the unparsable rating is reported and left out, the short row is kept
+	if doc := rows[1].Doc; rows[1].Line != 3 || len(rows[1].Errors) != 1 || doc["Name"] != "Short row" || doc["Ratings"] != nil {
+		t.Errorf("unexpected row %d %v %v", rows[1].Line, doc, rows[1].Errors)
+	}
+}
+
+func TestTsvByPosition(t *testing.T) {
+	rows := readAll(t, "products.tsv", "Lloyd AC\tappliances\tAir Conditioners\n", nil)
+	if len(rows) != 1 || rows[0].Doc["SubCategory"] != "Air Conditioners" {
+		t.Errorf("unexpected rows %v", rows)
+	}
+}
+
+func TestJsonl(t *testing.T) {
+	rows := readAll(t, "products.jsonl", "{\"Id\": \"p1\", \"name\": \"Lloyd AC\", \"Embedding\": [1, 2], \"unknown\": 1}\n\nnot json\n", nil)
+	if len(rows) != 2 {
+		t.Fatalf("expect 2 rows, got %d", len(rows))
+	}
+	if doc := rows[0].Doc; doc.Id() != "p1" || doc["Name"] != "Lloyd AC" || len(doc.Vector("Embedding")) != 2 {
+		t.Errorf("unexpected document %v", doc)
+	}
+	if rows[1].Doc != nil || rows[1].Line != 3 || len(rows[1].Errors) != 1 {
+		t.Errorf("unexpected row %v", rows[1])
+	}
+}
+
+func TestEmptyFiles(t *testing.T) {
+	for _, file := range []string{"empty.csv", "empty.tsv", "empty.jsonl"} {
+		if rows := readAll(t, file, "", nil); len(rows) != 0 {
+			t.Errorf("expect no rows in %s, got %d", file, len(rows))
+		}
+	}
+	if rows := readAll(t, "header.csv", "ratings,name\n", ProductMapping()); len(rows) != 0 {
+		t.Errorf("expect no rows below the header, got %d", len(rows))
+	}
+}
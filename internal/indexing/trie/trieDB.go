@@ -3,12 +3,15 @@ package storage
 import (
 	"bytes"
 	"log"
+	"slices"
 
 	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/kvdb"
 	"github.com/m1i3k0e7/distributed-search-engine/pkg/trie"
 )
 
+const MAX_ASSOCIATED_QUERIES = 10 // as many as one trie suggests
 
+// TrieDB keeps one trie of query suggestions per source file
 type TrieDB struct {
 	db kvdb.IKeyValueDB
 	path string
@@ -33,7 +36,8 @@ func NewTrieDB(dbPath string) (*TrieDB, error) {
 	return trieDB, nil
 }
 
-func (t *TrieDB) StoreTrie(trie *trie.Trie) error {
+// StoreTrie stores the query suggestions of a source file, they replace the suggestions stored for the file before
+func (t *TrieDB) StoreTrie(file string, trie *trie.Trie) error {
 	if trie == nil {
 		return nil
 	}
@@ -43,69 +47,86 @@ func (t *TrieDB) StoreTrie(trie *trie.Trie) error {
 		return err
 	}
 
-	err = t.db.Set([]byte("trie"), trieJson)
+	err = t.db.Set([]byte(file), trieJson)
 
 	return err
 }
 
+// DeleteTrie deletes the query suggestions of a source file that is gone
+func (t *TrieDB) DeleteTrie(file string) error {
+	return t.db.Delete([]byte(file))
+}
+
+// AssociateQuery combines the suggestions of the tries of all files
 func (t *TrieDB) AssociateQuery(query string) ([]string, error) {
-	value, err := t.db.Get([]byte("trie"))
+	associatedQueries := make([]string, 0)
+	var err error
+	total := t.db.IterDB(func(k, v []byte) error {
+		trieInstance, parseErr := parseTrie(v)
+		if parseErr != nil {
+			log.Printf("Error parsing trie of %s: %v", k, parseErr)
+			err = parseErr
+			return parseErr
+		}
+		for _, associated := range trieInstance.FindAllByPrefixForRecall(query) {
+			if len(associatedQueries) < MAX_ASSOCIATED_QUERIES && !slices.Contains(associatedQueries, associated) {
+				associatedQueries = append(associatedQueries, associated)
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		log.Printf("Error retrieving trie from database: %v", err)
 		return nil, err
 	}
 
-	if value == nil {
+	if total == 0 {
 		log.Println("No trie found in the database.")
 		return nil, nil // No trie stored
 	}
 
-	trieInstance := &trie.Trie{}
-	replacedValue := bytes.Replace(value, []byte("children"), []byte("children_recall"), -1)
-	node, err := trie.ParseTrieNode(string(replacedValue))
-	if err != nil {
-		log.Printf("Error parsing trie node: %v", err)
-		return nil, err
-	}
-
-	rootNode := trie.NewTrieNode("", nil)
-	rootNode.ChildrenRecall = node.ChildrenRecall["root"].ChildrenRecall
-	trieInstance.Root = rootNode
-
-	associatedQueries := trieInstance.FindAllByPrefixForRecall(query)
-
 	return associatedQueries, nil
 }
 
 func (t *TrieDB) IterTrie() error {
-	value, err := t.db.Get([]byte("trie"))
+	var err error
+	total := t.db.IterDB(func(k, v []byte) error {
+		trieInstance, parseErr := parseTrie(v)
+		if parseErr != nil {
+			log.Printf("Error parsing trie of %s: %v", k, parseErr)
+			err = parseErr
+			return parseErr
+		}
+		for key, _ := range trieInstance.Root.ChildrenRecall {
+			log.Printf("Trie word: %s", key)
+			log.Printf("Trie node: %v", trieInstance.Root.ChildrenRecall[key].Word)
+		}
+		return nil
+	})
 	if err != nil {
-		log.Printf("Error retrieving trie from database: %v", err)
 		return err
 	}
 
-	if value == nil {
+	if total == 0 {
 		log.Println("No trie found in the database.")
-		return nil // No trie stored
 	}
 
+	return nil
+}
+
+// parseTrie reads a stored trie for recall
+func parseTrie(value []byte) (*trie.Trie, error) {
 	trieInstance := &trie.Trie{}
 	replacedValue := bytes.Replace(value, []byte("children"), []byte("children_recall"), -1)
 	node, err := trie.ParseTrieNode(string(replacedValue))
 	if err != nil {
-		log.Printf("Error parsing trie node: %v", err)
-		return err
+		return nil, err
 	}
 
-	rootNode := trie.NewTrieNode("root", nil)
+	rootNode := trie.NewTrieNode("", nil)
 	rootNode.ChildrenRecall = node.ChildrenRecall["root"].ChildrenRecall
 	trieInstance.Root = rootNode
-	for key, _ := range trieInstance.Root.ChildrenRecall {
-		log.Printf("Trie word: %s", key)
-		log.Printf("Trie node: %v", trieInstance.Root.ChildrenRecall[key].Word)
-	}
 
-	return nil
+	return trieInstance, nil
 }
 
 func (t *TrieDB) Close() error {
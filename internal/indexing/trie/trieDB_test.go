@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/m1i3k0e7/distributed-search-engine/pkg/trie"
+)
+
+func newTrie(titles ...string) *trie.Trie {
+	queryTrie := trie.NewTrie()
+	for _, title := range titles {
+		queryTrie.Insert(title)
+	}
+	return queryTrie
+}
+
+func TestTriePerFile(t *testing.T) {
+	trieDB, err := NewTrieDB(filepath.Join(t.TempDir(), "trie"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer trieDB.Close()
+
+	expect := func(query string, suggestions ...string) {
+		t.Helper()
+		associated, err := trieDB.AssociateQuery(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		slices.Sort(associated)
+		if !slices.Equal(associated, suggestions) {
+			t.Errorf("expect %v for %q, got %v", suggestions, query, associated)
+		}
+	}
+
+	// a new file adds its suggestions to those of the other files
+	if err := trieDB.StoreTrie("a.csv", newTrie("red shoe")); err != nil {
+		t.Fatal(err)
+	}
+	if err := trieDB.StoreTrie("b.csv", newTrie("red shirt")); err != nil {
+		t.Fatal(err)
+	}
+	expect("red", " red shirt", " red shoe")
+
+	// a file stored again replaces its own suggestions only
+	if err := trieDB.StoreTrie("a.csv", newTrie("red hat")); err != nil {
+		t.Fatal(err)
+	}
+	expect("red", " red hat", " red shirt")
+
+	if err := trieDB.DeleteTrie("b.csv"); err != nil {
+		t.Fatal(err)
+	}
+	expect("red", " red hat")
+}
@@ -0,0 +1,281 @@
+package indexing
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	farmhash "github.com/leemcloughlin/gofarmhash"
+	index "github.com/m1i3k0e7/distributed-search-engine/api/proto/index"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/kvdb"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/source"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/trie"
+	"github.com/m1i3k0e7/distributed-search-engine/pkg/logger"
+)
+
+const (
+	WATCH_POLL_INTERVAL = 10 * time.Second // default interval between two scans of the directory, inotify events scan it at once
+	WATCH_SETTLE        = 2 * time.Second  // a file is ingested once its size and modification time stayed the same this long
+)
+
+// WatchedFile is the state of a file when it was ingested last
+type WatchedFile struct {
+	Size    int64
+	ModTime time.Time
+	Rows    map[string]uint64 // document id -> hash of its row
+}
+
+type pendingFile struct {
+	size    int64
+	modTime time.Time
+	seen    time.Time
+}
+
+// Watcher keeps an index up to date with a directory of source files. New and modified files are ingested when they
+// settled, only the rows whose hash changed are written again, and the documents of rows that disappeared are deleted.
+// The state of every ingested file is kept in a kvdb so a restart does not ingest the files again.
+// The directory is watched with inotify where available and scanned every interval in any case.
+type Watcher struct {
+	dir      string
+	indexer  IIndexer
+	options  BuildOptions
+	interval time.Duration
+	state    kvdb.IKeyValueDB // file name -> json WatchedFile
+	files    map[string]*WatchedFile
+	pending  map[string]pendingFile // changed files waiting to settle
+	trigger  chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+	started  bool
+}
+
+func NewWatcher(dir string, indexer IIndexer, options BuildOptions, dbtype int, statePath string, interval time.Duration) (*Watcher, error) {
+	state, err := kvdb.GetKvDb(dbtype, statePath)
+	if err != nil {
+		return nil, err
+	}
+	if interval <= 0 {
+		interval = WATCH_POLL_INTERVAL
+	}
+
+	// checkpoints belong to full builds, a file interrupted while being ingested is ingested again
+	options.Checkpoints = nil
+	watcher := &Watcher{
+		dir:      dir,
+		indexer:  indexer,
+		options:  options,
+		interval: interval,
+		state:    state,
+		files:    make(map[string]*WatchedFile),
+		pending:  make(map[string]pendingFile),
+		trigger:  make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	state.IterDB(func(k, v []byte) error {
+		var file WatchedFile
+		if err := json.Unmarshal(v, &file); err != nil {
+			logger.Log.Printf("parse state of watched file %s failed: %s", k, err)
+			return nil
+		}
+		watcher.files[string(k)] = &file
+		return nil
+	})
+
+	return watcher, nil
+}
+
+// Start scans the directory and keeps watching it in the background
+func (watcher *Watcher) Start() {
+	watcher.started = true
+	go watcher.run()
+}
+
+func (watcher *Watcher) run() {
+	defer close(watcher.done)
+	if err := watchDir(watcher.dir, watcher.notify, watcher.stop); err != nil {
+		log.Printf("watch %s failed: %s, poll it every %s", watcher.dir, err, watcher.interval)
+	}
+
+	ticker := time.NewTicker(watcher.interval)
+	defer ticker.Stop()
+	for {
+		watcher.scan()
+		select {
+		case <-watcher.stop:
+			return
+		case <-ticker.C:
+		case <-watcher.trigger:
+		}
+	}
+}
+
+// notify asks for a scan, events arriving during a scan are merged into one more scan
+func (watcher *Watcher) notify() {
+	select {
+	case watcher.trigger <- struct{}{}:
+	default:
+	}
+}
+
+func (watcher *Watcher) scan() {
+	entries, err := os.ReadDir(watcher.dir)
+	if err != nil {
+		log.Printf("read dir %s failed: %s", watcher.dir, err)
+		return
+	}
+
+	present := make(map[string]struct{}, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !source.Supported(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		name := entry.Name()
+		present[name] = struct{}{}
+		if file, exists := watcher.files[name]; exists && file.Size == info.Size() && file.ModTime.Equal(info.ModTime()) {
+			delete(watcher.pending, name)
+			continue
+		}
+
+		// an export may still be written, wait until the file stops changing
+		pending, exists := watcher.pending[name]
+		if !exists || pending.size != info.Size() || !pending.modTime.Equal(info.ModTime()) {
+			watcher.pending[name] = pendingFile{size: info.Size(), modTime: info.ModTime(), seen: time.Now()}
+			continue
+		}
+		if time.Since(pending.seen) < WATCH_SETTLE {
+			continue
+		}
+		delete(watcher.pending, name)
+		watcher.ingest(name, info.Size(), info.ModTime())
+	}
+	if len(watcher.pending) > 0 {
+		time.AfterFunc(WATCH_SETTLE, watcher.notify)
+	}
+
+	for name := range watcher.files {
+		if _, exists := present[name]; !exists {
+			watcher.remove(name)
+		}
+	}
+}
+
+// ingest writes the changed rows of a file and deletes the documents of the rows that are gone
+func (watcher *Watcher) ingest(name string, size int64, modTime time.Time) {
+	old := watcher.files[name]
+	current := &WatchedFile{Size: size, ModTime: modTime, Rows: make(map[string]uint64)}
+	lock := sync.Mutex{}
+	options := watcher.options
+	options.Changed = func(docId string, values []string) bool {
+		hash := farmhash.Hash64([]byte(strings.Join(values, "\x1f")))
+		lock.Lock()
+		current.Rows[docId] = hash
+		lock.Unlock()
+		if old == nil {
+			return true
+		}
+		oldHash, exists := old.Rows[docId]
+		return !exists || oldHash != hash
+	}
+
+	logger.Log.Printf("ingest changed file %s", name)
+	report := BuildIndexFromFile(filepath.Join(watcher.dir, name), watcher.indexer, options)
+	if !report.Complete {
+		log.Printf("ingest %s failed, it is ingested again at the next scan", name)
+		return
+	}
+
+	deleted := 0
+	if old != nil {
+		gone := make([]string, 0)
+		for docId := range old.Rows {
+			if _, exists := current.Rows[docId]; !exists && !watcher.owned(docId, name) {
+				gone = append(gone, docId)
+			}
+		}
+		deleted = watcher.delete(gone)
+	}
+	watcher.save(name, current)
+	logger.Log.Printf("file %s: %d rows written, %d unchanged, %d documents deleted", name, report.Documents, report.Unchanged, deleted)
+}
+
+// remove deletes the documents and the query suggestions of a file that is gone
+func (watcher *Watcher) remove(name string) {
+	gone := make([]string, 0, len(watcher.files[name].Rows))
+	for docId := range watcher.files[name].Rows {
+		if !watcher.owned(docId, name) {
+			gone = append(gone, docId)
+		}
+	}
+	deleted := watcher.delete(gone)
+	err := withTrieDB(watcher.options, func(trieDB *storage.TrieDB) error {
+		return trieDB.DeleteTrie(name)
+	})
+	if err != nil {
+		log.Printf("delete query suggestions of %s failed: %s", name, err)
+	}
+	if err := watcher.state.Delete([]byte(name)); err != nil {
+		log.Printf("delete state of watched file %s failed: %s", name, err)
+	}
+	delete(watcher.files, name)
+	logger.Log.Printf("file %s removed: %d documents deleted", name, deleted)
+}
+
+// owned tells whether a file other than name still has a row with the document id
+func (watcher *Watcher) owned(docId string, name string) bool {
+	for other, file := range watcher.files {
+		if other == name {
+			continue
+		}
+		if _, exists := file.Rows[docId]; exists {
+			return true
+		}
+	}
+	return false
+}
+
+func (watcher *Watcher) delete(docIds []string) int {
+	deleted := 0
+	for start := 0; start < len(docIds); start += BULK_BATCH_SIZE {
+		end := min(start+BULK_BATCH_SIZE, len(docIds))
+		operations := make([]*index.BulkOperation, 0, end-start)
+		for _, docId := range docIds[start:end] {
+			operations = append(operations, &index.BulkOperation{DeleteId: docId})
+		}
+		for i, result := range watcher.indexer.Bulk(operations) {
+			if len(result.Error) > 0 {
+				log.Printf("delete document %s failed: %s", docIds[start+i], result.Error)
+				continue
+			}
+			deleted += int(result.Count)
+		}
+	}
+	return deleted
+}
+
+func (watcher *Watcher) save(name string, file *WatchedFile) {
+	watcher.files[name] = file
+	bs, err := json.Marshal(file)
+	if err == nil {
+		err = watcher.state.Set([]byte(name), bs)
+	}
+	if err != nil {
+		log.Printf("save state of watched file %s failed: %s", name, err)
+	}
+}
+
+func (watcher *Watcher) Close() error {
+	close(watcher.stop)
+	if watcher.started {
+		<-watcher.done
+	}
+	return watcher.state.Close()
+}
@@ -0,0 +1,50 @@
+//go:build linux
+
+package indexing
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+const inotifyMask = unix.IN_CLOSE_WRITE | unix.IN_MODIFY | unix.IN_CREATE | unix.IN_DELETE | unix.IN_MOVED_FROM | unix.IN_MOVED_TO
+
+// watchDir calls notify on every change in dir until stop is closed, the events are not parsed since any of them asks for a scan
+func watchDir(dir string, notify func(), stop <-chan struct{}) error {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return err
+	}
+	if _, err := unix.InotifyAddWatch(fd, dir, inotifyMask); err != nil {
+		unix.Close(fd)
+		return err
+	}
+
+	go func() {
+		defer unix.Close(fd)
+		buffer := make([]byte, 64*unix.SizeofInotifyEvent)
+		fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			// wake up every second to see whether the watcher stopped
+			n, err := unix.Poll(fds, 1000)
+			if err != nil && err != unix.EINTR {
+				return
+			}
+			if n == 0 {
+				continue
+			}
+			for {
+				if _, err := unix.Read(fd, buffer); err != nil {
+					break
+				}
+			}
+			notify()
+		}
+	}()
+
+	return nil
+}
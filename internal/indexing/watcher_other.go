@@ -0,0 +1,12 @@
+//go:build !linux
+
+package indexing
+
+import (
+	"fmt"
+)
+
+// watchDir is only implemented with inotify, the watcher polls on other systems
+func watchDir(dir string, notify func(), stop <-chan struct{}) error {
+	return fmt.Errorf("file system events are not supported on this system")
+}
@@ -0,0 +1,27 @@
+package common
+
+import "github.com/m1i3k0e7/distributed-search-engine/internal/indexing/schema"
+
+// Explanation records why a document was recalled, kept and ranked where it is
+type Explanation struct {
+	RecallRanks  map[string]int                // recaller -> 1-based rank of the document in the recaller's output
+	MatchedTerms []string                      // TermQuery branches matched by the document
+	Categories   []string                      // categories of the document that matched the category filter
+	Filters      []string                      // filters the document survived
+	TermScores   map[string]map[string]float64 // ranking function -> query term -> score
+	Scores       map[string]float64            // total score given by each ranking function
+}
+
+func NewExplanation() *Explanation {
+	return &Explanation{
+		RecallRanks: make(map[string]int),
+		TermScores:  make(map[string]map[string]float64),
+		Scores:      make(map[string]float64),
+	}
+}
+
+// ExplainedDocument is returned instead of a bare document when SearchRequest.Explain is set
+type ExplainedDocument struct {
+	Document    schema.Document
+	Explanation *Explanation
+}
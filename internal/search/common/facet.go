@@ -0,0 +1,24 @@
+package common
+
+import (
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/category"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/schema"
+)
+
+// FacetedResult is returned instead of a bare document list when SearchRequest.Facets is set
+type FacetedResult struct {
+	Documents any // []schema.Document, or []ExplainedDocument if SearchRequest.Explain is set
+	Facets    []*category.Node
+}
+
+// Facets counts documents per category on every level of the taxonomy, a document counts for all its ancestors
+func Facets(docs []schema.Document, docSchema *schema.Schema) []*category.Node {
+	counts := make(map[string]int, 100)
+	for _, doc := range docs {
+		for _, ancestor := range category.Ancestors(docSchema.CategoryPath(doc)) {
+			counts[ancestor]++
+		}
+	}
+
+	return category.Tree(counts)
+}
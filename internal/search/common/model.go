@@ -1,6 +1,13 @@
 package common
 
 import (
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/schema"
 )
 
 type UN string
+
+// ScoredDocument is a document recalled by a recaller together with the recaller's own score
+type ScoredDocument struct {
+	Document schema.Document
+	Score    float64
+}
@@ -0,0 +1,8 @@
+package common
+
+// PartialResult is returned instead of the result of a search that misses shards when SearchRequest.AllowPartial is set
+type PartialResult struct {
+	Documents any   // the result of a complete search, e.g. []schema.Document or FacetedResult
+	TimedOut  []int // shards whose replicas did not answer before the deadline
+	Failed    []int // shards without an alive replica, or whose replicas all failed
+}
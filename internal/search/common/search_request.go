@@ -1,9 +1,28 @@
 package common
 
+// Range keeps documents whose numeric Field is in [From, To], To of 0 means no upper bound
+type Range struct {
+	Field string
+	From  float64
+	To    float64
+}
+
 type SearchRequest struct {
-	Classes  []string
-	Keywords []string
-	Query	string
+	Classes   []string
+	Keywords  []string
+	Query     string
 	PriceFrom int
 	PriceTo   int
+	Ranges    []Range   // filters on numeric fields, PriceFrom and PriceTo are a range on DiscountPrice
+	Explain   bool      // return why each product matched and how it was scored
+	Vector    []float32 // query embedding for VectorRecaller
+	TopK      int       // number of nearest neighbors VectorRecaller returns
+	Facets    bool      // also return the number of results in each category, per level of the taxonomy
+
+	TimeoutMs    int  // deadline of the search in milliseconds, the default timeout of the server if 0
+	AllowPartial bool // return the results of the shards that answered in time and list the others, instead of failing
+
+	Fusion          string             // how recaller outputs are combined, "rrf" (default) or "weighted"
+	RecallerWeights map[string]float64 // weight of each recaller in fusion, keyed by recaller name, default 1
+	FieldWeights    map[string]float64 // overrides the BM25F weight of product fields, e.g. {"Name": 5}
 }
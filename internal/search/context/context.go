@@ -2,19 +2,66 @@ package context
 
 import (
 	"context"
+	"sync"
 
-	search_proto "github.com/m1i3k0e7/distributed-search-engine/api/proto/search"
 	"github.com/m1i3k0e7/distributed-search-engine/internal/search/common"
 	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/category"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/schema"
+	"github.com/m1i3k0e7/distributed-search-engine/pkg/ranking"
 )
 
 type ProductSearchContext struct {
-	Ctx     context.Context
+	Ctx     context.Context // carries the deadline of the request to the workers, no deadline if nil
 	Indexer indexing.IIndexer
 	Request *common.SearchRequest
-	Products  []*search_proto.Product
+	Schema    *schema.Schema // decodes the stored documents
+	Documents []schema.Document
+	Ranking   *ranking.BM25FConfig // field weights used by KeywordRecaller, default config if nil
+	Categories category.ICategoryRegistry // resolves Request.Classes to category ids
+
+	Explanations map[string]*common.Explanation // key: document id, only filled when Request.Explain is set
+	explainLock  sync.Mutex
+
+	Status     indexing.SearchStatus // shards missing from the results of all recallers
+	statusLock sync.Mutex
+}
+
+// Context returns the context of the request, bounded by its deadline
+func (ctx *ProductSearchContext) Context() context.Context {
+	if ctx.Ctx == nil {
+		return context.Background()
+	}
+	return ctx.Ctx
+}
+
+// Missing records the shards a recaller got no results from, recallers run in parallel
+func (ctx *ProductSearchContext) Missing(status indexing.SearchStatus) {
+	ctx.statusLock.Lock()
+	defer ctx.statusLock.Unlock()
+	ctx.Status.Merge(status)
+}
+
+// Explain records explanation details of a document, it is a no-op unless the request asks for explanations.
+// Recallers run in parallel, so fn is called under a lock.
+func (ctx *ProductSearchContext) Explain(docId string, fn func(*common.Explanation)) {
+	if ctx.Request == nil || !ctx.Request.Explain {
+		return
+	}
+
+	ctx.explainLock.Lock()
+	defer ctx.explainLock.Unlock()
+	if ctx.Explanations == nil {
+		ctx.Explanations = make(map[string]*common.Explanation)
+	}
+	explanation, exists := ctx.Explanations[docId]
+	if !exists {
+		explanation = common.NewExplanation()
+		ctx.Explanations[docId] = explanation
+	}
+	fn(explanation)
 }
 
 type Filter interface {
 	Apply(*ProductSearchContext)
-}
\ No newline at end of file
+}
@@ -1,28 +1,49 @@
 package filter
 
 import (
-	search_proto "github.com/m1i3k0e7/distributed-search-engine/api/proto/search"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/schema"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/search/common"
 	"github.com/m1i3k0e7/distributed-search-engine/internal/search/context"
 )
 
+const (
+	PRICE_FIELD = "DiscountPrice" // field filtered by PriceFrom and PriceTo of a request
+)
+
 type ViewFilter struct {
 }
 
+// Apply keeps the documents whose numeric fields are within all ranges of the request
 func (ViewFilter) Apply(ctx *context.ProductSearchContext) {
 	request := ctx.Request
 	if request == nil {
 		return
 	}
-	if request.PriceFrom >= request.PriceTo {
+	ranges := make([]common.Range, 0, len(request.Ranges)+1)
+	ranges = append(ranges, request.Ranges...)
+	if request.PriceFrom < request.PriceTo {
+		ranges = append(ranges, common.Range{Field: PRICE_FIELD, From: float64(request.PriceFrom), To: float64(request.PriceTo)})
+	}
+	if len(ranges) == 0 {
 		return
 	}
 
-	products := make([]*search_proto.Product, 0, len(ctx.Products))
-	for _, product := range ctx.Products {
-		if product.DiscountPrice >= float64(request.PriceFrom) && product.DiscountPrice <= float64(request.PriceTo) {
-			products = append(products, product)
+	docs := make([]schema.Document, 0, len(ctx.Documents))
+	for _, doc := range ctx.Documents {
+		if inRanges(doc, ranges) {
+			docs = append(docs, doc)
 		}
 	}
 	
-	ctx.Products = products
+	ctx.Documents = docs
+}
+
+func inRanges(doc schema.Document, ranges []common.Range) bool {
+	for _, r := range ranges {
+		value, ok := doc.Float(r.Field)
+		if !ok || value < r.From || (r.To != 0 && value > r.To) {
+			return false
+		}
+	}
+	return true
 }
@@ -5,16 +5,18 @@ import (
 	"sync"
 	"time"
 
-	search_proto "github.com/m1i3k0e7/distributed-search-engine/api/proto/search"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/schema"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/search/common"
 	"github.com/m1i3k0e7/distributed-search-engine/internal/search/filter"
 	"github.com/m1i3k0e7/distributed-search-engine/internal/search/recaller"
 	"github.com/m1i3k0e7/distributed-search-engine/pkg/logger"
-	"golang.org/x/exp/maps"
+	"github.com/m1i3k0e7/distributed-search-engine/pkg/ranking"
 	"github.com/m1i3k0e7/distributed-search-engine/internal/search/context"
 )
 
+// Recaller returns candidate documents ranked by the recaller's own score, descending
 type Recaller interface {
-	Recall(*context.ProductSearchContext) []*search_proto.Product
+	Recall(*context.ProductSearchContext) []*common.ScoredDocument
 }
 
 type ProductSearcher struct {
@@ -35,61 +37,85 @@ func (searcher *ProductSearcher) Recall(searchContext *context.ProductSearchCont
 		return
 	}
 
-	// parallel recall
-	collection := make(chan *search_proto.Product, 1000)
+	// parallel recall, each recaller writes its own slot
+	lists := make([]ranking.RankedList, len(searcher.Recallers))
+	outputs := make([][]*common.ScoredDocument, len(searcher.Recallers))
 	wg := sync.WaitGroup{}
 	wg.Add(len(searcher.Recallers))
-	for _, recaller := range searcher.Recallers {
-		go func(recaller Recaller) {
+	for i, recaller := range searcher.Recallers {
+		go func(i int, recaller Recaller) {
 			defer wg.Done()
 			rule := reflect.TypeOf(recaller).Name()
 			result := recaller.Recall(searchContext)
 			logger.Log.Printf("recall %d docs by %s", len(result), rule)
-			for _, product := range result {
-				collection <- product
+			list := ranking.RankedList{Source: rule, Ids: make([]string, 0, len(result)), Scores: make([]float64, 0, len(result))}
+			for rank, scored := range result {
+				list.Ids = append(list.Ids, scored.Document.Id())
+				list.Scores = append(list.Scores, scored.Score)
+				searchContext.Explain(scored.Document.Id(), func(explanation *common.Explanation) {
+					explanation.RecallRanks[rule] = rank + 1
+				})
 			}
-		}(recaller)
+			lists[i] = list
+			outputs[i] = result
+		}(i, recaller)
 	}
+	wg.Wait()
 
-	// merge results, deduplicate by product ID
-	productMap := make(map[string]*search_proto.Product, 1000)
-	receiveFinish := make(chan struct{})
-	go func() {
-		for {
-			product, ok := <-collection
-			if !ok {
-				break
-			}
-			productMap[product.Id] = product
+	// merge results, deduplicate by document ID
+	docMap := make(map[string]schema.Document, 1000)
+	for _, output := range outputs {
+		for _, scored := range output {
+			docMap[scored.Document.Id()] = scored.Document
 		}
-		receiveFinish <- struct{}{}
-	}()
-	wg.Wait()
-	close(collection)
-	<-receiveFinish
+	}
 
-	searchContext.Products = maps.Values(productMap)
+	// fuse the rankings of all recallers into one
+	method := ranking.FUSION_RRF
+	var weights map[string]float64
+	if searchContext.Request != nil {
+		if len(searchContext.Request.Fusion) > 0 {
+			method = searchContext.Request.Fusion
+		}
+		weights = searchContext.Request.RecallerWeights
+	}
+	ids, scores := ranking.Fuse(method, lists, weights)
+
+	docs := make([]schema.Document, 0, len(ids))
+	for _, id := range ids {
+		docs = append(docs, docMap[id])
+		searchContext.Explain(id, func(explanation *common.Explanation) {
+			explanation.Scores[method] = scores[id]
+		})
+	}
+	searchContext.Documents = docs
 }
 
 func (searcher *ProductSearcher) Filter(searchContext *context.ProductSearchContext) {
 	// apply each filter in order
 	for _, filter := range searcher.Filters {
 		filter.Apply(searchContext)
+		rule := reflect.TypeOf(filter).Name()
+		for _, doc := range searchContext.Documents {
+			searchContext.Explain(doc.Id(), func(explanation *common.Explanation) {
+				explanation.Filters = append(explanation.Filters, rule)
+			})
+		}
 	}
 }
 
-func (searcher *ProductSearcher) Search(searchContext *context.ProductSearchContext) []*search_proto.Product {
+func (searcher *ProductSearcher) Search(searchContext *context.ProductSearchContext) []schema.Document {
 	t1 := time.Now()
 
 	searcher.Recall(searchContext)
 	t2 := time.Now()
-	logger.Log.Printf("recall %d docs in %d ms", len(searchContext.Products), t2.Sub(t1).Milliseconds())
+	logger.Log.Printf("recall %d docs in %d ms", len(searchContext.Documents), t2.Sub(t1).Milliseconds())
 
 	searcher.Filter(searchContext)
 	t3 := time.Now()
-	logger.Log.Printf("after filter remain %d docs in %d ms", len(searchContext.Products), t3.Sub(t2).Milliseconds())
+	logger.Log.Printf("after filter remain %d docs in %d ms", len(searchContext.Documents), t3.Sub(t2).Milliseconds())
 
-	return searchContext.Products
+	return searchContext.Documents
 }
 
 type AllProductSearcher struct {
@@ -98,7 +124,7 @@ type AllProductSearcher struct {
 
 func NewAllProductSearcher() *AllProductSearcher {
 	searcher := new(AllProductSearcher)
-	searcher.WithRecaller(recaller.KeywordRecaller{})
+	searcher.WithRecaller(recaller.KeywordRecaller{}, recaller.VectorRecaller{})
 	searcher.WithFilter(filter.ViewFilter{})
 	
 	return searcher
@@ -1,23 +1,29 @@
 package recaller
 
 import (
+	"sort"
+	"strings"
+
 	search_proto "github.com/m1i3k0e7/distributed-search-engine/api/proto/search"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/category"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/indexing/schema"
 	"github.com/m1i3k0e7/distributed-search-engine/internal/search/context"
 	"github.com/m1i3k0e7/distributed-search-engine/internal/search/common"
-	proto "google.golang.org/protobuf/proto"
+	"github.com/m1i3k0e7/distributed-search-engine/pkg/ranking"
 )
 
 type KeywordRecaller struct {
 }
 
-func (KeywordRecaller) Recall(ctx *context.ProductSearchContext) []*search_proto.Product {
+// Recall returns the documents matching all keywords, ranked by BM25F over the document fields
+func (KeywordRecaller) Recall(ctx *context.ProductSearchContext) []*common.ScoredDocument {
 	request := ctx.Request
 	if request == nil {
 		return nil
 	}
 
 	indexer := ctx.Indexer
-	if indexer == nil {
+	if indexer == nil || ctx.Schema == nil {
 		return nil
 	}
 
@@ -25,19 +31,80 @@ func (KeywordRecaller) Recall(ctx *context.ProductSearchContext) []*search_proto
 	query := new(search_proto.TermQuery)
 	if len(keywords) > 0 {
 		for _, word := range keywords {
-			query = query.And(search_proto.NewTermQuery("content", word))
+			query = query.And(search_proto.NewTermQuery(schema.CONTENT_FIELD, word))
+		}
+	}
+
+	if len(request.Classes) > 0 && ctx.Categories != nil {
+		categoryQuery, exists := category.Query(ctx.Categories, request.Classes)
+		if !exists {
+			return nil // none of the requested categories exists
 		}
+		query = query.And(categoryQuery)
 	}
 
-	orFlags := []uint64{common.GetClassBits(request.Classes)}
-	docs := indexer.Search(query, 0, 0, orFlags)
-	products := make([]*search_proto.Product, 0, len(docs))
+	docs, status := indexer.SearchContext(ctx.Context(), query, 0, 0, nil)
+	ctx.Missing(status)
+	documents := make([]schema.Document, 0, len(docs))
 	for _, doc := range docs {
-		var product search_proto.Product
-		if err := proto.Unmarshal(doc.Bytes, &product); err == nil {
-			products = append(products, &product)
+		if document, err := ctx.Schema.Unmarshal(doc.Bytes); err == nil {
+			documents = append(documents, document)
+			ctx.Explain(document.Id(), func(explanation *common.Explanation) {
+				explainDocument(explanation, doc, query, ctx.Categories)
+			})
 		}
 	}
 
-	return products
+	config := ctx.Schema.RankingConfig()
+	if ctx.Ranking != nil {
+		config = *ctx.Ranking
+	}
+	tokenized := make([]ranking.TokenizedDocument, 0, len(documents))
+	for _, document := range documents {
+		fields := make(map[string][]string, len(config.Fields))
+		for field := range config.Fields {
+			fields[field] = ctx.Schema.Tokens(document, field)
+		}
+		tokenized = append(tokenized, ranking.TokenizedDocument{Id: document.Id(), Fields: fields})
+	}
+	termScores := ranking.ExplainBM25F(request.Query, tokenized, config)
+	result := make([]*common.ScoredDocument, 0, len(documents))
+	for _, document := range documents {
+		id := document.Id()
+		score := 0.0
+		for _, termScore := range termScores[id] {
+			score += termScore
+		}
+		result = append(result, &common.ScoredDocument{Document: document, Score: score})
+		ctx.Explain(id, func(explanation *common.Explanation) {
+			explanation.TermScores["BM25F"] = termScores[id]
+			explanation.Scores["BM25F"] = score
+		})
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Score > result[j].Score
+	})
+
+	return result
+}
+
+// explainDocument records which branches of query and which categories the document matched
+func explainDocument(explanation *common.Explanation, doc *search_proto.Document, query *search_proto.TermQuery, categories category.ICategoryRegistry) {
+	keywords := make(map[string]*search_proto.Keyword, len(doc.Keywords))
+	keySet := make(map[string]struct{}, len(doc.Keywords))
+	for _, keyword := range doc.Keywords {
+		keywords[keyword.ToString()] = keyword
+		keySet[keyword.ToString()] = struct{}{}
+	}
+	if _, branches := query.Match(keySet); len(branches) > 0 {
+		for _, branch := range branches {
+			explanation.MatchedTerms = append(explanation.MatchedTerms, strings.ReplaceAll(branch, "\001", ":"))
+			// branches of a single category keyword are the categories that passed the category filter
+			if id, ok := category.ParseKeyword(keywords[branch]); ok && categories != nil {
+				if name, exists := categories.Name(id); exists {
+					explanation.Categories = append(explanation.Categories, name)
+				}
+			}
+		}
+	}
 }
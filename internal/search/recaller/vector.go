@@ -0,0 +1,46 @@
+package recaller
+
+import (
+	"github.com/m1i3k0e7/distributed-search-engine/internal/search/common"
+	"github.com/m1i3k0e7/distributed-search-engine/internal/search/context"
+)
+
+const (
+	DEFAULT_VECTOR_TOP_K = 50
+)
+
+// VectorRecaller recalls the approximate nearest neighbors of SearchRequest.Vector
+type VectorRecaller struct {
+}
+
+// Recall returns the nearest neighbors ranked by cosine similarity
+func (VectorRecaller) Recall(ctx *context.ProductSearchContext) []*common.ScoredDocument {
+	request := ctx.Request
+	if request == nil || len(request.Vector) == 0 {
+		return nil
+	}
+
+	indexer := ctx.Indexer
+	if indexer == nil || ctx.Schema == nil {
+		return nil
+	}
+
+	k := request.TopK
+	if k <= 0 {
+		k = DEFAULT_VECTOR_TOP_K
+	}
+
+	docs, scores, status := indexer.SearchVectorContext(ctx.Context(), request.Vector, k)
+	ctx.Missing(status)
+	result := make([]*common.ScoredDocument, 0, len(docs))
+	for i, doc := range docs {
+		if document, err := ctx.Schema.Unmarshal(doc.Bytes); err == nil {
+			result = append(result, &common.ScoredDocument{Document: document, Score: float64(scores[i])})
+			ctx.Explain(document.Id(), func(explanation *common.Explanation) {
+				explanation.Scores["VectorSimilarity"] = float64(scores[i])
+			})
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,352 @@
+package hnsw
+
+import (
+	"container/heap"
+	"math"
+	"math/rand"
+	"sync"
+)
+
+const (
+	DEFAULT_M               = 16  // max number of neighbors of a node on upper layers, layer 0 keeps 2*M
+	DEFAULT_EF_CONSTRUCTION = 200 // size of the dynamic candidate list when inserting
+	DEFAULT_EF_SEARCH       = 64  // size of the dynamic candidate list when searching
+	COMPACT_RATIO           = 0.5 // the graph is rebuilt from its live vectors once deleted nodes exceed this fraction of them
+)
+
+// Neighbor is a search result, Distance is the cosine distance to the query vector (0 means identical)
+type Neighbor struct {
+	Key      string
+	Distance float32
+}
+
+type node struct {
+	key     string
+	vector  []float32 // normalized vector
+	friends [][]int   // neighbors of each layer, friends[0] is the bottom layer
+	deleted bool      // deleted nodes are kept in the graph for navigation, but never returned
+}
+
+// Graph is a Hierarchical Navigable Small World graph for approximate nearest neighbor search on cosine distance
+type Graph struct {
+	M              int
+	EfConstruction int
+	EfSearch       int
+
+	levelMult float64
+	nodes     []*node
+	keys      map[string]int // key -> index of the node in nodes
+	entry     int            // entry point on the top layer, -1 if the graph is empty
+	maxLevel  int
+	dim       int // all vectors must have the same dimension, decided by the first one
+	rand      *rand.Rand
+	lock      sync.RWMutex
+}
+
+func NewGraph(M, efConstruction, efSearch int) *Graph {
+	return &Graph{
+		M:              M,
+		EfConstruction: efConstruction,
+		EfSearch:       efSearch,
+		levelMult:      1 / math.Log(float64(M)),
+		keys:           make(map[string]int),
+		entry:          -1,
+		rand:           rand.New(rand.NewSource(1)),
+	}
+}
+
+func NewDefaultGraph() *Graph {
+	return NewGraph(DEFAULT_M, DEFAULT_EF_CONSTRUCTION, DEFAULT_EF_SEARCH)
+}
+
+// Len returns the number of live vectors in the graph
+func (g *Graph) Len() int {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	return len(g.keys)
+}
+
+func (g *Graph) Dim() int {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	return g.dim
+}
+
+// Add inserts a vector, an existing vector with the same key is replaced. Vectors whose dimension differs from the graph's are ignored.
+func (g *Graph) Add(key string, vector []float32) bool {
+	if len(vector) == 0 {
+		return false
+	}
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if g.dim == 0 {
+		g.dim = len(vector)
+	} else if g.dim != len(vector) {
+		return false
+	}
+	if old, exists := g.keys[key]; exists {
+		g.nodes[old].deleted = true
+		delete(g.keys, key)
+	}
+	g.insert(key, normalize(vector))
+	g.compact()
+
+	return true
+}
+
+// insert links a normalized vector into the graph, the caller holds the write lock
+func (g *Graph) insert(key string, vector []float32) {
+	level := int(math.Floor(-math.Log(1-g.rand.Float64()) * g.levelMult))
+	n := &node{key: key, vector: vector, friends: make([][]int, level+1)}
+	id := len(g.nodes)
+	g.nodes = append(g.nodes, n)
+	g.keys[key] = id
+
+	if g.entry < 0 {
+		g.entry = id
+		g.maxLevel = level
+		return
+	}
+
+	// greedy search from the top layer down to the layer of the new node
+	curr := g.entry
+	currDist := distance(n.vector, g.nodes[curr].vector)
+	for l := g.maxLevel; l > level; l-- {
+		curr, currDist = g.greedy(n.vector, curr, currDist, l)
+	}
+
+	for l := min(level, g.maxLevel); l >= 0; l-- {
+		candidates := g.searchLayer(n.vector, curr, g.EfConstruction, l)
+		maxFriends := g.M
+		if l == 0 {
+			maxFriends = 2 * g.M
+		}
+		selected := candidates
+		if len(selected) > g.M {
+			selected = selected[:g.M]
+		}
+		for _, c := range selected {
+			n.friends[l] = append(n.friends[l], c.id)
+			friend := g.nodes[c.id]
+			friend.friends[l] = append(friend.friends[l], id)
+			if len(friend.friends[l]) > maxFriends {
+				g.shrink(friend, l, maxFriends)
+			}
+		}
+		if len(candidates) > 0 {
+			curr = candidates[0].id
+		}
+	}
+
+	if level > g.maxLevel {
+		g.maxLevel = level
+		g.entry = id
+	}
+}
+
+// compact rebuilds the graph from its live vectors once the deleted nodes pass COMPACT_RATIO of them. Deleted nodes still
+// cost every search a visit, and updates of the same keys would otherwise grow the graph without bound. A rebuild follows
+// many deletions, so it costs O(1) inserts per deletion.
+func (g *Graph) compact() {
+	deleted := len(g.nodes) - len(g.keys)
+	if deleted == 0 || float64(deleted) <= COMPACT_RATIO*float64(len(g.keys)) {
+		return
+	}
+
+	live := make([]*node, 0, len(g.keys))
+	for _, n := range g.nodes {
+		if !n.deleted {
+			live = append(live, n)
+		}
+	}
+	g.nodes = make([]*node, 0, len(live))
+	g.keys = make(map[string]int, len(live))
+	g.entry = -1
+	g.maxLevel = 0
+	for _, n := range live {
+		g.insert(n.key, n.vector)
+	}
+}
+
+// Delete removes a vector by key, returns false if the key does not exist
+func (g *Graph) Delete(key string) bool {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	id, exists := g.keys[key]
+	if !exists {
+		return false
+	}
+	g.nodes[id].deleted = true
+	delete(g.keys, key)
+	g.compact()
+
+	return true
+}
+
+// Search returns at most k nearest neighbors of vector, ordered by distance ascending
+func (g *Graph) Search(vector []float32, k int) []Neighbor {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+	if g.entry < 0 || k <= 0 || len(vector) != g.dim {
+		return nil
+	}
+
+	query := normalize(vector)
+	curr := g.entry
+	currDist := distance(query, g.nodes[curr].vector)
+	for l := g.maxLevel; l > 0; l-- {
+		curr, currDist = g.greedy(query, curr, currDist, l)
+	}
+
+	ef := g.EfSearch
+	if ef < k {
+		ef = k
+	}
+	// widen the search by the deleted nodes it may meet, at most twice, compact keeps them fewer than half of the live ones
+	candidates := g.searchLayer(query, curr, ef+min(len(g.nodes)-len(g.keys), ef), 0)
+
+	result := make([]Neighbor, 0, k)
+	for _, c := range candidates {
+		n := g.nodes[c.id]
+		if n.deleted {
+			continue
+		}
+		result = append(result, Neighbor{Key: n.key, Distance: c.distance})
+		if len(result) >= k {
+			break
+		}
+	}
+
+	return result
+}
+
+// greedy walks to the closest node of vector on layer l
+func (g *Graph) greedy(vector []float32, curr int, currDist float32, l int) (int, float32) {
+	for changed := true; changed; {
+		changed = false
+		for _, friend := range g.nodes[curr].friends[l] {
+			if d := distance(vector, g.nodes[friend].vector); d < currDist {
+				curr, currDist = friend, d
+				changed = true
+			}
+		}
+	}
+
+	return curr, currDist
+}
+
+// searchLayer returns the ef nearest nodes of vector on layer l, ordered by distance ascending
+func (g *Graph) searchLayer(vector []float32, entry int, ef int, l int) []candidate {
+	visited := map[int]struct{}{entry: {}}
+	first := candidate{id: entry, distance: distance(vector, g.nodes[entry].vector)}
+	candidates := &minHeap{first}
+	results := &maxHeap{first}
+
+	for candidates.Len() > 0 {
+		c := heap.Pop(candidates).(candidate)
+		if c.distance > (*results)[0].distance && results.Len() >= ef {
+			break
+		}
+		if l >= len(g.nodes[c.id].friends) {
+			continue
+		}
+		for _, friend := range g.nodes[c.id].friends[l] {
+			if _, exists := visited[friend]; exists {
+				continue
+			}
+			visited[friend] = struct{}{}
+			d := distance(vector, g.nodes[friend].vector)
+			if results.Len() < ef || d < (*results)[0].distance {
+				heap.Push(candidates, candidate{id: friend, distance: d})
+				heap.Push(results, candidate{id: friend, distance: d})
+				if results.Len() > ef {
+					heap.Pop(results)
+				}
+			}
+		}
+	}
+
+	sorted := make([]candidate, results.Len())
+	for i := len(sorted) - 1; i >= 0; i-- {
+		sorted[i] = heap.Pop(results).(candidate)
+	}
+
+	return sorted
+}
+
+// shrink keeps the maxFriends closest neighbors of n on layer l
+func (g *Graph) shrink(n *node, l int, maxFriends int) {
+	friends := &maxHeap{}
+	for _, friend := range n.friends[l] {
+		heap.Push(friends, candidate{id: friend, distance: distance(n.vector, g.nodes[friend].vector)})
+		if friends.Len() > maxFriends {
+			heap.Pop(friends)
+		}
+	}
+
+	kept := make([]int, 0, maxFriends)
+	for _, c := range *friends {
+		kept = append(kept, c.id)
+	}
+	n.friends[l] = kept
+}
+
+func normalize(vector []float32) []float32 {
+	var norm float64
+	for _, v := range vector {
+		norm += float64(v) * float64(v)
+	}
+	result := make([]float32, len(vector))
+	if norm == 0 {
+		return result
+	}
+
+	norm = math.Sqrt(norm)
+	for i, v := range vector {
+		result[i] = float32(float64(v) / norm)
+	}
+
+	return result
+}
+
+// distance is the cosine distance between two normalized vectors
+func distance(a, b []float32) float32 {
+	var dot float32
+	for i := range a {
+		dot += a[i] * b[i]
+	}
+
+	return 1 - dot
+}
+
+type candidate struct {
+	id       int
+	distance float32
+}
+
+type minHeap []candidate
+
+func (h minHeap) Len() int           { return len(h) }
+func (h minHeap) Less(i, j int) bool { return h[i].distance < h[j].distance }
+func (h minHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *minHeap) Push(x any)        { *h = append(*h, x.(candidate)) }
+func (h *minHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+type maxHeap []candidate
+
+func (h maxHeap) Len() int           { return len(h) }
+func (h maxHeap) Less(i, j int) bool { return h[i].distance > h[j].distance }
+func (h maxHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *maxHeap) Push(x any)        { *h = append(*h, x.(candidate)) }
+func (h *maxHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
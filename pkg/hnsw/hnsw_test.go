@@ -0,0 +1,162 @@
+package hnsw
+
+import (
+	"math/rand"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func randomVectors(n, dim int) [][]float32 {
+	r := rand.New(rand.NewSource(42))
+	vectors := make([][]float32, n)
+	for i := range vectors {
+		vectors[i] = make([]float32, dim)
+		for j := range vectors[i] {
+			vectors[i][j] = r.Float32()*2 - 1
+		}
+	}
+	return vectors
+}
+
+func TestSearchRecall(t *testing.T) {
+	vectors := randomVectors(1000, 16)
+	graph := NewDefaultGraph()
+	for i, vector := range vectors {
+		graph.Add(strconv.Itoa(i), vector)
+	}
+	if graph.Len() != len(vectors) {
+		t.Fatalf("expect %d vectors, got %d", len(vectors), graph.Len())
+	}
+
+	k, hit, total := 10, 0, 0
+	for _, query := range randomVectors(20, 16) {
+		// brute force
+		q := normalize(query)
+		exact := make([]Neighbor, 0, len(vectors))
+		for i, vector := range vectors {
+			exact = append(exact, Neighbor{Key: strconv.Itoa(i), Distance: distance(q, normalize(vector))})
+		}
+		sort.Slice(exact, func(i, j int) bool { return exact[i].Distance < exact[j].Distance })
+		expect := make(map[string]bool, k)
+		for _, n := range exact[:k] {
+			expect[n.Key] = true
+		}
+
+		result := graph.Search(query, k)
+		for i := 1; i < len(result); i++ {
+			if result[i].Distance < result[i-1].Distance {
+				t.Fatalf("result is not sorted by distance")
+			}
+		}
+		for _, n := range result {
+			if expect[n.Key] {
+				hit++
+			}
+		}
+		total += k
+	}
+
+	if recall := float64(hit) / float64(total); recall < 0.9 {
+		t.Errorf("recall %.2f is too low", recall)
+	}
+}
+
+func TestDeleteAndReplace(t *testing.T) {
+	graph := NewDefaultGraph()
+	graph.Add("a", []float32{1, 0})
+	graph.Add("b", []float32{0, 1})
+	graph.Add("c", []float32{1, 1})
+
+	if result := graph.Search([]float32{1, 0.1}, 1); len(result) != 1 || result[0].Key != "a" {
+		t.Fatalf("expect a, got %v", result)
+	}
+
+	graph.Delete("a")
+	if result := graph.Search([]float32{1, 0.1}, 1); len(result) != 1 || result[0].Key != "c" {
+		t.Fatalf("expect c after deleting a, got %v", result)
+	}
+
+	graph.Add("b", []float32{1, 0}) // replace b
+	if result := graph.Search([]float32{1, 0.1}, 1); len(result) != 1 || result[0].Key != "b" {
+		t.Fatalf("expect replaced b, got %v", result)
+	}
+	if graph.Len() != 2 {
+		t.Errorf("expect 2 vectors, got %d", graph.Len())
+	}
+	if graph.Add("d", []float32{1, 2, 3}) {
+		t.Errorf("vector with a different dimension should be rejected")
+	}
+}
+
+func TestUpdatesKeepRecallAndLatency(t *testing.T) {
+	const n, dim, k = 1000, 16, 10
+	queries := randomVectors(50, dim)
+	search := func(graph *Graph) time.Duration {
+		begin := time.Now()
+		for _, query := range queries {
+			graph.Search(query, k)
+		}
+		return time.Since(begin)
+	}
+
+	fresh := NewDefaultGraph()
+	for i, vector := range randomVectors(n, dim) {
+		fresh.Add(strconv.Itoa(i), vector)
+	}
+	freshTime := search(fresh)
+
+	// every key is replaced 5 times, the last vectors are the live ones
+	graph := NewDefaultGraph()
+	var vectors [][]float32
+	for round := 0; round < 5; round++ {
+		r := rand.New(rand.NewSource(int64(round)))
+		vectors = make([][]float32, n)
+		for i := range vectors {
+			vectors[i] = make([]float32, dim)
+			for j := range vectors[i] {
+				vectors[i][j] = r.Float32()*2 - 1
+			}
+			graph.Add(strconv.Itoa(i), vectors[i])
+		}
+	}
+	if graph.Len() != n {
+		t.Fatalf("expect %d vectors, got %d", n, graph.Len())
+	}
+	if len(graph.nodes) > n+n/2 {
+		t.Errorf("expect deleted nodes to be compacted, the graph has %d nodes for %d vectors", len(graph.nodes), n)
+	}
+
+	hit := 0
+	for _, query := range queries {
+		q := normalize(query)
+		exact := make([]Neighbor, 0, n)
+		for i, vector := range vectors {
+			exact = append(exact, Neighbor{Key: strconv.Itoa(i), Distance: distance(q, normalize(vector))})
+		}
+		sort.Slice(exact, func(i, j int) bool { return exact[i].Distance < exact[j].Distance })
+		expect := make(map[string]bool, k)
+		for _, neighbor := range exact[:k] {
+			expect[neighbor.Key] = true
+		}
+		for _, neighbor := range graph.Search(query, k) {
+			if expect[neighbor.Key] {
+				hit++
+			}
+		}
+	}
+	if recall := float64(hit) / float64(len(queries)*k); recall < 0.9 {
+		t.Errorf("recall %.2f after updates is too low", recall)
+	}
+	if updatedTime := search(graph); updatedTime > 4*freshTime+10*time.Millisecond {
+		t.Errorf("searches take %s after updates, %s on a fresh graph", updatedTime, freshTime)
+	}
+
+	for i := 0; i < n; i++ {
+		graph.Delete(strconv.Itoa(i))
+	}
+	if graph.Len() != 0 || len(graph.nodes) != 0 || graph.Search(queries[0], k) != nil {
+		t.Errorf("expect an empty graph after deleting every key, %d nodes left", len(graph.nodes))
+	}
+}
@@ -17,6 +17,29 @@ func RankDocumentByBM25(query string, docs []*search_proto.Product) []*search_pr
 		return docs
 	}
 
+	termScores := ExplainBM25(query, docs)
+	scores := make(map[string]float64, len(docs))
+	for id, terms := range termScores {
+		for _, score := range terms {
+			scores[id] += score
+		}
+	}
+
+	// Step 4: Sort documents by score.
+	sort.Slice(docs, func(i, j int) bool {
+		return scores[docs[i].Id] > scores[docs[j].Id]
+	})
+
+	return docs
+}
+
+// ExplainBM25 returns the BM25 score of every query term for each document, key: document id
+func ExplainBM25(query string, docs []*search_proto.Product) map[string]map[string]float64 {
+	termScores := make(map[string]map[string]float64, len(docs))
+	if len(docs) == 0 || query == "" {
+		return termScores
+	}
+
 	// Step 1: Preprocess documents and calculate term frequencies and document lengths.
 	processedDocs := make(map[string][]string)
 	docTermCounts := make(map[string]map[string]int)
@@ -57,27 +80,21 @@ func RankDocumentByBM25(query string, docs []*search_proto.Product) []*search_pr
 
 	idf := calculateIDF(docFreqs, len(docs))
 
-	// Step 3: Calculate BM25 score for each document.
-	scores := make(map[string]float64)
+	// Step 3: Calculate BM25 score of each term for each document.
 	for _, doc := range docs {
-		score := 0.0
+		scores := make(map[string]float64, len(uniqueQueryTerms))
 		docLen := float64(len(processedDocs[doc.Id]))
 		K := bm25K1 * (1 - bm25B + bm25B * docLen / avgDocLength)
 		for term := range uniqueQueryTerms {
 			if idfValue, ok := idf[term]; ok {
 				termFreq := float64(docTermCounts[doc.Id][term])
-				score += idfValue * (termFreq * (bm25K1 + 1)) / (termFreq + K)
+				scores[term] = idfValue * (termFreq * (bm25K1 + 1)) / (termFreq + K)
 			}
 		}
-		scores[doc.Id] = score
+		termScores[doc.Id] = scores
 	}
 
-	// Step 4: Sort documents by score.
-	sort.Slice(docs, func(i, j int) bool {
-		return scores[docs[i].Id] > scores[docs[j].Id]
-	})
-
-	return docs
+	return termScores
 }
 
 func calculateIDF(docFreqs map[string]int, docsNum int) map[string]float64 {
@@ -0,0 +1,190 @@
+package ranking
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+)
+
+const (
+	FIELD_NAME         = "Name"
+	FIELD_CATEGORY     = "Category"
+	FIELD_SUB_CATEGORY = "SubCategory"
+	FIELD_KEYWORDS     = "Keywords"
+)
+
+// FieldConfig is the weight and length normalization of a field in BM25F
+type FieldConfig struct {
+	Weight float64 // term frequency in this field is multiplied by Weight
+	B      float64 // length normalization, 0 ignores the field length, 1 fully normalizes it
+}
+
+type BM25FConfig struct {
+	K1     float64
+	Fields map[string]FieldConfig // key: document field name
+}
+
+// TokenizedDocument is a document split into terms per field, by the analyzer of each field
+type TokenizedDocument struct {
+	Id     string
+	Fields map[string][]string
+}
+
+// DefaultBM25FConfig makes matches in the product name outweigh matches in category text
+func DefaultBM25FConfig() BM25FConfig {
+	return BM25FConfig{
+		K1: bm25K1,
+		Fields: map[string]FieldConfig{
+			FIELD_NAME:         {Weight: 3.0, B: bm25B},
+			FIELD_CATEGORY:     {Weight: 1.0, B: 0.3},
+			FIELD_SUB_CATEGORY: {Weight: 1.0, B: 0.3},
+			FIELD_KEYWORDS:     {Weight: 1.5, B: bm25B},
+		},
+	}
+}
+
+// bm25fFile is a json config file, the values it does not set are nil
+type bm25fFile struct {
+	K1     *float64
+	Fields map[string]struct {
+		Weight *float64
+		B      *float64
+	}
+}
+
+// LoadBM25FConfig reads a json config, fields and values missing in the file keep their values in base
+func LoadBM25FConfig(configFile string, base BM25FConfig) (BM25FConfig, error) {
+	config := base.WithWeights(nil)
+	bs, err := os.ReadFile(configFile)
+	if err != nil {
+		return config, err
+	}
+
+	var loaded bm25fFile
+	if err := json.Unmarshal(bs, &loaded); err != nil {
+		return config, err
+	}
+	if loaded.K1 != nil && *loaded.K1 > 0 {
+		config.K1 = *loaded.K1
+	}
+	for field, loadedField := range loaded.Fields {
+		fieldConfig := config.Fields[field]
+		if loadedField.Weight != nil {
+			fieldConfig.Weight = *loadedField.Weight
+		}
+		if loadedField.B != nil {
+			fieldConfig.B = *loadedField.B
+		}
+		config.Fields[field] = fieldConfig
+	}
+
+	return config, nil
+}
+
+// WithWeights returns a copy of the config whose field weights are overridden, e.g. by a search request
+func (config BM25FConfig) WithWeights(weights map[string]float64) BM25FConfig {
+	fields := make(map[string]FieldConfig, len(config.Fields))
+	for field, fieldConfig := range config.Fields {
+		fields[field] = fieldConfig
+	}
+	for field, weight := range weights {
+		fieldConfig := fields[field]
+		fieldConfig.Weight = weight
+		fields[field] = fieldConfig
+	}
+
+	return BM25FConfig{K1: config.K1, Fields: fields}
+}
+
+func RankDocumentByBM25F(query string, docs []TokenizedDocument, config BM25FConfig) []TokenizedDocument {
+	if len(docs) == 0 || query == "" {
+		return docs
+	}
+
+	termScores := ExplainBM25F(query, docs, config)
+	scores := make(map[string]float64, len(docs))
+	for id, terms := range termScores {
+		for _, score := range terms {
+			scores[id] += score
+		}
+	}
+
+	sort.SliceStable(docs, func(i, j int) bool {
+		return scores[docs[i].Id] > scores[docs[j].Id]
+	})
+
+	return docs
+}
+
+// ExplainBM25F returns the BM25F score of every query term for each document, key: document id.
+// Term frequencies of all fields are combined, each weighted and normalized by the length of its field, before saturation by K1.
+func ExplainBM25F(query string, docs []TokenizedDocument, config BM25FConfig) map[string]map[string]float64 {
+	termScores := make(map[string]map[string]float64, len(docs))
+	if len(docs) == 0 || query == "" {
+		return termScores
+	}
+
+	// Step 1: Tokenize every field and calculate the average length of each field.
+	docFields := make(map[string]map[string]map[string]int, len(docs)) // doc id -> field -> term -> count
+	docLengths := make(map[string]map[string]float64, len(docs))       // doc id -> field -> length
+	avgLengths := make(map[string]float64, len(config.Fields))
+	for _, doc := range docs {
+		fields := make(map[string]map[string]int, len(config.Fields))
+		lengths := make(map[string]float64, len(config.Fields))
+		for field := range config.Fields {
+			tokens := doc.Fields[field]
+			counts := make(map[string]int, len(tokens))
+			for _, token := range tokens {
+				counts[token]++
+			}
+			fields[field] = counts
+			lengths[field] = float64(len(tokens))
+			avgLengths[field] += float64(len(tokens)) / float64(len(docs))
+		}
+		docFields[doc.Id] = fields
+		docLengths[doc.Id] = lengths
+	}
+
+	// Step 2: Calculate IDF of each unique query term, a document contains a term if any field does.
+	uniqueQueryTerms := make(map[string]bool)
+	for _, term := range preprocess(query) {
+		uniqueQueryTerms[term] = true
+	}
+	docFreqs := make(map[string]int)
+	for term := range uniqueQueryTerms {
+		for _, fields := range docFields {
+			for _, counts := range fields {
+				if counts[term] > 0 {
+					docFreqs[term]++
+					break
+				}
+			}
+		}
+	}
+	idf := calculateIDF(docFreqs, len(docs))
+
+	// Step 3: Combine the normalized term frequencies of all fields and saturate.
+	for _, doc := range docs {
+		scores := make(map[string]float64, len(uniqueQueryTerms))
+		for term := range uniqueQueryTerms {
+			tf := 0.0
+			for field, fieldConfig := range config.Fields {
+				count := float64(docFields[doc.Id][field][term])
+				if count == 0 {
+					continue
+				}
+				norm := 1.0
+				if avgLengths[field] > 0 {
+					norm = 1 - fieldConfig.B + fieldConfig.B*docLengths[doc.Id][field]/avgLengths[field]
+				}
+				tf += fieldConfig.Weight * count / norm
+			}
+			if tf > 0 {
+				scores[term] = idf[term] * tf / (config.K1 + tf)
+			}
+		}
+		termScores[doc.Id] = scores
+	}
+
+	return termScores
+}
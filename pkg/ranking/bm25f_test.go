@@ -0,0 +1,92 @@
+package ranking
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExplainBM25F(t *testing.T) {
+	config := BM25FConfig{K1: 1.2, Fields: map[string]FieldConfig{
+		FIELD_NAME:     {Weight: 2, B: 0.5},
+		FIELD_KEYWORDS: {Weight: 1, B: 0},
+	}}
+	docs := []TokenizedDocument{
+		{Id: "d1", Fields: map[string][]string{FIELD_NAME: {"red", "shoe"}, FIELD_KEYWORDS: {"red"}}},
+		{Id: "d2", Fields: map[string][]string{FIELD_NAME: {"blue", "shoe", "running"}}},
+	}
+
+	// average lengths: Name 2.5, Keywords 0.5. idf = ln((N - df + 0.5) / (df + 0.5) + 1) with N = 2
+	idfRed, idfShoe := math.Log(1.5/1.5+1), math.Log(0.5/2.5+1)
+	d1Name := 2 * 1 / (1 - 0.5 + 0.5*2/2.5) // weight * count / length norm
+	d2Name := 2 * 1 / (1 - 0.5 + 0.5*3/2.5)
+	d1Red := d1Name + 1 // Keywords has B 0, its norm is 1
+	expect := map[string]map[string]float64{
+		"d1": {"red": idfRed * d1Red / (1.2 + d1Red), "shoe": idfShoe * d1Name / (1.2 + d1Name)},
+		"d2": {"shoe": idfShoe * d2Name / (1.2 + d2Name)},
+	}
+
+	scores := ExplainBM25F("Red shoe", docs, config)
+	for id, terms := range expect {
+		if len(scores[id]) != len(terms) {
+			t.Errorf("expect the terms %v to score for %s, got %v", terms, id, scores[id])
+		}
+		for term, score := range terms {
+			if math.Abs(scores[id][term]-score) > 1e-9 {
+				t.Errorf("expect %s to score %g on %s, got %g", term, score, id, scores[id][term])
+			}
+		}
+	}
+
+	ranked := RankDocumentByBM25F("shoe", []TokenizedDocument{docs[1], docs[0]}, config)
+	if ranked[0].Id != "d1" {
+		t.Errorf("expect the shorter name to rank first, got %s", ranked[0].Id)
+	}
+}
+
+func TestBM25FFieldWeights(t *testing.T) {
+	config := DefaultBM25FConfig().WithWeights(map[string]float64{FIELD_KEYWORDS: 10})
+	if config.Fields[FIELD_KEYWORDS].Weight != 10 || config.Fields[FIELD_KEYWORDS].B != bm25B {
+		t.Errorf("expect only the weight of Keywords to change, got %+v", config.Fields[FIELD_KEYWORDS])
+	}
+	if DefaultBM25FConfig().Fields[FIELD_KEYWORDS].Weight != 1.5 {
+		t.Errorf("WithWeights must not change the config it copies")
+	}
+
+	docs := []TokenizedDocument{
+		{Id: "name", Fields: map[string][]string{FIELD_NAME: {"shoe"}, FIELD_KEYWORDS: {"sport"}}},
+		{Id: "keyword", Fields: map[string][]string{FIELD_NAME: {"sport"}, FIELD_KEYWORDS: {"shoe"}}},
+	}
+	if ranked := RankDocumentByBM25F("shoe", append([]TokenizedDocument{}, docs...), DefaultBM25FConfig()); ranked[0].Id != "name" {
+		t.Errorf("expect a match in the name to outweigh a keyword match, got %s", ranked[0].Id)
+	}
+	if ranked := RankDocumentByBM25F("shoe", append([]TokenizedDocument{}, docs...), config); ranked[0].Id != "keyword" {
+		t.Errorf("expect the keyword match to win with Keywords weighted 10, got %s", ranked[0].Id)
+	}
+}
+
+func TestLoadBM25FConfig(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "ranking.json")
+	if err := os.WriteFile(configFile, []byte(`{"Fields": {"Name": {"Weight": 5}, "Category": {"B": 0}, "Brand": {"Weight": 2}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config, err := LoadBM25FConfig(configFile, DefaultBM25FConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a value missing in the file keeps its default, a value set to 0 does not
+	base := DefaultBM25FConfig()
+	expect := map[string]FieldConfig{
+		FIELD_NAME:         {Weight: 5, B: bm25B},
+		FIELD_CATEGORY:     {Weight: 1, B: 0},
+		FIELD_SUB_CATEGORY: base.Fields[FIELD_SUB_CATEGORY],
+		FIELD_KEYWORDS:     base.Fields[FIELD_KEYWORDS],
+		"Brand":            {Weight: 2, B: 0},
+	}
+	if config.K1 != base.K1 || !reflect.DeepEqual(config.Fields, expect) {
+		t.Errorf("expect K1 %g and fields %v, got %g and %v", base.K1, expect, config.K1, config.Fields)
+	}
+}
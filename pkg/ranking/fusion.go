@@ -0,0 +1,96 @@
+package ranking
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	FUSION_RRF      = "rrf"      // reciprocal rank fusion, only looks at ranks
+	FUSION_WEIGHTED = "weighted" // weighted sum of min-max normalized scores
+
+	rrfK = 60.0 // damping constant of reciprocal rank fusion
+)
+
+// RankedList is the output of one retrieval source, Ids are ordered by Scores descending
+type RankedList struct {
+	Source string
+	Ids    []string
+	Scores []float64
+}
+
+// ValidFusion tells whether method is a fusion method Fuse knows, an empty method means FUSION_RRF
+func ValidFusion(method string) bool {
+	return len(method) == 0 || method == FUSION_RRF || method == FUSION_WEIGHTED
+}
+
+// Fuse combines ranked lists into one ranking with the given method, sources without a weight get 1.
+// It returns the ids ordered by fused score descending, and the fused score of each id.
+func Fuse(method string, lists []RankedList, weights map[string]float64) ([]string, map[string]float64) {
+	var scores map[string]float64
+	switch method {
+	case FUSION_WEIGHTED:
+		scores = WeightedScoreFusion(lists, weights)
+	default:
+		scores = ReciprocalRankFusion(lists, weights)
+	}
+
+	ids := make([]string, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if scores[ids[i]] == scores[ids[j]] {
+			return ids[i] < ids[j] // keep the order deterministic
+		}
+		return scores[ids[i]] > scores[ids[j]]
+	})
+
+	return ids, scores
+}
+
+// ReciprocalRankFusion scores each id by sum(weight / (k + rank)) over the lists containing it
+func ReciprocalRankFusion(lists []RankedList, weights map[string]float64) map[string]float64 {
+	scores := make(map[string]float64)
+	for _, list := range lists {
+		weight := sourceWeight(weights, list.Source)
+		for rank, id := range list.Ids {
+			scores[id] += weight / (rrfK + float64(rank+1))
+		}
+	}
+
+	return scores
+}
+
+// WeightedScoreFusion min-max normalizes the scores of each list to [0, 1] and sums them by weight
+func WeightedScoreFusion(lists []RankedList, weights map[string]float64) map[string]float64 {
+	scores := make(map[string]float64)
+	for _, list := range lists {
+		if len(list.Scores) != len(list.Ids) {
+			continue
+		}
+
+		weight := sourceWeight(weights, list.Source)
+		low, high := math.Inf(1), math.Inf(-1)
+		for _, score := range list.Scores {
+			low = math.Min(low, score)
+			high = math.Max(high, score)
+		}
+		for i, id := range list.Ids {
+			normalized := 1.0 // all scores are equal, treat every id as a top hit
+			if high > low {
+				normalized = (list.Scores[i] - low) / (high - low)
+			}
+			scores[id] += weight * normalized
+		}
+	}
+
+	return scores
+}
+
+func sourceWeight(weights map[string]float64, source string) float64 {
+	if weight, exists := weights[source]; exists {
+		return weight
+	}
+	return 1
+}
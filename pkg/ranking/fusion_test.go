@@ -0,0 +1,62 @@
+package ranking
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestFuse(t *testing.T) {
+	lists := []RankedList{
+		{Source: "keyword", Ids: []string{"x", "y", "z"}, Scores: []float64{3, 2, 1}},
+		{Source: "vector", Ids: []string{"y", "w"}, Scores: []float64{10, 5}},
+	}
+	tests := []struct {
+		method  string
+		weights map[string]float64
+		order   []string
+		scores  map[string]float64
+	}{
+		{FUSION_RRF, nil, []string{"y", "x", "w", "z"},
+			map[string]float64{"x": 1.0 / 61, "y": 1.0/62 + 1.0/61, "z": 1.0 / 63, "w": 1.0 / 62}},
+		{FUSION_RRF, map[string]float64{"vector": 0}, []string{"x", "y", "z", "w"},
+			map[string]float64{"x": 1.0 / 61, "y": 1.0 / 62, "z": 1.0 / 63, "w": 0}},
+		{"", map[string]float64{"keyword": 2}, []string{"y", "x", "z", "w"},
+			map[string]float64{"x": 2.0 / 61, "y": 2.0/62 + 1.0/61, "z": 2.0 / 63, "w": 1.0 / 62}},
+		// min-max normalized: keyword x=1 y=0.5 z=0, vector y=1 w=0, ties broken by id
+		{FUSION_WEIGHTED, nil, []string{"y", "x", "w", "z"},
+			map[string]float64{"x": 1, "y": 1.5, "z": 0, "w": 0}},
+		{FUSION_WEIGHTED, map[string]float64{"keyword": 3}, []string{"x", "y", "w", "z"},
+			map[string]float64{"x": 3, "y": 2.5, "z": 0, "w": 0}},
+	}
+
+	for _, test := range tests {
+		order, scores := Fuse(test.method, lists, test.weights)
+		if !reflect.DeepEqual(order, test.order) {
+			t.Errorf("%q %v: expect order %v, got %v", test.method, test.weights, test.order, order)
+		}
+		for id, expect := range test.scores {
+			if math.Abs(scores[id]-expect) > 1e-12 {
+				t.Errorf("%q %v: expect score %g of %s, got %g", test.method, test.weights, expect, id, scores[id])
+			}
+		}
+	}
+}
+
+func TestValidFusion(t *testing.T) {
+	for method, valid := range map[string]bool{"": true, FUSION_RRF: true, FUSION_WEIGHTED: true, "weigthed": false, "RRF": false} {
+		if ValidFusion(method) != valid {
+			t.Errorf("expect ValidFusion(%q) to be %v", method, valid)
+		}
+	}
+}
+
+func TestWeightedScoreFusionEqualScores(t *testing.T) {
+	scores := WeightedScoreFusion([]RankedList{
+		{Source: "keyword", Ids: []string{"a", "b"}, Scores: []float64{2, 2}},
+		{Source: "broken", Ids: []string{"c"}}, // no scores, skipped
+	}, nil)
+	if !reflect.DeepEqual(scores, map[string]float64{"a": 1, "b": 1}) {
+		t.Errorf("expect equal scores to normalize to 1 and lists without scores to be skipped, got %v", scores)
+	}
+}